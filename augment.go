@@ -0,0 +1,261 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+//Augmenter defines an aggregate of type A over items of type T,
+//such as sum, min or a hash of a range of items,
+//Combine must be associative in the sense that the aggregate of a range
+//does not depend on the shape of the tree holding it
+type Augmenter[T, A any] interface {
+	//aggregate of no item
+	Identity() A
+	//aggregate of items of left, then self, then items of right
+	Combine(left A, self T, right A) A
+}
+
+//item of an augmented set, agg is the aggregate of the subtree of its node
+type augItem[T, A any] struct {
+	item T
+	agg  A
+}
+
+//order augmented items by cmp, agg never takes part in comparison
+func augCompare[T, A any](cmp func(a, b T) int) func(a, b augItem[T, A]) int {
+	return func(a, b augItem[T, A]) int {
+		return cmp(a.item, b.item)
+	}
+}
+
+//node of any of the four trees holding augmented items
+type augNode[N any, T, A any] interface {
+	comparable
+	child(dir int) N
+	dataAddr() *augItem[T, A]
+}
+
+//operations an augmented set needs from its tree
+type augTab[T any] interface {
+	SymTabOf[T]
+	seqTab[T]
+}
+
+//AugSet is a set keeping the aggregate of every subtree in its root node,
+//the trees recompute it after every structural change, rotations included,
+//so Fold answers the aggregate of any range in O(log n)
+type AugSet[T, A any] struct {
+	tab augTab[augItem[T, A]]
+	cmp func(a, b T) int
+	aug Augmenter[T, A]
+}
+
+//recompute aggregate of n from its children l and r
+func augFix[T, A any](aug Augmenter[T, A]) func(n, l, r *augItem[T, A]) {
+	return func(n, l, r *augItem[T, A]) {
+		n.agg = aug.Combine(aggOf(aug, l), n.item, aggOf(aug, r))
+	}
+}
+
+//aggregate of subtree whose root holds p, p is nil for an empty subtree
+func aggOf[T, A any](aug Augmenter[T, A], p *augItem[T, A]) A {
+	if p == nil {
+		return aug.Identity()
+	}
+	return p.agg
+}
+
+func NewAvlAugSet[T, A any](cmp func(a, b T) int, aug Augmenter[T, A]) *AugSet[T, A] {
+	if cmp == nil || aug == nil {
+		return nil
+	}
+	s := NewAvlSet(augCompare[T, A](cmp))
+	s.fix = augFix(aug)
+	return &AugSet[T, A]{s, cmp, aug}
+}
+
+func NewPAvlAugSet[T, A any](cmp func(a, b T) int, aug Augmenter[T, A]) *AugSet[T, A] {
+	if cmp == nil || aug == nil {
+		return nil
+	}
+	s := NewPAvlSet(augCompare[T, A](cmp))
+	s.fix = augFix(aug)
+	return &AugSet[T, A]{s, cmp, aug}
+}
+
+func NewRbAugSet[T, A any](cmp func(a, b T) int, aug Augmenter[T, A]) *AugSet[T, A] {
+	if cmp == nil || aug == nil {
+		return nil
+	}
+	s := NewRbSet(augCompare[T, A](cmp))
+	s.fix = augFix(aug)
+	return &AugSet[T, A]{s, cmp, aug}
+}
+
+func NewPRbAugSet[T, A any](cmp func(a, b T) int, aug Augmenter[T, A]) *AugSet[T, A] {
+	if cmp == nil || aug == nil {
+		return nil
+	}
+	s := NewPRbSet(augCompare[T, A](cmp))
+	s.fix = augFix(aug)
+	return &AugSet[T, A]{s, cmp, aug}
+}
+
+func NewOrderedAvlAugSet[T cmp.Ordered, A any](aug Augmenter[T, A]) *AugSet[T, A] {
+	return NewAvlAugSet(cmp.Compare[T], aug)
+}
+
+func NewOrderedPAvlAugSet[T cmp.Ordered, A any](aug Augmenter[T, A]) *AugSet[T, A] {
+	return NewPAvlAugSet(cmp.Compare[T], aug)
+}
+
+func NewOrderedRbAugSet[T cmp.Ordered, A any](aug Augmenter[T, A]) *AugSet[T, A] {
+	return NewRbAugSet(cmp.Compare[T], aug)
+}
+
+func NewOrderedPRbAugSet[T cmp.Ordered, A any](aug Augmenter[T, A]) *AugSet[T, A] {
+	return NewPRbAugSet(cmp.Compare[T], aug)
+}
+
+func (s *AugSet[T, A]) Count() int {
+	if s == nil {
+		return 0
+	}
+	return s.tab.Count()
+}
+
+//search target in set
+//return found item and true if find it
+//else ok is false
+func (s *AugSet[T, A]) Find(target T) (item T, ok bool) {
+	if s == nil {
+		return
+	}
+	found, ok := s.tab.Find(augItem[T, A]{item: target})
+	return found.item, ok
+}
+
+//insert item in set
+//return false if item already in set
+func (s *AugSet[T, A]) Insert(item T) bool {
+	if s == nil {
+		return false
+	}
+	return s.tab.Insert(augItem[T, A]{item: item})
+}
+
+//replace item in set with same key item, aggregates are recomputed
+//insert item if no such key, ok is false in that case
+func (s *AugSet[T, A]) Replace(item T) (old T, ok bool) {
+	if s == nil {
+		return
+	}
+	found, ok := s.tab.Replace(augItem[T, A]{item: item})
+	return found.item, ok
+}
+
+//delete item in set
+//return item if find it
+//else ok is false
+func (s *AugSet[T, A]) Delete(item T) (deleted T, ok bool) {
+	if s == nil {
+		return
+	}
+	found, ok := s.tab.Delete(augItem[T, A]{item: item})
+	return found.item, ok
+}
+
+//sequence of all items in ascending order
+func (s *AugSet[T, A]) All() iter.Seq[T] {
+	if s == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		for m := range s.tab.All() {
+			if !yield(m.item) {
+				return
+			}
+		}
+	}
+}
+
+//aggregate of all items, O(1)
+func (s *AugSet[T, A]) Agg() A {
+	if s == nil {
+		var zero A
+		return zero
+	}
+	switch tab := s.tab.(type) {
+	case *AvlSet[augItem[T, A]]:
+		return aggOf(s.aug, tab.root.dataAddr())
+	case *PAvlSet[augItem[T, A]]:
+		return aggOf(s.aug, tab.root.dataAddr())
+	case *RbSet[augItem[T, A]]:
+		return aggOf(s.aug, tab.root.dataAddr())
+	case *PRbSet[augItem[T, A]]:
+		return aggOf(s.aug, tab.root.dataAddr())
+	}
+	return s.aug.Identity()
+}
+
+//aggregate of items in [lo, hi), O(log n)
+func (s *AugSet[T, A]) Fold(lo, hi T) A {
+	if s == nil {
+		var zero A
+		return zero
+	}
+	switch tab := s.tab.(type) {
+	case *AvlSet[augItem[T, A]]:
+		return fold(s, tab.root, lo, hi)
+	case *PAvlSet[augItem[T, A]]:
+		return fold(s, tab.root, lo, hi)
+	case *RbSet[augItem[T, A]]:
+		return fold(s, tab.root, lo, hi)
+	case *PRbSet[augItem[T, A]]:
+		return fold(s, tab.root, lo, hi)
+	}
+	return s.aug.Identity()
+}
+
+//aggregate of items of subtree n in [lo, hi)
+//go down to the first node in range, which splits the range
+//into a suffix of its left subtree and a prefix of its right subtree
+func fold[N augNode[N, T, A], T, A any](s *AugSet[T, A], n N, lo, hi T) A {
+	var null N
+	for n != null {
+		item := n.dataAddr().item
+		if s.cmp(item, lo) < 0 {
+			n = n.child(Right)
+		} else if s.cmp(item, hi) >= 0 {
+			n = n.child(Left)
+		} else {
+			return s.aug.Combine(foldGE(s, n.child(Left), lo), item, foldLT(s, n.child(Right), hi))
+		}
+	}
+	return s.aug.Identity()
+}
+
+//aggregate of items of subtree n not less than lo
+func foldGE[N augNode[N, T, A], T, A any](s *AugSet[T, A], n N, lo T) A {
+	var null N
+	for n != null && s.cmp(n.dataAddr().item, lo) < 0 {
+		n = n.child(Right)
+	}
+	if n == null {
+		return s.aug.Identity()
+	}
+	return s.aug.Combine(foldGE(s, n.child(Left), lo), n.dataAddr().item, aggOf(s.aug, n.child(Right).dataAddr()))
+}
+
+//aggregate of items of subtree n less than hi
+func foldLT[N augNode[N, T, A], T, A any](s *AugSet[T, A], n N, hi T) A {
+	var null N
+	for n != null && s.cmp(n.dataAddr().item, hi) >= 0 {
+		n = n.child(Left)
+	}
+	if n == null {
+		return s.aug.Identity()
+	}
+	return s.aug.Combine(aggOf(s.aug, n.child(Left).dataAddr()), n.dataAddr().item, foldLT(s, n.child(Right), hi))
+}
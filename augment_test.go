@@ -0,0 +1,135 @@
+package bbst
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+type keyVal struct {
+	k, v int
+}
+
+func cmpKeyVal(a, b keyVal) int {
+	return a.k - b.k
+}
+
+//aggregate keeping sum of values and keys in order,
+//keys check that Combine sees items in the right order
+type sumAgg struct {
+	sum  int
+	keys []int
+}
+
+type sumAug struct{}
+
+func (sumAug) Identity() sumAgg {
+	return sumAgg{}
+}
+
+func (sumAug) Combine(left sumAgg, self keyVal, right sumAgg) sumAgg {
+	keys := make([]int, 0, len(left.keys)+1+len(right.keys))
+	keys = append(append(append(keys, left.keys...), self.k), right.keys...)
+	return sumAgg{left.sum + self.v + right.sum, keys}
+}
+
+func TestAugSet(t *testing.T) {
+	const n = 500
+	sets := map[string]*AugSet[keyVal, sumAgg]{
+		"AvlAugSet":  NewAvlAugSet[keyVal, sumAgg](cmpKeyVal, sumAug{}),
+		"PAvlAugSet": NewPAvlAugSet[keyVal, sumAgg](cmpKeyVal, sumAug{}),
+		"RbAugSet":   NewRbAugSet[keyVal, sumAgg](cmpKeyVal, sumAug{}),
+		"PRbAugSet":  NewPRbAugSet[keyVal, sumAgg](cmpKeyVal, sumAug{}),
+	}
+	for name, s := range sets {
+		vals := map[int]int{}
+		//brute force aggregate of [lo, hi)
+		fold := func(lo, hi int) sumAgg {
+			var a sumAgg
+			for k := lo; k < hi; k++ {
+				if v, ok := vals[k]; ok {
+					a.sum += v
+					a.keys = append(a.keys, k)
+				}
+			}
+			return a
+		}
+		check := func(op string) {
+			if got, want := s.Agg(), fold(0, n); got.sum != want.sum || !slices.Equal(got.keys, want.keys) {
+				t.Fatalf("%s: after %s, Agg is %v, but should be %v\n", name, op, got, want)
+			}
+			for i := 0; i < 50; i++ {
+				lo := rand.Intn(n)
+				hi := lo + rand.Intn(n-lo+1)
+				if got, want := s.Fold(keyVal{k: lo}, keyVal{k: hi}), fold(lo, hi); got.sum != want.sum || !slices.Equal(got.keys, want.keys) {
+					t.Fatalf("%s: after %s, Fold(%d, %d) is %v, but should be %v\n", name, op, lo, hi, got, want)
+				}
+			}
+		}
+		for i := 0; i < n; i++ {
+			k, v := rand.Intn(n), rand.Intn(100)
+			if _, ok := vals[k]; s.Insert(keyVal{k, v}) == ok {
+				t.Fatalf("%s: insert of %d should return %v\n", name, k, !ok)
+			}
+			if _, ok := vals[k]; !ok {
+				vals[k] = v
+			}
+		}
+		check("insert")
+		for k := range vals {
+			if rand.Intn(2) == 0 {
+				vals[k] = rand.Intn(100)
+				if _, ok := s.Replace(keyVal{k, vals[k]}); !ok {
+					t.Fatalf("%s: replace of %d should succeed\n", name, k)
+				}
+			}
+		}
+		check("replace")
+		for i := 0; i < n/2; i++ {
+			k := rand.Intn(n)
+			_, want := vals[k]
+			if _, ok := s.Delete(keyVal{k: k}); ok != want {
+				t.Fatalf("%s: delete of %d should return %v\n", name, k, want)
+			}
+			delete(vals, k)
+		}
+		check("delete")
+		//range deletion goes through split and join
+		lo, hi := n/4, n/2
+		s.tab.(interface {
+			DeleteRange(lo, hi augItem[keyVal, sumAgg]) int
+		}).DeleteRange(augItem[keyVal, sumAgg]{item: keyVal{k: lo}}, augItem[keyVal, sumAgg]{item: keyVal{k: hi}})
+		for k := lo; k < hi; k++ {
+			delete(vals, k)
+		}
+		check("delete range")
+		//batch insertion goes one by one, through union or through rebuild by batch size
+		bt := s.tab.(interface {
+			InsertBatch(items []augItem[keyVal, sumAgg]) (int, []augItem[keyVal, sumAgg])
+		})
+		for _, m := range []int{1, s.Count() / 2, n} {
+			batch := make([]augItem[keyVal, sumAgg], m)
+			for i := range batch {
+				k := rand.Intn(n)
+				if m == n {
+					k = i
+				}
+				batch[i] = augItem[keyVal, sumAgg]{item: keyVal{k, rand.Intn(100)}}
+			}
+			bt.InsertBatch(batch)
+			//first of equal items in batch is kept
+			for _, a := range batch {
+				if _, ok := vals[a.item.k]; !ok {
+					vals[a.item.k] = a.item.v
+				}
+			}
+			check("insert batch")
+		}
+		if got := s.Fold(keyVal{k: 3}, keyVal{k: 3}); got.sum != 0 || len(got.keys) != 0 {
+			t.Errorf("%s: Fold of empty range is %v\n", name, got)
+		}
+		if got := slices.Collect(s.All()); len(got) != len(vals) || s.Count() != len(vals) {
+			t.Errorf("%s: set has %d items, but should have %d\n", name, len(got), len(vals))
+		}
+	}
+}
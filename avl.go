@@ -1,36 +1,103 @@
 package bbst
 
 import (
-	"unsafe"
+	"cmp"
+	"iter"
 )
 
 const avlMaxHeight = 92
 
-type node struct {
-	links   [ChildNum]*node
-	data    Item
+type node[T any] struct {
+	links   [ChildNum]*node[T]
+	data    T
 	balance int8
+	size    int
+	epoch   uint64 //epoch of the tree owning node, see Snapshot
 }
 
-type AvlTree struct {
-	root       *node       //root of  tree
-	cmpFunc    Compare     //compare function
-	extraParam interface{} //extra param for cmpFunc
-	count      int         // number of item in tree
-	generation int         // generation number
+//number of node in subtree rooted at n
+func (n *node[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+//recompute size of n from its children
+func (n *node[T]) updateSize() {
+	n.size = n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+//address of data of n, nil if n is nil
+func (n *node[T]) dataAddr() *T {
+	if n == nil {
+		return nil
+	}
+	return &n.data
+}
+
+//child of n in direction dir
+func (n *node[T]) child(dir int) *node[T] {
+	return n.links[dir]
+}
+
+//balance bits of shape byte of n, see marshalTree
+func (n *node[T]) shape() byte {
+	return byte(n.balance + 1)
+}
+
+//return n if it is owned by epoch, else a copy of n owned by epoch
+func (n *node[T]) own(epoch uint64) *node[T] {
+	if n == nil || n.epoch == epoch {
+		return n
+	}
+	c := *n
+	c.epoch = epoch
+	return &c
+}
+
+type AvlSet[T any] struct {
+	root       *node[T]         //root of  tree
+	cmpFunc    func(a, b T) int //compare function
+	count      int              // number of item in tree
+	generation int              // generation number
+	iterPolicy IterPolicy       //policy of iterators hooked with tree
+	codec      Codec[T]         //codec of items for MarshalBinary and UnmarshalBinary
+	epoch      uint64           //nodes of other epochs are shared, copy before write
+	fix        func(n, l, r *T) //recompute augmented data of item n from children l and r, nil if tree is not augmented
 }
 
-func NewAvlTree(cmp Compare, extra interface{}) *AvlTree {
+func NewAvlSet[T any](cmp func(a, b T) int) *AvlSet[T] {
 	if cmp == nil {
 		return nil
 	}
-	return &AvlTree{
-		cmpFunc:    cmp,
-		extraParam: extra,
+	return &AvlSet[T]{
+		cmpFunc: cmp,
+		epoch:   newEpoch(),
 	}
 }
 
-func (t *AvlTree) Count() int {
+//create a set holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n) without rebalancing
+//return ErrNotSorted if items are out of order or have duplicates
+func NewAvlSetFromSorted[T any](cmp func(a, b T) int, items []T) (*AvlSet[T], error) {
+	t := NewAvlSet(cmp)
+	if t == nil {
+		return nil, nil
+	}
+	if err := t.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedAvlSet[T cmp.Ordered]() *AvlSet[T] {
+	return NewAvlSet(cmp.Compare[T])
+}
+
+func (t *AvlSet[T]) Count() int {
 	if t == nil {
 		return 0
 	}
@@ -39,46 +106,236 @@ func (t *AvlTree) Count() int {
 
 //search target in tree
 //if find it return item
-//else return nil
-func (t *AvlTree) Find(target Item) Item {
-	if t == nil || target == nil {
-		return nil
+//else ok is false
+func (t *AvlSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
 	}
 	for w := t.root; w != nil; {
-		ret := t.cmpFunc(target, w.data, t.extraParam)
+		ret := t.cmpFunc(target, w.data)
 		if ret < 0 {
 			w = w.links[Left]
 		} else if ret > 0 {
 			w = w.links[Right]
 		} else {
-			return w.data
+			return w.data, true
 		}
 	}
+	return
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *AvlSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil || k < 0 || k >= t.count {
+		return
+	}
+	w := t.root
+	for {
+		ls := w.links[Left].subSize()
+		if k < ls {
+			w = w.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			w = w.links[Right]
+		} else {
+			return w.data, true
+		}
+	}
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *AvlSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
+	}
+	r := 0
+	for w := t.root; w != nil; {
+		ret := t.cmpFunc(item, w.data)
+		if ret < 0 {
+			w = w.links[Left]
+		} else if ret > 0 {
+			r += w.links[Left].subSize() + 1
+			w = w.links[Right]
+		} else {
+			return r + w.links[Left].subSize()
+		}
+	}
+	return r
+}
+
+//return the nearest node to item in direction dir,
+//dir Right looks for the least node greater than item,
+//dir Left looks for the greatest node less than item,
+//node equal to item is accepted if eq is true
+func (t *AvlSet[T]) near(item T, dir int, eq bool) *node[T] {
+	var c *node[T] //candidate node
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			return w
+		}
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	return c
+}
+
+//return the greatest item less than or equal to item
+//ok is false if there is no such item
+func (t *AvlSet[T]) Floor(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Left, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return the least item greater than or equal to item
+//ok is false if there is no such item
+func (t *AvlSet[T]) Ceiling(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Right, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return iterator at the first item not less than item
+func (t *AvlSet[T]) LowerBound(item T) IteratorOf[T] {
+	it := NewAvlSetIter[T]()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *AvlSet[T]) UpperBound(item T) IteratorOf[T] {
+	it := NewAvlSetIter[T]()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *AvlSet[T]) Range(lo, hi T, fn func(item T) bool) {
+	if t == nil || fn == nil {
+		return
+	}
+	var it AvlSetIter[T]
+	for item, ok := it.HookWith(t).SeekGE(lo); ok && t.cmpFunc(item, hi) < 0; item, ok = it.Next() {
+		if !fn(item) {
+			return
+		}
+	}
+}
+
+//recompute size and augmented data of n from its children
+func (t *AvlSet[T]) update(n *node[T]) {
+	n.updateSize()
+	if t.fix != nil {
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of nodes in path, which goes from top to bottom
+func (t *AvlSet[T]) fixPath(path []*node[T]) {
+	if t.fix == nil {
+		return
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of nodes on the path from root to item,
+//after item was changed in place
+func (t *AvlSet[T]) fixKey(item T) {
+	if t.fix == nil {
+		return
+	}
+	var (
+		pa  [avlMaxHeight]*node[T]
+		h   int
+		dir = Left
+	)
+	head := node[T]{links: [ChildNum]*node[T]{Left: t.root}}
+	for w := t.ownChild(&head, Left); w != nil; w = t.ownChild(w, dir) {
+		pa[h] = w
+		h++
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			break
+		}
+		if cmp > 0 {
+			dir = Right
+		} else {
+			dir = Left
+		}
+	}
+	t.root = head.links[Left]
+	t.fixPath(pa[:h])
+}
+
+//replace items of t with items, which are sorted in strictly ascending order
+func (t *AvlSet[T]) loadSorted(items []T) error {
+	if err := checkSorted(t.cmpFunc, items); err != nil {
+		return err
+	}
+	t.root, _ = t.build(items)
+	t.count = len(items)
+	t.generation++
 	return nil
 }
 
-func (t *AvlTree) insert(item Item) (*Item, bool) {
-	if t == nil || item == nil {
+//build a perfectly balanced subtree of items, return its root and height
+func (t *AvlSet[T]) build(items []T) (*node[T], int) {
+	if len(items) == 0 {
+		return nil, 0
+	}
+	m := len(items) / 2
+	n := &node[T]{data: items[m], epoch: t.epoch}
+	l, lh := t.build(items[:m])
+	r, rh := t.build(items[m+1:])
+	return n, t.link(n, l, lh, r, rh)
+}
+
+func (t *AvlSet[T]) insert(item T) (*T, bool) {
+	if t == nil {
 		return nil, false
 	}
 	var (
-		y   *node              //待更新平衡因子的最顶层节点
-		z   *node              //y's  parent
-		w   *node              //current walk node
-		p   *node              //w's  parent
-		n   *node              //new node
-		r   *node              //new root node of rebalanced subtree
-		dir byte               //下降方向
-		da  [avlMaxHeight]byte //缓存的下降方向数组
-		k   int                //length of da
+		y   *node[T]               //待更新平衡因子的最顶层节点
+		z   *node[T]               //y's  parent
+		w   *node[T]               //current walk node
+		p   *node[T]               //w's  parent
+		n   *node[T]               //new node
+		r   *node[T]               //new root node of rebalanced subtree
+		dir byte                   //下降方向
+		da  [avlMaxHeight]byte     //缓存的下降方向数组
+		k   int                    //length of da
+		pa  [avlMaxHeight]*node[T] //all node above new node
+		h   int                    //length of pa
 	)
-	z = (*node)(unsafe.Pointer(&t.root))
+	//header node stands for parent of root, its left link is root
+	head := node[T]{links: [ChildNum]*node[T]{Left: t.root}}
+	z = &head
 	dir = Left
-	y = t.root
-	for p, w = z, y; w != nil; p, w = w, w.links[dir] {
-		cmp := t.cmpFunc(item, w.data, t.extraParam)
+	y = t.ownChild(z, Left)
+	for p, w = z, y; w != nil; p, w = w, t.ownChild(w, int(dir)) {
+		cmp := t.cmpFunc(item, w.data)
 		if cmp == 0 {
 			//fmt.Printf("item: %v, w.data: %v\n", item, w.data)
+			t.root = head.links[Left]
 			return &w.data, false
 		}
 		if w.balance != 0 {
@@ -86,6 +343,8 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 			y = w
 			k = 0
 		}
+		pa[h] = w
+		h++
 		if cmp > 0 {
 			dir = Right
 		} else {
@@ -94,11 +353,20 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 		da[k] = dir
 		k++
 	}
-	n = &node{data: item}
+	n = &node[T]{data: item, size: 1, epoch: t.epoch}
 	p.links[dir] = n
 	t.count++
+	t.generation++
+	if t.fix != nil {
+		t.fix(&n.data, nil, nil)
+		t.fixPath(pa[:h])
+	}
+	for h--; h >= 0; h-- {
+		pa[h].size++
+	}
 	if y == nil {
 		//fmt.Println("tree is empty, ", n.data)
+		t.root = head.links[Left]
 		return &n.data, true
 	}
 	for w, k = y, 0; w != n; w, k = w.links[da[k]], k+1 {
@@ -114,6 +382,8 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 			r = x
 			y.links[Left] = x.links[Right]
 			x.links[Right] = y
+			t.update(y)
+			t.update(x)
 			x.balance = 0
 			y.balance = 0
 		} else { //x.balance == 1
@@ -122,6 +392,9 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 			r.links[Left] = x
 			y.links[Left] = r.links[Right]
 			r.links[Right] = y
+			t.update(x)
+			t.update(y)
+			t.update(r)
 			if r.balance == -1 {
 				x.balance = 0
 				y.balance = 1
@@ -140,6 +413,8 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 			r = x
 			y.links[Right] = x.links[Left]
 			x.links[Left] = y
+			t.update(y)
+			t.update(x)
 			x.balance = 0
 			y.balance = 0
 		} else { //x->avl_balance == -1
@@ -148,6 +423,9 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 			r.links[Right] = x
 			y.links[Right] = r.links[Left]
 			r.links[Left] = y
+			t.update(x)
+			t.update(y)
+			t.update(r)
 			if r.balance == 1 {
 				x.balance = 0
 				y.balance = -1
@@ -161,6 +439,7 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 			r.balance = 0
 		}
 	} else {
+		t.root = head.links[Left]
 		return &n.data, true
 	}
 	if y != z.links[Left] {
@@ -169,48 +448,61 @@ func (t *AvlTree) insert(item Item) (*Item, bool) {
 		dir = Left
 	}
 	z.links[dir] = r
-	t.generation++
+	t.root = head.links[Left]
 	return &n.data, true
 }
 
 //insert item in tree
 //return true if item was successfully inserted
 //return false if item already in tree
-func (t *AvlTree) Insert(item Item) bool {
+func (t *AvlSet[T]) Insert(item T) bool {
 	_, succ := t.insert(item)
 	return succ
 }
 
+//insert all items in tree, items equal to one in tree or to an earlier one in items are skipped
+//the batch is sorted and merged into tree, O(m log(n/m + 1)) for m items in a tree of n items
+//return number of inserted items and skipped items in ascending order
+func (t *AvlSet[T]) InsertBatch(items []T) (inserted int, dups []T) {
+	if t == nil {
+		return
+	}
+	return insertBatch(t, t.cmpFunc, items)
+}
+
 //replace item in tree with same key item
-func (t *AvlTree) Replace(item Item) Item {
+//insert item if no such key, ok is false in that case
+func (t *AvlSet[T]) Replace(item T) (old T, ok bool) {
 	addr, succ := t.insert(item)
 	if addr == nil || succ {
-		return nil
+		return
 	}
 	r := *addr
 	*addr = item
-	return r
+	t.fixKey(item)
+	return r, true
 }
 
 //delete item in tree
 //return item if find it
-//else  return nil
-func (t *AvlTree) Delete(item Item) Item {
-	if t == nil || item == nil {
-		return nil
+//else ok is false
+func (t *AvlSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
 	}
 
 	var (
-		pa  [avlMaxHeight]*node
+		pa  [avlMaxHeight]*node[T]
 		da  [avlMaxHeight]byte
 		k   int
-		w   *node
+		w   *node[T]
 		dir byte
 		cmp int
 	)
 	k = 0
-	w = (*node)(unsafe.Pointer(&t.root))
-	for cmp = -1; cmp != 0; cmp = t.cmpFunc(item, w.data, t.extraParam) {
+	head := node[T]{links: [ChildNum]*node[T]{Left: t.root}}
+	w = &head
+	for cmp = -1; cmp != 0; cmp = t.cmpFunc(item, w.data) {
 		if cmp > 0 {
 			dir = Right
 		} else {
@@ -219,35 +511,48 @@ func (t *AvlTree) Delete(item Item) Item {
 		pa[k] = w
 		da[k] = dir
 		k++
-		w = w.links[dir]
+		w = t.ownChild(w, int(dir))
 		if w == nil {
-			return nil
+			t.root = head.links[Left]
+			return
 		}
 	}
+	deleted, _ = t.deleteAt(&pa, &da, k)
+	return deleted, true
+}
+
+//delete the node at pa[k-1].links[da[k-1]], pa[0] is a header node whose left link is root,
+//all nodes on the path must be owned by t already
+//return deleted item and the least level of pa where a rotation happened,
+//nodes in pa above it keep their place, avlMaxHeight if no rotation at all
+func (t *AvlSet[T]) deleteAt(pa *[avlMaxHeight]*node[T], da *[avlMaxHeight]byte, k int) (deleted T, rot int) {
+	rot = avlMaxHeight
+	w := pa[k-1].links[da[k-1]]
 	ret := w.data
 
 	//fmt.Printf("in delete(), ret: %v, k=%d\n", ret, k)
 	if w.links[Right] == nil { //case 1, w has no right child
 		pa[k-1].links[da[k-1]] = w.links[Left]
 	} else { //case 2, w's right child has no left child
-		r := w.links[Right]
+		r := t.ownChild(w, Right)
 		if r.links[Left] == nil {
 			r.links[Left] = w.links[Left]
 			r.balance = w.balance
+			r.size = w.size
 			pa[k-1].links[da[k-1]] = r
 			da[k] = Right
 			pa[k] = r
 			k++
 		} else { //case 3, w's right child has left child
 
-			var s *node
+			var s *node[T]
 			j := k
 			k++
 			for {
 				da[k] = Left
 				pa[k] = r
 				k++
-				s = r.links[Left]
+				s = t.ownChild(r, Left)
 				if s.links[Left] == nil {
 					break
 				}
@@ -257,6 +562,7 @@ func (t *AvlTree) Delete(item Item) Item {
 			r.links[Left] = s.links[Right]
 			s.links[Right] = w.links[Right]
 			s.balance = w.balance
+			s.size = w.size
 
 			pa[j-1].links[da[j-1]] = s
 			da[j] = Right
@@ -264,6 +570,11 @@ func (t *AvlTree) Delete(item Item) Item {
 		}
 	}
 	w = nil
+	//pa[0] is header node standing for parent of root, skip it
+	for i := 1; i < k; i++ {
+		pa[i].size--
+	}
+	t.fixPath(pa[1:k])
 	//删除后，更新平衡因子, 重新平衡
 	k--
 	//fmt.Printf("before loop: k=%d\n", k)
@@ -275,13 +586,17 @@ func (t *AvlTree) Delete(item Item) Item {
 			if y.balance == 1 {
 				break
 			} else if y.balance == 2 { //重新平衡
-				x := y.links[Right]
+				rot = k
+				x := t.ownChild(y, Right)
 				if x.balance == -1 {
-					r := x.links[Left]
+					r := t.ownChild(x, Left)
 					x.links[Left] = r.links[Right]
 					r.links[Right] = x
 					y.links[Right] = r.links[Left]
 					r.links[Left] = y
+					t.update(x)
+					t.update(y)
+					t.update(r)
 					if r.balance == 1 {
 						x.balance = 0
 						y.balance = -1
@@ -297,6 +612,8 @@ func (t *AvlTree) Delete(item Item) Item {
 				} else { /*  x.balance == 0  ||  x.balance == 1 */
 					y.links[Right] = x.links[Left]
 					x.links[Left] = y
+					t.update(y)
+					t.update(x)
 					pa[k-1].links[da[k-1]] = x
 					if x.balance == 0 {
 						x.balance = -1
@@ -314,13 +631,17 @@ func (t *AvlTree) Delete(item Item) Item {
 			if y.balance == -1 {
 				break
 			} else if y.balance == -2 {
-				x := y.links[Left]
+				rot = k
+				x := t.ownChild(y, Left)
 				if x.balance == 1 {
-					r := x.links[Right]
+					r := t.ownChild(x, Right)
 					x.links[Right] = r.links[Left]
 					r.links[Left] = x
 					y.links[Left] = r.links[Right]
 					r.links[Right] = y
+					t.update(x)
+					t.update(y)
+					t.update(r)
 					if r.balance == -1 {
 						x.balance = 0
 						y.balance = 1
@@ -336,6 +657,8 @@ func (t *AvlTree) Delete(item Item) Item {
 				} else {
 					y.links[Left] = x.links[Right]
 					x.links[Right] = y
+					t.update(y)
+					t.update(x)
 					pa[k-1].links[da[k-1]] = x
 					if x.balance == 0 {
 						x.balance = 1
@@ -350,34 +673,501 @@ func (t *AvlTree) Delete(item Item) Item {
 		}
 	}
 
+	t.root = pa[0].links[Left]
 	t.count--
 	t.generation++
-	return ret
+	return ret, rot
+}
+
+//delete all items in [lo, hi)
+//the range is split out of tree and the rest joined back, O(log n)
+//return number of deleted items
+func (t *AvlSet[T]) DeleteRange(lo, hi T) int {
+	if t == nil || t.cmpFunc(lo, hi) >= 0 {
+		return 0
+	}
+	i, j := t.Rank(lo), t.Rank(hi)
+	if i == j {
+		return 0
+	}
+	l, lh, r, rh := t.splitAt(t.root, t.root.height(), i)
+	_, _, r, rh = t.splitAt(r, rh, j-i)
+	root, _ := t.concat(l, lh, r, rh)
+	t.setRoot(root)
+	t.count -= j - i
+	t.generation++
+	return j - i
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied, O(log n)
+//return false and change nothing if items are out of order
+func (t *AvlSet[T]) Join(pivot T, right *AvlSet[T]) bool {
+	if t == nil || right == t {
+		return false
+	}
+	if n := t.root.extreme(Right); n != nil && t.cmpFunc(n.data, pivot) >= 0 {
+		return false
+	}
+	var (
+		r     *node[T]
+		count int
+	)
+	if right != nil {
+		r, count = right.root, right.count
+	}
+	if n := r.extreme(Left); n != nil && t.cmpFunc(pivot, n.data) >= 0 {
+		return false
+	}
+	root, _ := t.join(t.root, t.root.height(), &node[T]{data: pivot, epoch: t.epoch}, r, r.height())
+	t.setRoot(root)
+	t.count += count + 1
+	t.generation++
+	if right != nil {
+		right.clear()
+	}
+	return true
+}
+
+//split t into items less than item and items greater than item, O(log n)
+//eq is the item equal to item, found is false if there is no such item
+//t is emptied
+func (t *AvlSet[T]) Split(item T) (lt *AvlSet[T], eq T, found bool, gt *AvlSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, e, r, _ := t.split(t.root, t.root.height(), item)
+	if e != nil {
+		eq, found = e.data, true
+	}
+	lt, gt = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
 }
 
-func (t *AvlTree) Copy() *AvlTree {
+//split t into the first k items and the rest, O(log n)
+//t is emptied
+func (t *AvlSet[T]) SplitAt(k int) (lt, ge *AvlSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, r, _ := t.splitAt(t.root, t.root.height(), k)
+	lt, ge = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//height of subtree rooted at n, walking down the higher side
+func (n *node[T]) height() int {
+	h := 0
+	for n != nil {
+		h++
+		if n.balance < 0 {
+			n = n.links[Left]
+		} else {
+			n = n.links[Right]
+		}
+	}
+	return h
+}
+
+//heights of left and right subtree of n, h is height of n
+func (n *node[T]) childHeights(h int) (lh, rh int) {
+	lh, rh = h-1, h-1
+	if n.balance > 0 {
+		lh--
+	} else if n.balance < 0 {
+		rh--
+	}
+	return
+}
+
+//the last node of subtree rooted at n in direction dir
+func (n *node[T]) extreme(dir int) *node[T] {
+	for n != nil && n.links[dir] != nil {
+		n = n.links[dir]
+	}
+	return n
+}
+
+//make l and r, whose heights are lh and rh, children of n
+//return height of n
+func (t *AvlSet[T]) link(n *node[T], l *node[T], lh int, r *node[T], rh int) int {
+	n.links[Left] = l
+	n.links[Right] = r
+	n.balance = int8(rh - lh)
+	t.update(n)
+	return max(lh, rh) + 1
+}
+
+//set root of t to n
+func (t *AvlSet[T]) setRoot(n *node[T]) {
+	t.root = n
+}
+
+//make an empty tree with the same order, augmentation and settings as t
+func (t *AvlSet[T]) emptyLike() *AvlSet[T] {
+	s := t.withRoot(nil)
+	s.iterPolicy = t.iterPolicy
+	s.codec = t.codec
+	return s
+}
+
+//make a tree with the same order as t rooted at n
+func (t *AvlSet[T]) withRoot(n *node[T]) *AvlSet[T] {
+	s := &AvlSet[T]{cmpFunc: t.cmpFunc, epoch: newEpoch(), fix: t.fix}
+	s.setRoot(n)
+	s.count = n.subSize()
+	return s
+}
+
+//join subtree l, node n and subtree r, whose items are in ascending order
+//heights of l and r may be of any difference
+//return root and height of the joined tree
+func (t *AvlSet[T]) join(l *node[T], lh int, n *node[T], r *node[T], rh int) (*node[T], int) {
+	switch {
+	case lh > rh+1:
+		return t.joinRight(l, lh, n, r, rh)
+	case rh > lh+1:
+		return t.joinLeft(l, lh, n, r, rh)
+	}
+	return n, t.link(n, l, lh, r, rh)
+}
+
+//join n and r into right spine of l, l is higher than r by more than one
+func (t *AvlSet[T]) joinRight(l *node[T], lh int, n *node[T], r *node[T], rh int) (*node[T], int) {
+	l = l.own(t.epoch)
+	a, c := l.links[Left], l.links[Right]
+	ah, ch := l.childHeights(lh)
+	var (
+		m  *node[T]
+		mh int
+	)
+	if ch > rh+1 {
+		m, mh = t.joinRight(c, ch, n, r, rh)
+	} else {
+		m, mh = n, t.link(n, c, ch, r, rh)
+	}
+	if mh <= ah+1 {
+		return l, t.link(l, a, ah, m, mh)
+	}
+	//m is higher than a by two
+	ml, mr := m.links[Left], m.links[Right]
+	mlh, mrh := m.childHeights(mh)
+	if mlh <= mrh {
+		return m, t.link(m, l, t.link(l, a, ah, ml, mlh), mr, mrh)
+	}
+	ml = ml.own(t.epoch)
+	x, y := ml.links[Left], ml.links[Right]
+	xh, yh := ml.childHeights(mlh)
+	return ml, t.link(ml, l, t.link(l, a, ah, x, xh), m, t.link(m, y, yh, mr, mrh))
+}
+
+//join l and n into left spine of r, r is higher than l by more than one
+func (t *AvlSet[T]) joinLeft(l *node[T], lh int, n *node[T], r *node[T], rh int) (*node[T], int) {
+	r = r.own(t.epoch)
+	c, b := r.links[Left], r.links[Right]
+	ch, bh := r.childHeights(rh)
+	var (
+		m  *node[T]
+		mh int
+	)
+	if ch > lh+1 {
+		m, mh = t.joinLeft(l, lh, n, c, ch)
+	} else {
+		m, mh = n, t.link(n, l, lh, c, ch)
+	}
+	if mh <= bh+1 {
+		return r, t.link(r, m, mh, b, bh)
+	}
+	//m is higher than b by two
+	ml, mr := m.links[Left], m.links[Right]
+	mlh, mrh := m.childHeights(mh)
+	if mrh <= mlh {
+		return m, t.link(m, ml, mlh, r, t.link(r, mr, mrh, b, bh))
+	}
+	mr = mr.own(t.epoch)
+	x, y := mr.links[Left], mr.links[Right]
+	xh, yh := mr.childHeights(mrh)
+	return mr, t.link(mr, m, t.link(m, ml, mlh, x, xh), r, t.link(r, y, yh, b, bh))
+}
+
+//join subtree l and subtree r, every item in l is less than every item in r
+func (t *AvlSet[T]) concat(l *node[T], lh int, r *node[T], rh int) (*node[T], int) {
+	if l == nil {
+		return r, rh
+	}
+	l, lh, n := t.splitLast(l, lh)
+	return t.join(l, lh, n, r, rh)
+}
+
+//unlink the greatest node of nonempty subtree n, whose height is h
+//return the rest subtree with its height and the greatest node
+func (t *AvlSet[T]) splitLast(n *node[T], h int) (l *node[T], lh int, last *node[T]) {
+	n = n.own(t.epoch)
+	a, b := n.links[Left], n.links[Right]
+	ah, bh := n.childHeights(h)
+	if b == nil {
+		return a, ah, n
+	}
+	l, lh, last = t.splitLast(b, bh)
+	l, lh = t.join(a, ah, n, l, lh)
+	return
+}
+
+//split subtree n, whose height is h, into subtree less than item and subtree greater than item
+//eq is the node equal to item, or nil if there is no such node
+func (t *AvlSet[T]) split(n *node[T], h int, item T) (l *node[T], lh int, eq *node[T], r *node[T], rh int) {
+	if n == nil {
+		return
+	}
+	n = n.own(t.epoch)
+	a, b := n.links[Left], n.links[Right]
+	ah, bh := n.childHeights(h)
+	switch c := t.cmpFunc(item, n.data); {
+	case c < 0:
+		l, lh, eq, r, rh = t.split(a, ah, item)
+		r, rh = t.join(r, rh, n, b, bh)
+	case c > 0:
+		l, lh, eq, r, rh = t.split(b, bh, item)
+		l, lh = t.join(a, ah, n, l, lh)
+	default:
+		l, lh, eq, r, rh = a, ah, n, b, bh
+	}
+	return
+}
+
+//split subtree n, whose height is h, into subtree of the first k nodes and subtree of the rest
+func (t *AvlSet[T]) splitAt(n *node[T], h int, k int) (l *node[T], lh int, r *node[T], rh int) {
+	if n == nil {
+		return
+	}
+	n = n.own(t.epoch)
+	a, b := n.links[Left], n.links[Right]
+	ah, bh := n.childHeights(h)
+	if s := a.subSize(); k <= s {
+		l, lh, r, rh = t.splitAt(a, ah, k)
+		r, rh = t.join(r, rh, n, b, bh)
+	} else {
+		l, lh, r, rh = t.splitAt(b, bh, k-s-1)
+		l, lh = t.join(a, ah, n, l, lh)
+	}
+	return
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+//O(m log(n/m + 1)) for sizes m <= n, big trees are merged in parallel,
+//so compare function and resolve must be safe for concurrent use
+func (t *AvlSet[T]) Union(other *AvlSet[T], resolve func(a, b T) T) {
+	if t == nil || other == nil || other == t {
+		return
+	}
+	root, _ := t.union(t.root, t.root.height(), other.root, other.root.height(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *AvlSet[T]) Intersect(other *AvlSet[T], resolve func(a, b T) T) {
+	if t == nil || other == t {
+		return
+	}
+	if other == nil {
+		t.clear()
+		return
+	}
+	root, _ := t.intersect(t.root, t.root.height(), other.root, other.root.height(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//delete all items of other from t, other is emptied
+func (t *AvlSet[T]) Difference(other *AvlSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.difference(t.root, t.root.height(), other.root, other.root.height())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *AvlSet[T]) SymmetricDifference(other *AvlSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.symmetricDifference(t.root, t.root.height(), other.root, other.root.height())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//drop all items of t
+//the nodes may live on in another tree, so t never writes them again
+func (t *AvlSet[T]) clear() {
+	t.root = nil
+	t.count = 0
+	t.generation++
+	t.epoch = newEpoch()
+}
+
+//union of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *AvlSet[T]) union(a *node[T], ah int, b *node[T], bh int, resolve func(a, b T) T) (*node[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	a = a.own(t.epoch)
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *node[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.union(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.union(r1, rh1, r2, rh2, resolve)
+	})
+	if eq != nil && resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//intersection of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *AvlSet[T]) intersect(a *node[T], ah int, b *node[T], bh int, resolve func(a, b T) T) (*node[T], int) {
+	if a == nil || b == nil {
+		return nil, 0
+	}
+	a = a.own(t.epoch)
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *node[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.intersect(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.intersect(r1, rh1, r2, rh2, resolve)
+	})
+	if eq == nil {
+		return t.concat(l, lh, r, rh)
+	}
+	if resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//subtree a without items of subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *AvlSet[T]) difference(a *node[T], ah int, b *node[T], bh int) (*node[T], int) {
+	if a == nil || b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l2, r2 := b.links[Left], b.links[Right]
+	lh2, rh2 := b.childHeights(bh)
+	l1, lh1, _, r1, rh1 := t.split(a, ah, b.data)
+	var (
+		l, r   *node[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.difference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.difference(r1, rh1, r2, rh2)
+	})
+	return t.concat(l, lh, r, rh)
+}
+
+//items in exactly one of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *AvlSet[T]) symmetricDifference(a *node[T], ah int, b *node[T], bh int) (*node[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	a = a.own(t.epoch)
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *node[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.symmetricDifference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.symmetricDifference(r1, rh1, r2, rh2)
+	})
+	if eq != nil {
+		return t.concat(l, lh, r, rh)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+func (t *AvlSet[T]) Copy() *AvlSet[T] {
 	if t == nil {
 		return nil
 	}
-	n := NewAvlTree(t.cmpFunc, t.extraParam)
+	n := NewAvlSet(t.cmpFunc)
 	if n == nil {
 		return nil
 	}
 	n.count = t.count
+	n.iterPolicy = t.iterPolicy
+	n.codec = t.codec
+	n.fix = t.fix
 	if n.count == 0 {
 		return n
 	}
 	var (
-		stack  [2 * (avlMaxHeight + 1)]*node
+		stack  [2 * (avlMaxHeight + 1)]*node[T]
 		height int
-		x      *node
-		y      *node
+		x      *node[T]
+		y      *node[T]
 	)
-	x = (*node)(unsafe.Pointer(&t.root))
-	y = (*node)(unsafe.Pointer(&n.root))
+	//header nodes stand for parents of roots
+	var hx, hy node[T]
+	hx.links[Left] = t.root
+	x, y = &hx, &hy
 	for {
 		for x.links[Left] != nil {
-			y.links[Left] = &node{}
+			y.links[Left] = &node[T]{}
 			stack[height] = x
 			height++
 			stack[height] = y
@@ -389,8 +1179,10 @@ func (t *AvlTree) Copy() *AvlTree {
 		for {
 			y.data = x.data
 			y.balance = x.balance
+			y.size = x.size
+			y.epoch = n.epoch
 			if x.links[Right] != nil {
-				y.links[Right] = &node{}
+				y.links[Right] = &node[T]{}
 				x = x.links[Right]
 				y = y.links[Right]
 				break
@@ -398,6 +1190,7 @@ func (t *AvlTree) Copy() *AvlTree {
 				y.links[Right] = nil
 			}
 			if height <= 2 {
+				n.root = hy.links[Left]
 				return n
 			}
 			height--
@@ -408,27 +1201,156 @@ func (t *AvlTree) Copy() *AvlTree {
 	}
 }
 
-func (t *AvlTree) Iter() Iterator {
-	it := NewAvlIter()
+//make child of p in direction dir owned by t and return it,
+//p must be owned by t already
+//cloning a node moves it in memory, so iterators walking the old one are out of date
+func (t *AvlSet[T]) ownChild(p *node[T], dir int) *node[T] {
+	c := p.links[dir]
+	if c != nil && c.epoch != t.epoch {
+		c = c.own(t.epoch)
+		p.links[dir] = c
+		t.generation++
+	}
+	return c
+}
+
+//return a copy of t in O(1) time,
+//t and the copy share all nodes, both take a new epoch,
+//so shared nodes are cloned lazily by whichever tree writes them first
+func (t *AvlSet[T]) Snapshot() *AvlSet[T] {
+	if t == nil {
+		return nil
+	}
+	s := &AvlSet[T]{
+		root:       t.root,
+		cmpFunc:    t.cmpFunc,
+		count:      t.count,
+		epoch:      newEpoch(),
+		iterPolicy: t.iterPolicy,
+		codec:      t.codec,
+		fix:        t.fix,
+	}
+	t.epoch = newEpoch()
+	return s
+}
+
+func (t *AvlSet[T]) Iter() IteratorOf[T] {
+	it := NewAvlSetIter[T]()
 	return it.HookWith(t)
 }
 
-type AvlIter struct {
-	tree       *AvlTree            //the tree be iterated
-	node       *node               //current node in tree
-	stack      [avlMaxHeight]*node //all node above current node
-	height     int                 //current depth of stack
-	generation int                 // generation number
+//set policy of iterators hooked with tree from now on, see IterPolicy
+func (t *AvlSet[T]) SetIterPolicy(p IterPolicy) {
+	if t == nil {
+		return
+	}
+	t.iterPolicy = p
 }
 
-func NewAvlIter() *AvlIter {
-	return &AvlIter{}
+//set codec of items used by MarshalBinary and UnmarshalBinary
+func (t *AvlSet[T]) SetCodec(c Codec[T]) {
+	if t == nil {
+		return
+	}
+	t.codec = c
 }
 
-func (it *AvlIter) HookWith(tree *AvlTree) *AvlIter {
+//encode items of t in ascending order along with the shape of tree,
+//implements encoding.BinaryMarshaler
+func (t *AvlSet[T]) MarshalBinary() ([]byte, error) {
+	if t == nil {
+		return nil, ErrNoCodec
+	}
+	return marshalTree(encAvl, t.root, t.count, t.codec)
+}
+
+//replace items of t with the tree encoded by MarshalBinary of a AvlSet,
+//the same tree is rebuilt in O(n) without comparisons,
+//so compare function of t must order items as the encoding tree did
+//implements encoding.BinaryUnmarshaler
+func (t *AvlSet[T]) UnmarshalBinary(data []byte) error {
+	if t == nil {
+		return ErrNoCodec
+	}
+	root, count, err := unmarshalTree(encAvl, data, t.codec, func(c byte, l *node[T], item T, r *node[T]) *node[T] {
+		n := &node[T]{links: [ChildNum]*node[T]{l, r}, data: item, balance: int8(c) - 1, epoch: t.epoch}
+		t.update(n)
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	t.root = root
+	t.count = count
+	t.generation++
+	return nil
+}
+
+func (t *AvlSet[T]) seekIter() seekIterOf[T] {
+	return NewAvlSetIter[T]().HookWith(t)
+}
+
+//sequence of all items in ascending order
+func (t *AvlSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAll(t.seekIter)
+}
+
+//sequence of all items in descending order
+func (t *AvlSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBackward(t.seekIter)
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *AvlSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAscend(t.seekIter, from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *AvlSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqDescend(t.seekIter, from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *AvlSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBetween(t.seekIter, t.cmpFunc, lo, hi)
+}
+
+type AvlSetIter[T any] struct {
+	tree       *AvlSet[T]             //the tree be iterated
+	node       *node[T]               //current node in tree
+	stack      [avlMaxHeight]*node[T] //all node above current node
+	height     int                    //current depth of stack
+	generation int                    // generation number
+	policy     IterPolicy             //how to react to changes of tree
+}
+
+func NewAvlSetIter[T any]() *AvlSetIter[T] {
+	return &AvlSetIter[T]{}
+}
+
+func (it *AvlSetIter[T]) HookWith(tree *AvlSet[T]) *AvlSetIter[T] {
 	if it == nil {
 		return nil
 	}
+	it.policy = tree.iterPolicy
+	if it.policy == IterSnapshot {
+		tree = tree.Snapshot()
+	}
 	it.tree = tree
 	it.node = nil
 	it.height = 0
@@ -437,14 +1359,16 @@ func (it *AvlIter) HookWith(tree *AvlTree) *AvlIter {
 	return it
 }
 
-func (it *AvlIter) First() Item {
+func (it *AvlSetIter[T]) First() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	it.height = 0
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Left] != nil {
 		it.stack[it.height] = w
@@ -452,17 +1376,19 @@ func (it *AvlIter) First() Item {
 		w = w.links[Left]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *AvlIter) Last() Item {
+func (it *AvlSetIter[T]) Last() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	it.height = 0
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Right] != nil {
 		it.stack[it.height] = w
@@ -470,23 +1396,24 @@ func (it *AvlIter) Last() Item {
 		w = w.links[Right]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *AvlIter) Find(item Item) Item {
-	if it == nil || it.tree == nil || item == nil {
-		return nil
+func (it *AvlSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
 	}
+	it.generation = it.tree.generation
 	it.height = 0
 	var (
-		w *node //walk node
-		n *node //child of w
+		w *node[T] //walk node
+		n *node[T] //child of w
 	)
 	for w = it.tree.root; w != nil; w = n {
-		cmp := it.tree.cmpFunc(item, w.data, it.tree.extraParam)
+		cmp := it.tree.cmpFunc(item, w.data)
 		if cmp == 0 {
 			it.node = w
-			return w.data
+			return w.data, true
 		}
 		if cmp < 0 {
 			n = w.links[Left]
@@ -498,15 +1425,106 @@ func (it *AvlIter) Find(item Item) Item {
 	}
 	it.height = 0
 	it.node = nil
-	return nil
+	return
 }
 
-func (it *AvlIter) Next() Item {
+//move iterator to item at index k in sorted order
+//if k is out of range, iterator is moved to nil position
+func (it *AvlSetIter[T]) Seek(k int) (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
-	if it.generation != it.tree.generation {
-		it.refresh()
+	it.generation = it.tree.generation
+	it.height = 0
+	it.node = nil
+	if k < 0 || k >= it.tree.count {
+		return
+	}
+	w := it.tree.root
+	for {
+		ls := w.links[Left].subSize()
+		if k == ls {
+			break
+		}
+		it.stack[it.height] = w
+		it.height++
+		if k < ls {
+			w = w.links[Left]
+		} else {
+			k -= ls + 1
+			w = w.links[Right]
+		}
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move iterator to the nearest item to item in direction dir, see near
+func (it *AvlSetIter[T]) seekNear(item T, dir int, eq bool) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	it.generation = it.tree.generation
+	var (
+		c  *node[T] //candidate node
+		ch int      //stack height of candidate node
+	)
+	it.height = 0
+	for w := it.tree.root; w != nil; {
+		cmp := it.tree.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			c, ch = w, it.height
+			break
+		}
+		it.stack[it.height] = w
+		it.height++
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c, ch = w, it.height-1
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	it.node = c
+	if c == nil {
+		it.height = 0
+		return
+	}
+	it.height = ch
+	return c.data, true
+}
+
+//move iterator to the least item greater than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *AvlSetIter[T]) SeekGE(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, true)
+}
+
+//move iterator to the least item greater than item
+//if there is no such item, iterator is moved to nil position
+func (it *AvlSetIter[T]) SeekGT(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, false)
+}
+
+//move iterator to the greatest item less than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *AvlSetIter[T]) SeekLE(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, true)
+}
+
+//move iterator to the greatest item less than item
+//if there is no such item, iterator is moved to nil position
+func (it *AvlSetIter[T]) SeekLT(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, false)
+}
+
+func (it *AvlSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Right, false)
 	}
 	w := it.node
 	if w == nil {
@@ -524,7 +1542,7 @@ func (it *AvlIter) Next() Item {
 		for {
 			if it.height == 0 {
 				it.node = nil
-				return nil
+				return
 			}
 			n := w
 			it.height--
@@ -535,15 +1553,16 @@ func (it *AvlIter) Next() Item {
 		}
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *AvlIter) Prev() Item {
+func (it *AvlSetIter[T]) Prev() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
-	if it.generation != it.tree.generation {
-		it.refresh()
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Left, false)
 	}
 	w := it.node
 	if w == nil {
@@ -561,7 +1580,7 @@ func (it *AvlIter) Prev() Item {
 		for {
 			if it.height == 0 {
 				it.node = nil
-				return nil
+				return
 			}
 			n := w
 			it.height--
@@ -573,75 +1592,222 @@ func (it *AvlIter) Prev() Item {
 
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *AvlIter) refresh() {
-	if it == nil || it.tree == nil {
-		return
-	}
-	it.generation = it.tree.generation
-	if it.node != nil {
-		cmpFunc := it.tree.cmpFunc
-		param := it.tree.extraParam
-		node := it.node
-		it.height = 0
-		for w := it.tree.root; w != node; {
-			it.stack[it.height] = w
-			it.height++
-			ret := cmpFunc(node.data, w.data, param)
-			if ret > 0 {
-				w = w.links[Right]
-			} else {
-				w = w.links[Left]
-			}
+//locate current item again after tree is changed, node may have been replaced by its copy
+//return false if current item is deleted, iterator is left alone then
+func (it *AvlSetIter[T]) refresh() bool {
+	cmpFunc := it.tree.cmpFunc
+	node := it.node
+	it.height = 0
+	for w := it.tree.root; w != nil; {
+		ret := cmpFunc(node.data, w.data)
+		if ret == 0 {
+			it.node = w
+			it.generation = it.tree.generation
+			return true
+		}
+		it.stack[it.height] = w
+		it.height++
+		if ret > 0 {
+			w = w.links[Right]
+		} else {
+			w = w.links[Left]
 		}
 	}
+	return false
 }
 
-func (it *AvlIter) Current() Item {
+//report whether tree is unchanged since iterator was positioned,
+//panic with ErrConcurrentModification if not and policy is IterFailFast
+func (it *AvlSetIter[T]) synced() bool {
+	if it.generation == it.tree.generation {
+		return true
+	}
+	if it.policy == IterFailFast {
+		panic(ErrConcurrentModification)
+	}
+	return false
+}
+
+func (it *AvlSetIter[T]) Current() (item T, ok bool) {
 	if it == nil || it.node == nil {
-		return nil
+		return
+	}
+	return it.node.data, true
+}
+
+//return index of current item in sorted order
+//return -1 if iterator is at nil position
+func (it *AvlSetIter[T]) Index() int {
+	if it == nil || it.tree == nil || it.node == nil {
+		return -1
 	}
-	return it.node.data
+	if !it.synced() && !it.refresh() {
+		return -1
+	}
+	idx := it.node.links[Left].subSize()
+	for i, n := it.height-1, it.node; i >= 0; i, n = i-1, it.stack[i] {
+		if it.stack[i].links[Right] == n {
+			idx += it.stack[i].links[Left].subSize() + 1
+		}
+	}
+	return idx
 }
 
 //don't change key part of item
-func (it *AvlIter) Replace(new Item) Item {
-	if it == nil || it.node == nil || new == nil {
-		return nil
+func (it *AvlSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
+	}
+	if it.node.epoch != it.tree.epoch {
+		//node is shared, copy the path down to it
+		it.Insert(it.node.data)
 	}
 	old := it.node.data
 	it.node.data = new
-	return old
+	it.tree.fixKey(new)
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *AvlSetIter[T]) Delete() (item T, ok bool) {
+	return it.delete(Right)
 }
 
-func (it *AvlIter) CopyFrom(other *AvlIter) Item {
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *AvlSetIter[T]) DeletePrev() (item T, ok bool) {
+	return it.delete(Left)
+}
+
+//delete current item and move to its neighbour in direction dir,
+//the tree is changed by the iterator itself, so it stays valid
+func (it *AvlSetIter[T]) delete(dir int) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
+	}
+	t := it.tree
+	var (
+		pa  [avlMaxHeight]*node[T] //path to current node, pa[0] is header node whose left link is root
+		da  [avlMaxHeight]byte     //direction taken at each node of pa
+		ext [avlMaxHeight]*node[T] //path to neighbour below pa
+		n   int                    //number of nodes in ext
+	)
+	//read directions off the stack before shared nodes are cloned
+	k := it.height + 1
+	for i := 0; i < it.height; i++ {
+		next := it.node
+		if i+1 < it.height {
+			next = it.stack[i+1]
+		}
+		if it.stack[i].links[Right] == next {
+			da[i+1] = Right
+		} else {
+			da[i+1] = Left
+		}
+	}
+	head := node[T]{links: [ChildNum]*node[T]{Left: t.root}}
+	pa[0] = &head
+	for i := 1; i < k; i++ {
+		pa[i] = t.ownChild(pa[i-1], int(da[i-1]))
+	}
+	w := t.ownChild(pa[k-1], int(da[k-1]))
+
+	//find the neighbour before deletion, owning its path so rebalancing won't clone it,
+	//its new stack is pa[1:prefix] plus ext, valid if no rotation at level top or above
+	var (
+		nb          *node[T]
+		prefix, top int
+		s           *node[T] //successor which takes place of w
+	)
+	if w.links[Right] != nil {
+		for s = t.ownChild(w, Right); s.links[Left] != nil; s = t.ownChild(s, Left) {
+		}
+	}
+	if w.links[dir] == nil {
+		//neighbour is the nearest ancestor we left in opposite direction
+		for i := k - 1; i > 0; i-- {
+			if int(da[i]) == 1-dir {
+				nb, prefix, top = pa[i], i, i
+				break
+			}
+		}
+	} else if dir == Right {
+		nb, prefix, top = s, k, k
+	} else {
+		prefix, top = k, k-1
+		if s != nil {
+			ext[n] = s
+			n++
+			top = k
+		}
+		for nb = t.ownChild(w, Left); nb.links[Right] != nil; nb = t.ownChild(nb, Right) {
+			ext[n] = nb
+			n++
+		}
+	}
+
+	_, rot := t.deleteAt(&pa, &da, k)
+	it.generation = t.generation
+	it.node = nb
+	if nb == nil {
+		it.height = 0
+		return
+	}
+	if top < rot {
+		it.height = copy(it.stack[:], pa[1:prefix])
+		it.height += copy(it.stack[it.height:], ext[:n])
+		return nb.data, true
+	}
+	//nodes from level rot down were rotated, search neighbour below them only
+	it.height = copy(it.stack[:], pa[1:rot])
+	for x := pa[rot-1].links[da[rot-1]]; x != nb; {
+		it.stack[it.height] = x
+		it.height++
+		if t.cmpFunc(nb.data, x.data) < 0 {
+			x = x.links[Left]
+		} else {
+			x = x.links[Right]
+		}
+	}
+	return nb.data, true
+}
+
+func (it *AvlSetIter[T]) CopyFrom(other *AvlSetIter[T]) (item T, ok bool) {
 	if it == nil || other == nil {
-		return nil
+		return
 	}
 	if it != other {
 		it.tree = other.tree
 		it.node = other.node
 		it.generation = other.generation
+		it.policy = other.policy
 		if it.generation == it.tree.generation {
 			it.height = other.height
 			copy(it.stack[:it.height], other.stack[:other.height])
 		}
 	}
 	if it.node == nil {
-		return nil
+		return
 	}
-	return it.node.data
+	return it.node.data, true
 }
 
-func (it *AvlIter) Insert(item Item) (*Item, bool) {
-	if it == nil || it.tree == nil || item == nil {
+func (it *AvlSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
 		return nil, false
 	}
 	addr, ok := it.tree.insert(item)
-
-	it.node = (*node)(unsafe.Pointer(uintptr(unsafe.Pointer(addr)) - unsafe.Offsetof(it.node.data)))
-	it.generation = it.tree.generation - 1
+	//move to item and rebuild stack, which rotations may have broken
+	it.Find(item)
 	return addr, ok
 }
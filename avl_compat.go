@@ -0,0 +1,467 @@
+package bbst
+
+import (
+	"iter"
+	"slices"
+)
+
+//AvlTree is the interface{} flavour of AvlSet,
+//kept as a thin layer over AvlSet[Item] for existing callers
+type AvlTree struct {
+	AvlSet[Item]
+}
+
+func NewAvlTree(cmp Compare, extra interface{}) *AvlTree {
+	if cmp == nil {
+		return nil
+	}
+	return &AvlTree{AvlSet[Item]{cmpFunc: compareOf(cmp, extra), epoch: newEpoch()}}
+}
+
+//create a tree holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n)
+//return ErrNilItem if any item is nil,
+//ErrNotSorted if items are out of order or have duplicates
+func NewAvlTreeFromSorted(cmp Compare, extra interface{}, items []Item) (*AvlTree, error) {
+	t := NewAvlTree(cmp, extra)
+	if t == nil {
+		return nil, nil
+	}
+	if err := checkNil(items); err != nil {
+		return nil, err
+	}
+	if err := t.AvlSet.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *AvlTree) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.AvlSet.Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *AvlTree) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := t.AvlSet.Find(target)
+	return item
+}
+
+//return item at index k in sorted order, k counts from 0
+//return nil if k is out of range
+func (t *AvlTree) Select(k int) Item {
+	if t == nil {
+		return nil
+	}
+	item, _ := t.AvlSet.Select(k)
+	return item
+}
+
+//return number of items in tree less than item
+func (t *AvlTree) Rank(item Item) int {
+	if t == nil || item == nil {
+		return 0
+	}
+	return t.AvlSet.Rank(item)
+}
+
+//return the greatest item less than or equal to item
+//return nil if there is no such item
+func (t *AvlTree) Floor(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.AvlSet.Floor(item)
+	return found
+}
+
+//return the least item greater than or equal to item
+//return nil if there is no such item
+func (t *AvlTree) Ceiling(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.AvlSet.Ceiling(item)
+	return found
+}
+
+//return iterator at the first item not less than item
+func (t *AvlTree) LowerBound(item Item) Iterator {
+	it := NewAvlIter()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *AvlTree) UpperBound(item Item) Iterator {
+	it := NewAvlIter()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+func (t *AvlTree) insert(item Item) (*Item, bool) {
+	if t == nil || item == nil {
+		return nil, false
+	}
+	return t.AvlSet.insert(item)
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *AvlTree) Insert(item Item) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//insert all items in tree, nil items and items equal to one in tree
+//or to an earlier one in items are skipped
+//return number of inserted items and skipped non nil items in ascending order
+func (t *AvlTree) InsertBatch(items []Item) (inserted int, dups []Item) {
+	if t == nil {
+		return
+	}
+	return t.AvlSet.InsertBatch(slices.DeleteFunc(slices.Clone(items), func(item Item) bool {
+		return item == nil
+	}))
+}
+
+//replace item in tree with same key item
+//return old item
+func (t *AvlTree) Replace(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	old, _ := t.AvlSet.Replace(item)
+	return old
+}
+
+//delete item in tree
+//return item if find it
+//else  return nil
+func (t *AvlTree) Delete(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	deleted, _ := t.AvlSet.Delete(item)
+	return deleted
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *AvlTree) Range(lo, hi Item, fn func(item Item) bool) {
+	if t == nil || lo == nil || hi == nil {
+		return
+	}
+	t.AvlSet.Range(lo, hi, fn)
+}
+
+//delete all items in [lo, hi)
+//return number of deleted items
+func (t *AvlTree) DeleteRange(lo, hi Item) int {
+	if t == nil || lo == nil || hi == nil {
+		return 0
+	}
+	return t.AvlSet.DeleteRange(lo, hi)
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied
+//return false and change nothing if items are out of order
+func (t *AvlTree) Join(pivot Item, right *AvlTree) bool {
+	if t == nil || pivot == nil {
+		return false
+	}
+	if right == nil {
+		return t.AvlSet.Join(pivot, nil)
+	}
+	return t.AvlSet.Join(pivot, &right.AvlSet)
+}
+
+//split t into items less than item and items greater than item
+//eq is the item equal to item, or nil if there is no such item
+//t is emptied
+func (t *AvlTree) Split(item Item) (lt *AvlTree, eq Item, gt *AvlTree) {
+	if t == nil || item == nil {
+		return
+	}
+	l, eq, _, r := t.AvlSet.Split(item)
+	return &AvlTree{*l}, eq, &AvlTree{*r}
+}
+
+//split t into the first k items and the rest
+//t is emptied
+func (t *AvlTree) SplitAt(k int) (lt, ge *AvlTree) {
+	if t == nil {
+		return
+	}
+	l, r := t.AvlSet.SplitAt(k)
+	return &AvlTree{*l}, &AvlTree{*r}
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *AvlTree) Union(other *AvlTree, resolve func(a, b Item) Item) {
+	if t == nil || other == nil {
+		return
+	}
+	t.AvlSet.Union(&other.AvlSet, resolve)
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *AvlTree) Intersect(other *AvlTree, resolve func(a, b Item) Item) {
+	if t == nil {
+		return
+	}
+	if other == nil {
+		t.AvlSet.Intersect(nil, resolve)
+		return
+	}
+	t.AvlSet.Intersect(&other.AvlSet, resolve)
+}
+
+//delete all items of other from t, other is emptied
+func (t *AvlTree) Difference(other *AvlTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.AvlSet.Difference(&other.AvlSet)
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *AvlTree) SymmetricDifference(other *AvlTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.AvlSet.SymmetricDifference(&other.AvlSet)
+}
+
+func (t *AvlTree) Copy() *AvlTree {
+	if t == nil {
+		return nil
+	}
+	return &AvlTree{*t.AvlSet.Copy()}
+}
+
+//return a copy of t in O(1) time, see AvlSet.Snapshot
+func (t *AvlTree) Snapshot() *AvlTree {
+	if t == nil {
+		return nil
+	}
+	return &AvlTree{*t.AvlSet.Snapshot()}
+}
+
+//sequence of all items in ascending order
+func (t *AvlTree) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.AvlSet.All()
+}
+
+//sequence of all items in descending order
+func (t *AvlTree) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.AvlSet.Backward()
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *AvlTree) Ascend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.AvlSet.Ascend(from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *AvlTree) Descend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.AvlSet.Descend(from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *AvlTree) Between(lo, hi Item) iter.Seq[Item] {
+	if t == nil || lo == nil || hi == nil {
+		return empty[Item]
+	}
+	return t.AvlSet.Between(lo, hi)
+}
+
+func (t *AvlTree) Iter() Iterator {
+	it := NewAvlIter()
+	return it.HookWith(t)
+}
+
+type AvlIter struct {
+	AvlSetIter[Item]
+}
+
+func NewAvlIter() *AvlIter {
+	return &AvlIter{}
+}
+
+func (it *AvlIter) HookWith(tree *AvlTree) *AvlIter {
+	if it == nil {
+		return nil
+	}
+	it.AvlSetIter.HookWith(&tree.AvlSet)
+	return it
+}
+
+func (it *AvlIter) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.First()
+	return item
+}
+
+func (it *AvlIter) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.Last()
+	return item
+}
+
+func (it *AvlIter) Find(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.AvlSetIter.Find(item)
+	return found
+}
+
+//move iterator to item at index k in sorted order
+func (it *AvlIter) Seek(k int) Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.Seek(k)
+	return item
+}
+
+//return index of current item, -1 at nil position
+func (it *AvlIter) Index() int {
+	if it == nil {
+		return -1
+	}
+	return it.AvlSetIter.Index()
+}
+
+//move iterator to the least item greater than or equal to item
+func (it *AvlIter) SeekGE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.AvlSetIter.SeekGE(item)
+	return found
+}
+
+//move iterator to the least item greater than item
+func (it *AvlIter) SeekGT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.AvlSetIter.SeekGT(item)
+	return found
+}
+
+//move iterator to the greatest item less than or equal to item
+func (it *AvlIter) SeekLE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.AvlSetIter.SeekLE(item)
+	return found
+}
+
+//move iterator to the greatest item less than item
+func (it *AvlIter) SeekLT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.AvlSetIter.SeekLT(item)
+	return found
+}
+
+func (it *AvlIter) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.Next()
+	return item
+}
+
+func (it *AvlIter) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.Prev()
+	return item
+}
+
+func (it *AvlIter) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.Current()
+	return item
+}
+
+//don't change key part of item
+func (it *AvlIter) Replace(new Item) Item {
+	if it == nil || new == nil {
+		return nil
+	}
+	old, _ := it.AvlSetIter.Replace(new)
+	return old
+}
+
+//delete current item and move to its successor, return the successor
+func (it *AvlIter) Delete() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.Delete()
+	return item
+}
+
+//delete current item and move to its predecessor, return the predecessor
+func (it *AvlIter) DeletePrev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.DeletePrev()
+	return item
+}
+
+func (it *AvlIter) CopyFrom(other *AvlIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.AvlSetIter.CopyFrom(&other.AvlSetIter)
+	return item
+}
+
+func (it *AvlIter) Insert(item Item) (*Item, bool) {
+	if it == nil || item == nil {
+		return nil, false
+	}
+	return it.AvlSetIter.Insert(item)
+}
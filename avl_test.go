@@ -6,7 +6,7 @@ import (
 	"testing"
 )
 
-func (n *node) print(lvl int) {
+func (n *node[T]) print(lvl int) {
 	if n == nil {
 		return
 	}
@@ -26,7 +26,7 @@ func (n *node) print(lvl int) {
 	}
 }
 
-func recurseVerifyTree(t *testing.T, node *node, ok *bool, count *int, min, max int, height *int) {
+func recurseVerifyTree(t *testing.T, node *node[Item], ok *bool, count *int, min, max int, height *int) {
 	var (
 		d         int
 		subcount  [ChildNum]int
@@ -50,6 +50,10 @@ func recurseVerifyTree(t *testing.T, node *node, ok *bool, count *int, min, max
 	recurseVerifyTree(t, node.links[Right], ok, &subcount[Right], d+1, max, &subheight[Right])
 
 	*count = 1 + subcount[Left] + subcount[Right]
+	if node.size != *count {
+		t.Errorf("Node %d has size %d, but subtree has %d nodes.\n", d, node.size, *count)
+		*ok = false
+	}
 	maxHeight := 0
 	if subheight[Left] > subheight[Right] {
 		maxHeight = subheight[Left]
@@ -214,14 +218,14 @@ func (it *AvlIter) check(t *testing.T, i, n int, title string) bool {
 	return ok
 }
 
-func compareTrees(t *testing.T, a, b *node) bool {
+func compareTrees(t *testing.T, a, b *node[Item]) bool {
 	if a == nil && b == nil {
 		return true
 	}
 	if a.data != b.data ||
 		((a.links[Left] != nil) != (b.links[Left] != nil)) ||
 		((a.links[Right] != nil) != (b.links[Right] != nil)) ||
-		a.balance != b.balance {
+		a.balance != b.balance || a.size != b.size {
 		t.Logf("Copied nodes differ: a=%d (bal=%d) b=%d (bal=%d) a:", a.data, a.balance, b.data, b.balance)
 		if a.links[Left] != nil {
 			t.Logf("l")
@@ -0,0 +1,82 @@
+package bbst
+
+import (
+	"iter"
+	"slices"
+	"sync"
+)
+
+//operations InsertBatch needs from a set of type S
+type batchTab[T, S any] interface {
+	Count() int
+	Insert(item T) bool
+	All() iter.Seq[T]
+	Union(other S, resolve func(a, b T) T)
+	loadSorted(items []T) error
+	emptyLike() S
+}
+
+//insert items in t, an item equal to one in t or to an earlier one in items is skipped
+//items are sorted first, then merged into t in the cheapest way for the sizes:
+//one by one for a small batch, by flattening t and rebuilding it
+//for a batch not smaller than t, else by union with a tree built from the batch
+//return number of inserted items and skipped items in ascending order
+func insertBatch[T any, S batchTab[T, S]](t S, cmp func(a, b T) int, items []T) (inserted int, dups []T) {
+	batch := slices.Clone(items)
+	slices.SortStableFunc(batch, cmp)
+	//keep first of each run of equal items
+	k := 0
+	for i, item := range batch {
+		if i > 0 && cmp(item, batch[k-1]) == 0 {
+			dups = append(dups, item)
+			continue
+		}
+		batch[k] = item
+		k++
+	}
+	batch = batch[:k]
+	n, m := t.Count(), len(batch)
+	switch {
+	case m == 0:
+		return 0, dups
+	case m < n/16:
+		for _, item := range batch {
+			if t.Insert(item) {
+				inserted++
+			} else {
+				dups = append(dups, item)
+			}
+		}
+		slices.SortStableFunc(dups, cmp)
+		return
+	case m >= n:
+		merged := make([]T, 0, n+m)
+		j := 0
+		for item := range t.All() {
+			for ; j < m && cmp(batch[j], item) < 0; j++ {
+				merged = append(merged, batch[j])
+			}
+			if j < m && cmp(batch[j], item) == 0 {
+				dups = append(dups, batch[j])
+				j++
+			}
+			merged = append(merged, item)
+		}
+		merged = append(merged, batch[j:]...)
+		t.loadSorted(merged)
+	default:
+		//other must compute augmented data the way t does
+		other := t.emptyLike()
+		other.loadSorted(batch)
+		//halves of union may run in parallel
+		var mu sync.Mutex
+		t.Union(other, func(a, b T) T {
+			mu.Lock()
+			dups = append(dups, b)
+			mu.Unlock()
+			return a
+		})
+	}
+	slices.SortStableFunc(dups, cmp)
+	return t.Count() - n, dups
+}
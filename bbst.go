@@ -1,5 +1,11 @@
 package bbst
 
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
 const (
 	Left = iota
 	Right
@@ -36,3 +42,106 @@ type SymTab interface {
 	Delete(item Item) Item
 	Iter() Iterator
 }
+
+//IterPolicy decides what an iterator does when its tree is changed
+//by anything but the iterator itself, set it with SetIterPolicy of the tree
+type IterPolicy int
+
+const (
+	//move on from the key of current item, Next returns its successor
+	//and Prev its predecessor, even if the item itself was deleted
+	IterResync IterPolicy = iota
+	//panic with ErrConcurrentModification on the next move
+	IterFailFast
+	//iterate a snapshot taken when the iterator is hooked with tree,
+	//it is O(1) for AvlSet and RbSet, but an O(n) copy for PAvlSet and PRbSet,
+	//Insert, Replace and Delete through the iterator change the snapshot only
+	IterSnapshot
+)
+
+var ErrConcurrentModification = errors.New("bbst: tree changed during iteration")
+
+var (
+	ErrNotSorted = errors.New("bbst: items are not in strictly ascending order")
+	ErrNilItem   = errors.New("bbst: nil item")
+)
+
+//return ErrNotSorted unless items are in strictly ascending order by cmp,
+//which rejects equal items too
+func checkSorted[T any](cmp func(a, b T) int, items []T) error {
+	for i := 1; i < len(items); i++ {
+		if cmp(items[i-1], items[i]) >= 0 {
+			return ErrNotSorted
+		}
+	}
+	return nil
+}
+
+//return ErrNilItem if any of items is nil
+func checkNil(items []Item) error {
+	for _, item := range items {
+		if item == nil {
+			return ErrNilItem
+		}
+	}
+	return nil
+}
+
+//type-parameterized counterpart of Iterator,
+//ok is false when the iterator walks off either end of the tree
+type IteratorOf[T any] interface {
+	First() (item T, ok bool)
+	Last() (item T, ok bool)
+	Prev() (item T, ok bool)
+	Next() (item T, ok bool)
+	Current() (item T, ok bool)
+}
+
+//type-parameterized counterpart of SymTab,
+//implemented by AvlSet, PAvlSet, RbSet, PRbSet and RcuSet
+type SymTabOf[T any] interface {
+	Count() int
+	Find(target T) (found T, ok bool)
+	Insert(item T) bool
+	Replace(item T) (old T, ok bool)
+	Delete(item T) (deleted T, ok bool)
+	Iter() IteratorOf[T]
+}
+
+//adapt a Compare and its extra param to the comparator used by the generic trees
+func compareOf(cmp Compare, extra interface{}) func(a, b Item) int {
+	if cmp == nil {
+		return nil
+	}
+	return func(a, b Item) int {
+		return cmp(a, b, extra)
+	}
+}
+
+//subtrees with less items than this are not worth a goroutine
+const parallelCutoff = 1 << 12
+
+//run f and g, concurrently if n, the number of items they work on, is big enough
+func fork(n int, f, g func()) {
+	if n < parallelCutoff {
+		f()
+		g()
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+	g()
+	wg.Wait()
+}
+
+//source of epochs for copy on write trees, every tree and every snapshot takes a new one,
+//so an epoch is never shared by two trees
+var epochs atomic.Uint64
+
+func newEpoch() uint64 {
+	return epochs.Add(1)
+}
@@ -2,6 +2,7 @@ package bbst
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
 )
 
@@ -50,6 +51,50 @@ func BenchmarkInsert(b *testing.B) {
 			}
 		}
 	})
+	b.Run(fmt.Sprintf("splay/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tree := NewSplayTree(intCmp, nil)
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("treap/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tree := NewTreapTree(intCmp, nil)
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("scapegoat/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tree := NewScapegoatTree(intCmp, nil)
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("wavl/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tree := NewWavlTree(intCmp, nil)
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+		}
+	})
 
 }
 
@@ -107,6 +152,58 @@ func BenchmarkFind(b *testing.B) {
 			}
 		}
 	})
+	b.Run(fmt.Sprintf("splay/%d", *treeSize), func(b *testing.B) {
+		tree := NewSplayTree(intCmp, nil)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, elem := range insertArr {
+				tree.Find(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("treap/%d", *treeSize), func(b *testing.B) {
+		tree := NewTreapTree(intCmp, nil)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, elem := range insertArr {
+				tree.Find(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("scapegoat/%d", *treeSize), func(b *testing.B) {
+		tree := NewScapegoatTree(intCmp, nil)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, elem := range insertArr {
+				tree.Find(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("wavl/%d", *treeSize), func(b *testing.B) {
+		tree := NewWavlTree(intCmp, nil)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, elem := range insertArr {
+				tree.Find(elem)
+			}
+		}
+	})
 
 }
 
@@ -174,4 +271,201 @@ func BenchmarkDelete(b *testing.B) {
 			}
 		}
 	})
+	b.Run(fmt.Sprintf("splay/%d", *treeSize), func(b *testing.B) {
+		tree := NewSplayTree(intCmp, nil)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Delete(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("treap/%d", *treeSize), func(b *testing.B) {
+		tree := NewTreapTree(intCmp, nil)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Delete(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("scapegoat/%d", *treeSize), func(b *testing.B) {
+		tree := NewScapegoatTree(intCmp, nil)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Delete(elem)
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("wavl/%d", *treeSize), func(b *testing.B) {
+		tree := NewWavlTree(intCmp, nil)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			for _, elem := range insertArr {
+				tree.Insert(elem)
+			}
+			b.StartTimer()
+
+			for _, elem := range insertArr {
+				tree.Delete(elem)
+			}
+		}
+	})
+}
+
+//find with a few hot keys, 7 of every 8 lookups go to 8 keys
+func BenchmarkFindSkewed(b *testing.B) {
+	hot := insertArr[:min(8, len(insertArr))]
+	keys := make([]int, len(insertArr))
+	for i, elem := range insertArr {
+		if i%8 == 0 {
+			keys[i] = elem
+		} else {
+			keys[i] = hot[i%len(hot)]
+		}
+	}
+	for _, c := range []struct {
+		name string
+		tree SymTab
+	}{
+		{"avlNoParent", NewAvlTree(intCmp, nil)},
+		{"rbNoParent", NewRbTree(intCmp, nil)},
+		{"splay", NewSplayTree(intCmp, nil)},
+	} {
+		b.Run(fmt.Sprintf("%s/%d", c.name, *treeSize), func(b *testing.B) {
+			for _, elem := range insertArr {
+				c.tree.Insert(elem)
+			}
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				for _, key := range keys {
+					c.tree.Find(key)
+				}
+			}
+		})
+	}
+}
+
+//live heap bytes per item of trees of int, garbage made while building is not counted
+func BenchmarkMemoryPerNode(b *testing.B) {
+	for _, c := range []struct {
+		name  string
+		build func() any
+	}{
+		{"avlNoParent", func() any { return buildSet(NewOrderedAvlSet[int]()) }},
+		{"avlWithParent", func() any { return buildSet(NewOrderedPAvlSet[int]()) }},
+		{"rbNoParent", func() any { return buildSet(NewOrderedRbSet[int]()) }},
+		{"rbWithParent", func() any { return buildSet(NewOrderedPRbSet[int]()) }},
+		{"avlThreaded", func() any { return buildSet(NewOrderedTAvlSet[int]()) }},
+		{"rbThreaded", func() any { return buildSet(NewOrderedTRbSet[int]()) }},
+		{"splay", func() any { return buildSet(NewOrderedSplaySet[int]()) }},
+		{"treap", func() any { return buildSet(NewOrderedTreapSet[int]()) }},
+		{"scapegoat", func() any { return buildSet(NewOrderedScapegoatSet[int]()) }},
+		{"wavl", func() any { return buildSet(NewOrderedWavlSet[int]()) }},
+	} {
+		b.Run(fmt.Sprintf("%s/%d", c.name, *treeSize), func(b *testing.B) {
+			var (
+				ms    runtime.MemStats
+				bytes uint64
+			)
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				before := ms.HeapAlloc
+				set := c.build()
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				bytes += ms.HeapAlloc - before
+				runtime.KeepAlive(set)
+			}
+			b.ReportMetric(float64(bytes)/float64(b.N*max(len(insertArr), 1)), "B/node")
+		})
+	}
+}
+
+//insert all of insertArr in set, return set
+func buildSet[S interface{ Insert(item int) bool }](set S) S {
+	for _, elem := range insertArr {
+		set.Insert(elem)
+	}
+	return set
+}
+
+func BenchmarkFromSorted(b *testing.B) {
+	items := make([]Item, *treeSize)
+	for i := range items {
+		items[i] = i
+	}
+	b.Run(fmt.Sprintf("avlNoParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewAvlTreeFromSorted(intCmp, nil, items)
+		}
+	})
+	b.Run(fmt.Sprintf("avlWithParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewPAvlTreeFromSorted(intCmp, nil, items)
+		}
+	})
+	b.Run(fmt.Sprintf("rbNoParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewRbTreeFromSorted(intCmp, nil, items)
+		}
+	})
+	b.Run(fmt.Sprintf("rbWithParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewPRbTreeFromSorted(intCmp, nil, items)
+		}
+	})
+}
+
+func BenchmarkInsertBatch(b *testing.B) {
+	batch := make([]Item, len(insertArr))
+	for i, elem := range insertArr {
+		batch[i] = elem
+	}
+	b.Run(fmt.Sprintf("avlNoParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewAvlTree(intCmp, nil).InsertBatch(batch)
+		}
+	})
+	b.Run(fmt.Sprintf("avlWithParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewPAvlTree(intCmp, nil).InsertBatch(batch)
+		}
+	})
+	b.Run(fmt.Sprintf("rbNoParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewRbTree(intCmp, nil).InsertBatch(batch)
+		}
+	})
+	b.Run(fmt.Sprintf("rbWithParent/%d", *treeSize), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewPRbTree(intCmp, nil).InsertBatch(batch)
+		}
+	})
 }
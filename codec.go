@@ -0,0 +1,195 @@
+package bbst
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+//Codec encodes items for MarshalBinary and decodes them for UnmarshalBinary,
+//set it with SetCodec of the tree
+type Codec[T any] interface {
+	//append encoding of item to buf and return the extended buffer
+	AppendItem(buf []byte, item T) ([]byte, error)
+	//decode the item at the start of buf
+	//return it with the number of bytes it takes
+	DecodeItem(buf []byte) (item T, n int, err error)
+}
+
+var (
+	ErrNoCodec     = errors.New("bbst: tree has no codec")
+	ErrBadEncoding = errors.New("bbst: malformed tree encoding")
+)
+
+/*
+encoding of a tree:
+
+	kind byte, version byte, uvarint number of nodes,
+	one shape byte per node in preorder,
+	items encoded by codec in ascending order
+
+shape byte:
+
+	bit 0 is set if node has left child, bit 1 if it has right child,
+	bits 2-3 are balance factor + 1 of avl node,
+	bit 2 is color of red black node
+*/
+const (
+	encVersion = 1
+
+	encAvl  byte = 'a'
+	encPAvl byte = 'A'
+	encRb   byte = 'r'
+	encPRb  byte = 'R'
+
+	shapeLeft  = 1 << 0
+	shapeRight = 1 << 1
+	shapeShift = 2
+)
+
+//node of any of the four trees, as seen by the encoder
+type encNode[N any, T any] interface {
+	comparable
+	child(dir int) N
+	dataAddr() *T
+	shape() byte //balance or color bits of shape byte
+}
+
+//encode tree rooted at root, which has count nodes
+func marshalTree[N encNode[N, T], T any](kind byte, root N, count int, codec Codec[T]) ([]byte, error) {
+	if codec == nil {
+		return nil, ErrNoCodec
+	}
+	var null N
+	buf := make([]byte, 0, 2+binary.MaxVarintLen64+count)
+	buf = append(buf, kind, encVersion)
+	buf = binary.AppendUvarint(buf, uint64(count))
+	var shapes func(n N)
+	shapes = func(n N) {
+		s := n.shape() << shapeShift
+		if l := n.child(Left); l != null {
+			s |= shapeLeft
+		}
+		if r := n.child(Right); r != null {
+			s |= shapeRight
+		}
+		buf = append(buf, s)
+		if s&shapeLeft != 0 {
+			shapes(n.child(Left))
+		}
+		if s&shapeRight != 0 {
+			shapes(n.child(Right))
+		}
+	}
+	var items func(n N) error
+	items = func(n N) error {
+		for ; n != null; n = n.child(Right) {
+			if err := items(n.child(Left)); err != nil {
+				return err
+			}
+			var err error
+			if buf, err = codec.AppendItem(buf, *n.dataAddr()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if root != null {
+		shapes(root)
+	}
+	if err := items(root); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+//decode a tree of kind encoded by marshalTree,
+//mk links item with subtrees l and r into a node with balance or color bits s,
+//balance factors and colors are checked, so the tree is a valid one of kind
+//return root and number of nodes
+func unmarshalTree[N comparable, T any](kind byte, data []byte, codec Codec[T], mk func(s byte, l N, item T, r N) N) (root N, count int, err error) {
+	if codec == nil {
+		err = ErrNoCodec
+		return
+	}
+	if len(data) < 2 || data[0] != kind || data[1] != encVersion {
+		err = ErrBadEncoding
+		return
+	}
+	n, k := binary.Uvarint(data[2:])
+	if k <= 0 || n > uint64(len(data)-2-k) {
+		err = ErrBadEncoding
+		return
+	}
+	count = int(n)
+	shapes := data[2+k : 2+k+count]
+	items := data[2+k+count:]
+	avl := kind == encAvl || kind == encPAvl
+	//build subtree from shapes and items
+	//return it with its height, black height for red black tree, and color
+	var build func() (n N, h int, c byte, err error)
+	build = func() (n N, h int, c byte, err error) {
+		if len(shapes) == 0 {
+			err = ErrBadEncoding
+			return
+		}
+		s := shapes[0]
+		shapes = shapes[1:]
+		var (
+			l, r   N
+			lh, rh int
+			lc, rc byte
+			item   T
+			size   int
+		)
+		if s&shapeLeft != 0 {
+			if l, lh, lc, err = build(); err != nil {
+				return
+			}
+		}
+		if item, size, err = codec.DecodeItem(items); err != nil {
+			return
+		}
+		if size < 0 || size > len(items) {
+			err = ErrBadEncoding
+			return
+		}
+		items = items[size:]
+		if s&shapeRight != 0 {
+			if r, rh, rc, err = build(); err != nil {
+				return
+			}
+		}
+		c = s >> shapeShift
+		if avl {
+			if c > 2 || int(c)-1 != rh-lh {
+				err = ErrBadEncoding
+				return
+			}
+			h = max(lh, rh) + 1
+		} else {
+			if c > red || lh != rh || c == red && (lc == red || rc == red) {
+				err = ErrBadEncoding
+				return
+			}
+			h = lh
+			if c == black {
+				h++
+			}
+		}
+		return mk(c, l, item, r), h, c, nil
+	}
+	if count > 0 {
+		var c byte
+		if root, _, c, err = build(); err != nil {
+			return
+		}
+		if !avl && c == red {
+			err = ErrBadEncoding
+			return
+		}
+	}
+	if len(shapes) != 0 || len(items) != 0 {
+		err = ErrBadEncoding
+	}
+	return
+}
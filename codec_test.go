@@ -0,0 +1,110 @@
+package bbst
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+//codec of int items as varints
+type intCodec struct{}
+
+func (intCodec) AppendItem(buf []byte, item Item) ([]byte, error) {
+	return binary.AppendVarint(buf, int64(item.(int))), nil
+}
+
+func (intCodec) DecodeItem(buf []byte) (Item, int, error) {
+	v, n := binary.Varint(buf)
+	if n <= 0 {
+		return nil, 0, errors.New("bad varint")
+	}
+	return int(v), n, nil
+}
+
+func TestMarshalBinary(t *testing.T) {
+	type codecTab interface {
+		SymTab
+		encoding.BinaryMarshaler
+		encoding.BinaryUnmarshaler
+		SetCodec(c Codec[Item])
+	}
+	insArr := genInsertArr(1000, insRandom)
+	delArr := genDeleteArr(insArr, delRandom)[:500]
+	for typ, name := range treeNames {
+		tree := newIntTree(typ).(codecTab)
+		if _, err := tree.MarshalBinary(); err != ErrNoCodec {
+			t.Errorf("%s: marshal without codec returned %v\n", name, err)
+		}
+		tree.SetCodec(intCodec{})
+		for _, elem := range insArr {
+			tree.Insert(elem)
+		}
+		for _, elem := range delArr {
+			tree.Delete(elem)
+		}
+		data, err := tree.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: marshal failed: %v\n", name, err)
+		}
+		loaded := newIntTree(typ).(codecTab)
+		loaded.SetCodec(intCodec{})
+		loaded.Insert(-1) //replaced by the loaded items
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: unmarshal failed: %v\n", name, err)
+		}
+		var want []int
+		for _, elem := range insArr {
+			if tree.Find(elem) != nil {
+				want = append(want, elem)
+			}
+		}
+		var same bool
+		switch typ {
+		case avlNoParent:
+			same = verifyTree(t, loaded.(*AvlTree), want) && compareTrees(t, tree.(*AvlTree).root, loaded.(*AvlTree).root)
+		case avlWithParent:
+			same = verifyPTree(t, loaded.(*PAvlTree), want) && comparePAvlTrees(t, tree.(*PAvlTree).root, loaded.(*PAvlTree).root)
+		case rbNoParent:
+			same = verifyRbTree(t, loaded.(*RbTree), want) && compareRbTrees(t, tree.(*RbTree).root, loaded.(*RbTree).root)
+		case rbWithParent:
+			same = verifyPRbTree(t, loaded.(*PRbTree), want) && comparePRbTrees(t, tree.(*PRbTree).root, loaded.(*PRbTree).root)
+		}
+		if !same {
+			t.Errorf("%s: loaded tree differs from the marshaled one\n", name)
+		}
+		//loaded tree is a working one
+		for _, elem := range delArr {
+			loaded.Insert(elem)
+		}
+		if loaded.Count() != len(insArr) {
+			t.Errorf("%s: count is %d after reinsert, but should be %d\n", name, loaded.Count(), len(insArr))
+		}
+
+		empty := newIntTree(typ).(codecTab)
+		empty.SetCodec(intCodec{})
+		if e, err := empty.MarshalBinary(); err != nil || loaded.UnmarshalBinary(e) != nil || loaded.Count() != 0 {
+			t.Errorf("%s: round trip of empty tree failed\n", name)
+		}
+
+		//malformed input is rejected and leaves tree as it was
+		bad := [][]byte{
+			nil,
+			data[:len(data)-1],
+			append(append([]byte{}, data...), 0),
+			append([]byte{data[0] ^ 0x20}, data[1:]...),
+		}
+		//flip balance or color bits of the root shape byte
+		flipped := append([]byte{}, data...)
+		flipped[2+len(binary.AppendUvarint(nil, uint64(tree.Count())))] ^= 1 << shapeShift
+		bad = append(bad, flipped)
+		for i, b := range bad {
+			if err := loaded.UnmarshalBinary(b); err == nil {
+				t.Errorf("%s: unmarshal of bad data %d should fail\n", name, i)
+			}
+		}
+		if loaded.Count() != 0 {
+			t.Errorf("%s: tree changed by failed unmarshal\n", name)
+		}
+	}
+}
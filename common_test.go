@@ -1,10 +1,14 @@
 package bbst
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"iter"
 	"math/rand"
 	"os"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -36,6 +40,12 @@ const (
 	avlWithParent
 	rbNoParent
 	rbWithParent
+	avlThreaded
+	rbThreaded
+	splayTree
+	treapTree
+	scapegoatTree
+	wavlTree
 )
 
 func genBalancedTree(min, max int, ret []int) {
@@ -134,6 +144,18 @@ func TestInt(t *testing.T) {
 			testRbCorrectness(t, insertArr, deleteArr)
 		case rbWithParent:
 			testPRbCorrectness(t, insertArr, deleteArr)
+		case avlThreaded:
+			testCompatCorrectness[*TAvlTree, TAvlIter](t, NewTAvlTree, verifyTAvlTree, insertArr, deleteArr)
+		case rbThreaded:
+			testCompatCorrectness[*TRbTree, TRbIter](t, NewTRbTree, verifyTRbTree, insertArr, deleteArr)
+		case splayTree:
+			testCompatCorrectness[*SplayTree, SplayIter](t, NewSplayTree, verifySplayTree, insertArr, deleteArr)
+		case treapTree:
+			testCompatCorrectness[*TreapTree, TreapIter](t, NewTreapTree, verifyTreapTree, insertArr, deleteArr)
+		case scapegoatTree:
+			testCompatCorrectness[*ScapegoatTree, ScapegoatIter](t, NewScapegoatTree, verifyScapegoatTree, insertArr, deleteArr)
+		case wavlTree:
+			testCompatCorrectness[*WavlTree, WavlIter](t, NewWavlTree, verifyWavlTree, insertArr, deleteArr)
 		}
 	case overflowTest:
 		switch *treeType {
@@ -145,6 +167,18 @@ func TestInt(t *testing.T) {
 			testRbOverflow(t, insertArr)
 		case rbWithParent:
 			testPRbOverflow(t, insertArr)
+		case avlThreaded:
+			testCompatOverflow[*TAvlTree, TAvlIter](t, NewTAvlTree, verifyTAvlTree, insertArr)
+		case rbThreaded:
+			testCompatOverflow[*TRbTree, TRbIter](t, NewTRbTree, verifyTRbTree, insertArr)
+		case splayTree:
+			testCompatOverflow[*SplayTree, SplayIter](t, NewSplayTree, verifySplayTree, insertArr)
+		case treapTree:
+			testCompatOverflow[*TreapTree, TreapIter](t, NewTreapTree, verifyTreapTree, insertArr)
+		case scapegoatTree:
+			testCompatOverflow[*ScapegoatTree, ScapegoatIter](t, NewScapegoatTree, verifyScapegoatTree, insertArr)
+		case wavlTree:
+			testCompatOverflow[*WavlTree, WavlIter](t, NewWavlTree, verifyWavlTree, insertArr)
 		}
 	}
 }
@@ -194,6 +228,18 @@ func TestMap(t *testing.T) {
 		m = NewRbTree(mapCmp, nil)
 	case rbWithParent:
 		m = NewPRbTree(mapCmp, nil)
+	case avlThreaded:
+		m = NewTAvlTree(mapCmp, nil)
+	case rbThreaded:
+		m = NewTRbTree(mapCmp, nil)
+	case splayTree:
+		m = NewSplayTree(mapCmp, nil)
+	case treapTree:
+		m = NewTreapTree(mapCmp, nil)
+	case scapegoatTree:
+		m = NewScapegoatTree(mapCmp, nil)
+	case wavlTree:
+		m = NewWavlTree(mapCmp, nil)
 	}
 	m.Insert(kv{"GPU", 15})
 	m.Insert(kv{"RAM", 20})
@@ -224,6 +270,18 @@ func TestMultiMap(t *testing.T) {
 		m = NewRbTree(multiMapCmp, nil)
 	case rbWithParent:
 		m = NewPRbTree(multiMapCmp, nil)
+	case avlThreaded:
+		m = NewTAvlTree(multiMapCmp, nil)
+	case rbThreaded:
+		m = NewTRbTree(multiMapCmp, nil)
+	case splayTree:
+		m = NewSplayTree(multiMapCmp, nil)
+	case treapTree:
+		m = NewTreapTree(multiMapCmp, nil)
+	case scapegoatTree:
+		m = NewScapegoatTree(multiMapCmp, nil)
+	case wavlTree:
+		m = NewWavlTree(multiMapCmp, nil)
 	}
 	str := "this is it"
 	for pos, char := range str {
@@ -248,7 +306,7 @@ func TestMultiMap(t *testing.T) {
 }
 
 var treeSize = flag.Int("size", 15, "number of node in tree")
-var treeType = flag.Int("type", avlNoParent, "test tree type, 0(avlNoParent), 1(avlWithParent), 2(rbNoParent), 3(rbWithParent)")
+var treeType = flag.Int("type", avlNoParent, "test tree type, 0(avlNoParent), 1(avlWithParent), 2(rbNoParent), 3(rbWithParent), 4(avlThreaded), 5(rbThreaded), 6(splayTree), 7(treapTree), 8(scapegoatTree), 9(wavlTree)")
 var testMode = flag.Int("mode", correctTest, "test mode of tree(0|1)")
 var verbose = flag.Int("verbose", 0, "turn up test output message verbosity level(0|1|2|3)")
 var insOrder = flag.Int("insOrder", insRandom, "insort array order(0|1|2|3|4|5)")
@@ -276,7 +334,7 @@ func TestMain(m *testing.M) {
 		fmt.Printf("invalid test mode\n")
 		os.Exit(1)
 	}
-	if *treeType < avlNoParent || *treeType > rbWithParent {
+	if *treeType < avlNoParent || *treeType > wavlTree {
 		fmt.Printf("invalid tree type\n")
 		os.Exit(1)
 	}
@@ -294,3 +352,1294 @@ func TestMain(m *testing.M) {
 	exitCode := m.Run()
 	os.Exit(exitCode)
 }
+
+var treeNames = [...]string{"avlNoParent", "avlWithParent", "rbNoParent", "rbWithParent"}
+
+func newIntSet(typ int) SymTabOf[int] {
+	switch typ {
+	case avlNoParent:
+		return NewOrderedAvlSet[int]()
+	case avlWithParent:
+		return NewOrderedPAvlSet[int]()
+	case rbNoParent:
+		return NewOrderedRbSet[int]()
+	case rbWithParent:
+		return NewOrderedPRbSet[int]()
+	}
+	return nil
+}
+
+func TestSetOf(t *testing.T) {
+	for typ, name := range treeNames {
+		set := newIntSet(typ)
+		for _, elem := range insertArr {
+			if !set.Insert(elem) {
+				t.Fatalf("%s: insert %d failed\n", name, elem)
+			}
+		}
+		if set.Insert(insertArr[0]) {
+			t.Errorf("%s: duplicate %d inserted\n", name, insertArr[0])
+		}
+		if set.Count() != len(insertArr) {
+			t.Errorf("%s: count is %d, but should be %d\n", name, set.Count(), len(insertArr))
+		}
+		it := set.Iter()
+		i := 0
+		for item, ok := it.First(); ok; item, ok = it.Next() {
+			if item != i {
+				t.Errorf("%s: iter at %d, but should be at %d\n", name, item, i)
+			}
+			i++
+		}
+		if i != len(insertArr) {
+			t.Errorf("%s: traversal visits %d items, but should be %d\n", name, i, len(insertArr))
+		}
+		for i, elem := range deleteArr {
+			if item, ok := set.Delete(elem); !ok || item != elem {
+				t.Errorf("%s: delete %d returned %d, %v\n", name, elem, item, ok)
+			}
+			if _, ok := set.Find(elem); ok {
+				t.Errorf("%s: deleted %d still found\n", name, elem)
+			}
+			if set.Count() != len(deleteArr)-i-1 {
+				t.Errorf("%s: count is %d, but should be %d\n", name, set.Count(), len(deleteArr)-i-1)
+			}
+		}
+		if _, ok := set.Delete(0); ok {
+			t.Errorf("%s: deletion from empty set succeeded\n", name)
+		}
+	}
+}
+
+func newTree(typ int, cmp Compare) SymTab {
+	switch typ {
+	case avlNoParent:
+		return NewAvlTree(cmp, nil)
+	case avlWithParent:
+		return NewPAvlTree(cmp, nil)
+	case rbNoParent:
+		return NewRbTree(cmp, nil)
+	case rbWithParent:
+		return NewPRbTree(cmp, nil)
+	}
+	return nil
+}
+
+func newIntTree(typ int) SymTab {
+	return newTree(typ, intCmp)
+}
+
+func TestOrderStatistic(t *testing.T) {
+	type rankTab interface {
+		SymTab
+		Select(k int) Item
+		Rank(item Item) int
+	}
+	type rankIter interface {
+		Iterator
+		Seek(k int) Item
+		Index() int
+	}
+	n := len(insertArr)
+	for typ, name := range treeNames {
+		tree := newIntTree(typ).(rankTab)
+		//insert even numbers only, so odd numbers probe gaps
+		for _, elem := range insertArr {
+			tree.Insert(2 * elem)
+		}
+		for i := 0; i < n; i++ {
+			if item := tree.Select(i); item != 2*i {
+				t.Errorf("%s: select %d got %v, but should be %d\n", name, i, item, 2*i)
+			}
+			if r := tree.Rank(2 * i); r != i {
+				t.Errorf("%s: rank of %d is %d, but should be %d\n", name, 2*i, r, i)
+			}
+			if r := tree.Rank(2*i + 1); r != i+1 {
+				t.Errorf("%s: rank of %d is %d, but should be %d\n", name, 2*i+1, r, i+1)
+			}
+		}
+		if tree.Select(-1) != nil || tree.Select(n) != nil {
+			t.Errorf("%s: select out of range should be nil\n", name)
+		}
+		it := tree.Iter().(rankIter)
+		for i := 0; i < n; i++ {
+			if item := it.Seek(i); item != 2*i {
+				t.Errorf("%s: seek %d got %v, but should be %d\n", name, i, item, 2*i)
+			}
+			if idx := it.Index(); idx != i {
+				t.Errorf("%s: index after seek %d is %d\n", name, i, idx)
+			}
+			next := it.Next()
+			if i+1 < n && next != 2*(i+1) || i+1 == n && next != nil {
+				t.Errorf("%s: next after seek %d got %v\n", name, i, next)
+			}
+		}
+		if it.Seek(n) != nil || it.Index() != -1 {
+			t.Errorf("%s: seek out of range should leave iterator at nil\n", name)
+		}
+		//ranks stay correct while deleting
+		for i, elem := range deleteArr {
+			tree.Delete(2 * elem)
+			if i+1 < n {
+				k := (i * 7) % (n - i - 1)
+				item := tree.Select(k)
+				if item == nil || tree.Rank(item) != k {
+					t.Errorf("%s: select/rank mismatch at %d after deleting %d\n", name, k, elem)
+				}
+			}
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	type boundTab interface {
+		SymTab
+		Floor(item Item) Item
+		Ceiling(item Item) Item
+		LowerBound(item Item) Iterator
+		UpperBound(item Item) Iterator
+	}
+	type seekIter interface {
+		Iterator
+		SeekGE(item Item) Item
+		SeekGT(item Item) Item
+		SeekLE(item Item) Item
+		SeekLT(item Item) Item
+	}
+	n := len(insertArr)
+	//brute force reference over the even numbers 0, 2, ..., 2n-2
+	near := func(x int, dir int, eq bool) Item {
+		var found Item
+		for v := 0; v < 2*n; v += 2 {
+			if (eq && v == x) || (dir == Right && v > x && found == nil) || (dir == Left && v < x) {
+				found = v
+			}
+			if eq && v == x {
+				break
+			}
+		}
+		return found
+	}
+	for typ, name := range treeNames {
+		tree := newIntTree(typ).(boundTab)
+		for _, elem := range insertArr {
+			tree.Insert(2 * elem)
+		}
+		it := tree.Iter().(seekIter)
+		for x := -2; x <= 2*n; x++ {
+			if item, want := tree.Floor(x), near(x, Left, true); item != want {
+				t.Errorf("%s: floor of %d got %v, but should be %v\n", name, x, item, want)
+			}
+			if item, want := tree.Ceiling(x), near(x, Right, true); item != want {
+				t.Errorf("%s: ceiling of %d got %v, but should be %v\n", name, x, item, want)
+			}
+			if item, want := tree.LowerBound(x).Current(), near(x, Right, true); item != want {
+				t.Errorf("%s: lower bound of %d got %v, but should be %v\n", name, x, item, want)
+			}
+			if item, want := tree.UpperBound(x).Current(), near(x, Right, false); item != want {
+				t.Errorf("%s: upper bound of %d got %v, but should be %v\n", name, x, item, want)
+			}
+			if item, want := it.SeekGE(x), near(x, Right, true); item != want {
+				t.Errorf("%s: seekGE %d got %v, but should be %v\n", name, x, item, want)
+			}
+			if item, want := it.SeekGT(x), near(x, Right, false); item != want {
+				t.Errorf("%s: seekGT %d got %v, but should be %v\n", name, x, item, want)
+			} else if item != nil {
+				//the stack or parent chain must be usable after seeking
+				if next, want := it.Next(), near(item.(int), Right, false); next != want {
+					t.Errorf("%s: next after seekGT %d got %v, but should be %v\n", name, x, next, want)
+				}
+			}
+			if item, want := it.SeekLE(x), near(x, Left, true); item != want {
+				t.Errorf("%s: seekLE %d got %v, but should be %v\n", name, x, item, want)
+			}
+			if item, want := it.SeekLT(x), near(x, Left, false); item != want {
+				t.Errorf("%s: seekLT %d got %v, but should be %v\n", name, x, item, want)
+			} else if item != nil {
+				if prev, want := it.Prev(), near(item.(int), Left, false); prev != want {
+					t.Errorf("%s: prev after seekLT %d got %v, but should be %v\n", name, x, prev, want)
+				}
+			}
+		}
+	}
+}
+
+//verify structure of tree against the items expected in it
+func verifyIntTree(t *testing.T, tree SymTab, arr []int) bool {
+	switch tree := tree.(type) {
+	case *AvlTree:
+		return verifyTree(t, tree, arr)
+	case *PAvlTree:
+		return verifyPTree(t, tree, arr)
+	case *RbTree:
+		return verifyRbTree(t, tree, arr)
+	case *PRbTree:
+		return verifyPRbTree(t, tree, arr)
+	}
+	return false
+}
+
+func TestRange(t *testing.T) {
+	type rangeTab interface {
+		SymTab
+		Range(lo, hi Item, fn func(item Item) bool)
+		DeleteRange(lo, hi Item) int
+	}
+	n := len(insertArr)
+	for typ, name := range treeNames {
+		tree := newIntTree(typ).(rangeTab)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		lo, hi := n/4, n-n/4
+		var got []int
+		tree.Range(lo, hi, func(item Item) bool {
+			got = append(got, item.(int))
+			return true
+		})
+		if len(got) != hi-lo {
+			t.Errorf("%s: range [%d, %d) visits %d items\n", name, lo, hi, len(got))
+		}
+		for i, v := range got {
+			if v != lo+i {
+				t.Errorf("%s: range item %d is %d, but should be %d\n", name, i, v, lo+i)
+			}
+		}
+		visits := 0
+		tree.Range(0, n, func(item Item) bool {
+			visits++
+			return visits < 3
+		})
+		if n >= 3 && visits != 3 {
+			t.Errorf("%s: range visits %d items after stop\n", name, visits)
+		}
+
+		if cnt := tree.DeleteRange(hi, lo); cnt != 0 {
+			t.Errorf("%s: empty range deleted %d items\n", name, cnt)
+		}
+		if cnt := tree.DeleteRange(lo, hi); cnt != hi-lo {
+			t.Errorf("%s: delete range [%d, %d) deleted %d items\n", name, lo, hi, cnt)
+		}
+		var left []int
+		for i := 0; i < n; i++ {
+			if i < lo || i >= hi {
+				left = append(left, i)
+			}
+		}
+		if !verifyIntTree(t, tree, left) {
+			t.Errorf("%s: tree broken after delete range [%d, %d)\n", name, lo, hi)
+		}
+		//deleted range is gone, but normal updates still work
+		for _, elem := range deleteArr {
+			tree.Delete(elem)
+			tree.Insert(elem)
+		}
+		if tree.DeleteRange(-1, n+1) != n || tree.Count() != 0 {
+			t.Errorf("%s: delete whole range left %d items\n", name, tree.Count())
+		}
+		if !verifyIntTree(t, tree, nil) {
+			t.Errorf("%s: tree broken after delete whole range\n", name)
+		}
+	}
+}
+
+//ints in [lo, hi) in ascending order
+func intRange(lo, hi int) []int {
+	var arr []int
+	for i := lo; i < hi; i++ {
+		arr = append(arr, i)
+	}
+	return arr
+}
+
+type joinSplitTab[T any] interface {
+	*T
+	SymTab
+	Join(pivot Item, right *T) bool
+	Split(item Item) (lt *T, eq Item, gt *T)
+	SplitAt(k int) (lt, ge *T)
+}
+
+func testJoinSplit[T any, P joinSplitTab[T]](t *testing.T, typ int) {
+	name := treeNames[typ]
+	n := len(insertArr)
+	build := func(arr []int) P {
+		tree := newIntTree(typ).(P)
+		for _, elem := range arr {
+			tree.Insert(elem)
+		}
+		return tree
+	}
+	for k := -1; k <= n; k++ {
+		tree := build(insertArr)
+		lt, eq, gt := tree.Split(k)
+		if tree.Count() != 0 {
+			t.Errorf("%s: %d items left after split\n", name, tree.Count())
+		}
+		lo, hi := max(k, 0), min(k+1, n)
+		if k >= n {
+			lo = n
+		}
+		if (k >= 0 && k < n) != (eq != nil) || eq != nil && eq.(int) != k {
+			t.Errorf("%s: split at %d found %v\n", name, k, eq)
+		}
+		if !verifyIntTree(t, P(lt), intRange(0, lo)) || !verifyIntTree(t, P(gt), intRange(hi, n)) {
+			t.Fatalf("%s: tree broken after split at %d\n", name, k)
+		}
+		if eq == nil {
+			continue
+		}
+		if !P(lt).Join(eq, gt) || P(gt).Count() != 0 {
+			t.Errorf("%s: join at %d failed\n", name, k)
+		}
+		if !verifyIntTree(t, P(lt), intRange(0, n)) {
+			t.Fatalf("%s: tree broken after join at %d\n", name, k)
+		}
+	}
+	for k := 0; k <= n; k++ {
+		lt, ge := build(insertArr).SplitAt(k)
+		if !verifyIntTree(t, P(lt), intRange(0, k)) || !verifyIntTree(t, P(ge), intRange(k, n)) {
+			t.Fatalf("%s: tree broken after split at rank %d\n", name, k)
+		}
+	}
+
+	//cut [0, n) at random pivots, join the pieces back from either end,
+	//so that heights of joined trees differ a lot
+	var pivots []int
+	for p := rand.Intn(n/2 + 1); p < n; p += 1 + rand.Intn(n/2+1) {
+		pivots = append(pivots, p)
+	}
+	pieces := func() []P {
+		var ps []P
+		lo := 0
+		for _, p := range pivots {
+			ps = append(ps, build(intRange(lo, p)))
+			lo = p + 1
+		}
+		return append(ps, build(intRange(lo, n)))
+	}
+	ps := pieces()
+	for i, p := range pivots {
+		if !ps[0].Join(p, ps[i+1]) {
+			t.Errorf("%s: join at %d failed\n", name, p)
+		}
+	}
+	if !verifyIntTree(t, ps[0], intRange(0, n)) {
+		t.Fatalf("%s: tree broken after joining from left\n", name)
+	}
+	ps = pieces()
+	for i := len(pivots) - 1; i >= 0; i-- {
+		if !ps[i].Join(pivots[i], ps[i+1]) {
+			t.Errorf("%s: join at %d failed\n", name, pivots[i])
+		}
+	}
+	if !verifyIntTree(t, ps[0], intRange(0, n)) {
+		t.Fatalf("%s: tree broken after joining from right\n", name)
+	}
+
+	tree, other := build(intRange(0, n)), build([]int{n + 1})
+	if tree.Join(n/2, other) || tree.Join(n+1, other) || tree.Join(n, tree) {
+		t.Errorf("%s: join out of order succeeded\n", name)
+	}
+	if !verifyIntTree(t, tree, intRange(0, n)) || other.Count() != 1 {
+		t.Errorf("%s: failed join changed trees\n", name)
+	}
+}
+
+func TestJoinSplit(t *testing.T) {
+	testJoinSplit[AvlTree](t, avlNoParent)
+	testJoinSplit[PAvlTree](t, avlWithParent)
+	testJoinSplit[RbTree](t, rbNoParent)
+	testJoinSplit[PRbTree](t, rbWithParent)
+}
+
+type setAlgebraTab[T any] interface {
+	*T
+	SymTab
+	Union(other *T, resolve func(a, b Item) Item)
+	Intersect(other *T, resolve func(a, b Item) Item)
+	Difference(other *T)
+	SymmetricDifference(other *T)
+}
+
+func testSetAlgebra[T any, P setAlgebraTab[T]](t *testing.T, typ int) {
+	name := treeNames[typ]
+	ops := []struct {
+		name string
+		op   func(a, b P)
+		keep func(inA, inB bool) bool
+	}{
+		{"union", func(a, b P) { a.Union(b, nil) }, func(inA, inB bool) bool { return inA || inB }},
+		{"intersect", func(a, b P) { a.Intersect(b, nil) }, func(inA, inB bool) bool { return inA && inB }},
+		{"difference", func(a, b P) { a.Difference(b) }, func(inA, inB bool) bool { return inA && !inB }},
+		{"symmetric difference", func(a, b P) { a.SymmetricDifference(b) }, func(inA, inB bool) bool { return inA != inB }},
+	}
+	//the big size makes sure subtrees are merged in parallel
+	for _, n := range []int{len(insertArr), 3 * parallelCutoff} {
+		inA, inB := make([]bool, 2*n), make([]bool, 2*n)
+		for i := range inA {
+			inA[i], inB[i] = rand.Intn(2) == 0, rand.Intn(3) == 0
+		}
+		for _, op := range ops {
+			a, b := newIntTree(typ).(P), newIntTree(typ).(P)
+			var want []int
+			for i := range inA {
+				if inA[i] {
+					a.Insert(i)
+				}
+				if inB[i] {
+					b.Insert(i)
+				}
+				if op.keep(inA[i], inB[i]) {
+					want = append(want, i)
+				}
+			}
+			op.op(a, b)
+			if b.Count() != 0 {
+				t.Errorf("%s: %d items left in other after %s\n", name, b.Count(), op.name)
+			}
+			if !verifyIntTree(t, a, want) {
+				t.Fatalf("%s: tree broken after %s of size %d\n", name, op.name, n)
+			}
+		}
+	}
+
+	a := newIntTree(typ).(P)
+	for _, elem := range insertArr {
+		a.Insert(elem)
+	}
+	a.Union(a, nil)
+	a.Intersect(a, nil)
+	if !verifyIntTree(t, a, intRange(0, len(insertArr))) {
+		t.Errorf("%s: tree broken after union with itself\n", name)
+	}
+	a.SymmetricDifference(a)
+	if !verifyIntTree(t, a, nil) {
+		t.Errorf("%s: tree not empty after symmetric difference with itself\n", name)
+	}
+
+	//items with equal keys but different values are resolved by the callback
+	newKv := func(keys string, v int) P {
+		tree := newTree(typ, mapCmp).(P)
+		for _, k := range keys {
+			tree.Insert(kv{string(k), v})
+		}
+		return tree
+	}
+	takeB := func(a, b Item) Item { return b }
+	for _, c := range []struct {
+		op   func(a, b P)
+		want []kv
+	}{
+		{func(a, b P) { a.Union(b, nil) }, []kv{{"a", 1}, {"b", 1}, {"c", 1}, {"d", 2}}},
+		{func(a, b P) { a.Union(b, takeB) }, []kv{{"a", 1}, {"b", 2}, {"c", 2}, {"d", 2}}},
+		{func(a, b P) { a.Intersect(b, nil) }, []kv{{"b", 1}, {"c", 1}}},
+		{func(a, b P) { a.Intersect(b, takeB) }, []kv{{"b", 2}, {"c", 2}}},
+	} {
+		a, b := newKv("abc", 1), newKv("bcd", 2)
+		c.op(a, b)
+		var got []kv
+		it := a.Iter()
+		for item := it.First(); item != nil; item = it.Next() {
+			got = append(got, item.(kv))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("%s: resolved items are %v, but should be %v\n", name, got, c.want)
+		}
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	testSetAlgebra[AvlTree](t, avlNoParent)
+	testSetAlgebra[PAvlTree](t, avlWithParent)
+	testSetAlgebra[RbTree](t, rbNoParent)
+	testSetAlgebra[PRbTree](t, rbWithParent)
+}
+
+type snapshotTab[T any] interface {
+	*T
+	SymTab
+	Snapshot() *T
+	DeleteRange(lo, hi Item) int
+	Union(other *T, resolve func(a, b Item) Item)
+}
+
+//apply random updates to random snapshots of a tree and check every version against its model
+func testSnapshot[T any, P snapshotTab[T]](t *testing.T, typ int) {
+	name := treeNames[typ]
+	n := len(insertArr)
+	tree := newIntTree(typ).(P)
+	model := map[int]bool{}
+	for _, elem := range insertArr {
+		tree.Insert(elem)
+		model[elem] = true
+	}
+	versions, models := []P{tree}, []map[int]bool{model}
+	sorted := func(m map[int]bool) []int {
+		var arr []int
+		for i := 0; i < 2*n; i++ {
+			if m[i] {
+				arr = append(arr, i)
+			}
+		}
+		return arr
+	}
+	for step := 0; step < 50*n; step++ {
+		i := rand.Intn(len(versions))
+		v, m := versions[i], models[i]
+		switch key := rand.Intn(2 * n); rand.Intn(6) {
+		case 0:
+			if len(versions) < 16 {
+				c := map[int]bool{}
+				for k := range m {
+					c[k] = true
+				}
+				versions, models = append(versions, P(v.Snapshot())), append(models, c)
+			}
+		case 1:
+			v.Insert(key)
+			m[key] = true
+		case 2:
+			v.Delete(key)
+			delete(m, key)
+		case 3:
+			v.Replace(key)
+			m[key] = true
+		case 4:
+			hi := key + rand.Intn(n/4+1)
+			v.DeleteRange(key, hi)
+			for k := key; k < hi; k++ {
+				delete(m, k)
+			}
+		case 5:
+			j := rand.Intn(len(versions))
+			if j != i {
+				v.Union(P(versions[j]).Snapshot(), nil)
+				for k := range models[j] {
+					m[k] = true
+				}
+			}
+		}
+	}
+	for i, v := range versions {
+		if !verifyIntTree(t, v, sorted(models[i])) {
+			t.Fatalf("%s: version %d broken\n", name, i)
+		}
+	}
+
+	//replacing through an iterator does not leak into the snapshot
+	m := newTree(typ, mapCmp).(P)
+	m.Insert(kv{"CPU", 10})
+	m.Insert(kv{"GPU", 15})
+	s := P(m.Snapshot())
+	it := m.Iter()
+	for item := it.First(); item != nil; item = it.Next() {
+		e := item.(kv)
+		e.v++
+		it.(interface{ Replace(Item) Item }).Replace(e)
+	}
+	if s.Find(kv{k: "GPU"}).(kv).v != 15 || m.Find(kv{k: "GPU"}).(kv).v != 16 {
+		t.Errorf("%s: iterator replace leaks into snapshot\n", name)
+	}
+
+	//a write after snapshot copies nodes, iterators of the tree follow the copies
+	a := newIntTree(typ).(P)
+	for _, v := range []int{10, 20, 30} {
+		a.Insert(v)
+	}
+	ai := a.Iter().(interface {
+		Iterator
+		Find(item Item) Item
+	})
+	ai.Find(10)
+	a.Snapshot()
+	a.Insert(25)
+	var got []int
+	for item := ai.Next(); item != nil; item = ai.Next() {
+		got = append(got, item.(int))
+	}
+	if !slices.Equal(got, []int{20, 25, 30}) {
+		t.Errorf("%s: iterator walks %v after insert following snapshot\n", name, got)
+	}
+	it = m.Iter()
+	it.First()
+	m.Snapshot()
+	m.Replace(kv{"GPU", 99})
+	if e := it.Next(); e == nil || e.(kv).v != 99 {
+		t.Errorf("%s: iterator got %v after replace following snapshot\n", name, e)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	testSnapshot[AvlTree](t, avlNoParent)
+	testSnapshot[RbTree](t, rbNoParent)
+}
+
+//check items seen by iterator are ascending and within [0, n)
+func checkAscending(t *testing.T, name string, it Iterator, n int) {
+	last := -1
+	for item := it.First(); item != nil; item = it.Next() {
+		v := item.(int)
+		if v <= last || v >= n {
+			t.Errorf("%s: iterator got %d after %d\n", name, v, last)
+			return
+		}
+		last = v
+	}
+}
+
+func TestConcurrentSymTab(t *testing.T) {
+	const workers = 4
+	n := len(insertArr)
+	for typ, name := range treeNames {
+		c := NewConcurrentSymTab(newIntTree(typ))
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(2)
+			//writer w owns the items congruent to w
+			go func(w int) {
+				defer wg.Done()
+				for _, elem := range insertArr {
+					if elem%workers == w && !c.Insert(elem) {
+						t.Errorf("%s: insert %d failed\n", name, elem)
+					}
+				}
+				for _, elem := range deleteArr {
+					if elem%workers == w && elem%2 == 0 && c.Delete(elem) == nil {
+						t.Errorf("%s: delete %d failed\n", name, elem)
+					}
+				}
+			}(w)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 4; i++ {
+					c.Find(w)
+					checkAscending(t, name, c.Iter(), n)
+					it := c.LockedIter()
+					checkAscending(t, name, it, n)
+					it.Close()
+					it.Close()
+				}
+			}(w)
+		}
+		wg.Wait()
+		var odd []int
+		for i := 1; i < n; i += 2 {
+			odd = append(odd, i)
+		}
+		c.View(func(tab SymTab) {
+			if !verifyIntTree(t, tab, odd) {
+				t.Errorf("%s: tree broken after concurrent updates\n", name)
+			}
+		})
+
+		evens := make([]Item, 0, n)
+		for i := 0; i < n; i += 2 {
+			evens = append(evens, i)
+		}
+		snap := c.Iter()
+		if cnt, dups := c.InsertBatch(evens); cnt != len(evens) || len(dups) != 0 {
+			t.Errorf("%s: insert batch inserted %d items, skipped %v\n", name, cnt, dups)
+		}
+		if cnt, dups := c.InsertBatch(evens); cnt != 0 || !slices.Equal(dups, evens) {
+			t.Errorf("%s: insert batch again inserted %d items, skipped %v\n", name, cnt, dups)
+		}
+		found := c.FindBatch([]Item{0, n, n - 1})
+		if found[0] != 0 || found[1] != nil || found[2] != n-1 {
+			t.Errorf("%s: find batch got %v\n", name, found)
+		}
+		//snapshot taken before the batch does not see it
+		if !verifyPstTraversal(t, snap, odd) {
+			t.Errorf("%s: snapshot iterator sees later insert\n", name)
+		}
+		//a table of unknown type is copied
+		if !verifyPstTraversal(t, NewConcurrentSymTab(c).Iter(), intRange(0, n)) {
+			t.Errorf("%s: iterator over copied table is wrong\n", name)
+		}
+		if cnt := c.DeleteBatch(evens); cnt != len(evens) || c.Count() != len(odd) {
+			t.Errorf("%s: delete batch deleted %d items\n", name, cnt)
+		}
+		c.Update(func(tab SymTab) {
+			for _, v := range odd {
+				tab.Delete(v)
+			}
+		})
+		if c.Count() != 0 {
+			t.Errorf("%s: update left %d items\n", name, c.Count())
+		}
+
+		//locked iterators hooked with snapshots run together
+		tab := newIntTree(typ).(interface {
+			SymTab
+			SetIterPolicy(p IterPolicy)
+		})
+		for _, elem := range insertArr {
+			tab.Insert(elem)
+		}
+		tab.SetIterPolicy(IterSnapshot)
+		c = NewConcurrentSymTab(tab)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				it := c.LockedIter()
+				checkAscending(t, name, it, n)
+				it.Close()
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestIterPolicy(t *testing.T) {
+	type policyTab interface {
+		SymTab
+		SetIterPolicy(p IterPolicy)
+	}
+	type indexIter interface {
+		Iterator
+		Find(item Item) Item
+		Index() int
+		Insert(item Item) (*Item, bool)
+	}
+	n := len(insertArr)
+	if n < 8 {
+		t.Skip("tree too small")
+	}
+	failed := func(f func()) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err, _ = r.(error)
+			}
+		}()
+		f()
+		return
+	}
+	for typ, name := range treeNames {
+		tree := newIntTree(typ).(policyTab)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		//resync moves on from the key of a deleted current item
+		k := n / 2
+		it := tree.Iter().(indexIter)
+		it.Find(k)
+		tree.Delete(k)
+		tree.Delete(k + 1)
+		if idx := it.Index(); idx != -1 {
+			t.Errorf("%s: deleted current item has index %d\n", name, idx)
+		}
+		if item := it.Next(); item != k+2 {
+			t.Errorf("%s: next after delete got %v, but should be %d\n", name, item, k+2)
+		}
+		tree.Delete(k + 3)
+		if item := it.Next(); item != k+4 || it.Index() != k+1 {
+			t.Errorf("%s: next after delete got %v at %d, but should be %d\n", name, item, it.Index(), k+4)
+		}
+		tree.Delete(k + 4)
+		tree.Delete(k + 2)
+		if item := it.Prev(); item != k-1 {
+			t.Errorf("%s: prev after delete got %v, but should be %d\n", name, item, k-1)
+		}
+		for _, v := range []int{k, k + 1, k + 2, k + 3, k + 4} {
+			tree.Insert(v)
+		}
+
+		//fail-fast panics on the next move, but not after own changes
+		tree.SetIterPolicy(IterFailFast)
+		it = tree.Iter().(indexIter)
+		it.First()
+		it.Insert(n)
+		if err := failed(func() { it.Next(); it.Prev() }); err != nil {
+			t.Errorf("%s: own insert of iterator fails with %v\n", name, err)
+		}
+		tree.Delete(n)
+		if err := failed(func() { it.Next() }); !errors.Is(err, ErrConcurrentModification) {
+			t.Errorf("%s: next after delete fails with %v\n", name, err)
+		}
+		if err := failed(func() { it.Index() }); !errors.Is(err, ErrConcurrentModification) {
+			t.Errorf("%s: index after delete fails with %v\n", name, err)
+		}
+		if err := failed(func() { it.First(); it.Next() }); err != nil {
+			t.Errorf("%s: repositioned iterator fails with %v\n", name, err)
+		}
+		//an insert needing no rotation trips it too
+		small := newIntTree(typ).(policyTab)
+		small.SetIterPolicy(IterFailFast)
+		for _, v := range []int{20, 10, 30} {
+			small.Insert(v)
+		}
+		it = small.Iter().(indexIter)
+		it.First()
+		small.Insert(40)
+		if err := failed(func() { it.Next() }); !errors.Is(err, ErrConcurrentModification) {
+			t.Errorf("%s: next after insert without rotation fails with %v\n", name, err)
+		}
+
+		//snapshot never sees later changes
+		tree.SetIterPolicy(IterSnapshot)
+		snap := tree.Iter()
+		for i := 0; i < n; i += 2 {
+			tree.Delete(i)
+		}
+		tree.Insert(n)
+		if !verifyPstTraversal(t, snap, intRange(0, n)) {
+			t.Errorf("%s: snapshot iterator sees later changes\n", name)
+		}
+		var odd []int
+		for i := 1; i < n; i += 2 {
+			odd = append(odd, i)
+		}
+		tree.Delete(n)
+		if !verifyIntTree(t, tree, odd) {
+			t.Errorf("%s: tree broken after snapshot iteration\n", name)
+		}
+	}
+}
+
+func TestIterDelete(t *testing.T) {
+	type deleteIter interface {
+		Iterator
+		Delete() Item
+		DeletePrev() Item
+	}
+	for typ, name := range treeNames {
+		tree := newIntTree(typ).(interface {
+			SymTab
+			SetIterPolicy(p IterPolicy)
+		})
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+		}
+		//own deletes never trip a fail-fast iterator
+		tree.SetIterPolicy(IterFailFast)
+		it := tree.Iter().(deleteIter)
+		var odd, rest []int
+		for item := it.First(); item != nil; {
+			if v := item.(int); v%2 == 0 {
+				if next := it.Delete(); next != nil && next.(int) != v+1 {
+					t.Errorf("%s: delete %d moved to %v\n", name, v, next)
+				}
+				item = it.Current()
+			} else {
+				odd = append(odd, v)
+				item = it.Next()
+			}
+		}
+		if !verifyIntTree(t, tree, odd) {
+			t.Errorf("%s: tree broken after deleting evens through iterator\n", name)
+		}
+		for item := it.Last(); item != nil; {
+			if v := item.(int); v%3 == 0 {
+				if prev := it.DeletePrev(); prev != nil && prev.(int) != v-2 {
+					t.Errorf("%s: delete %d moved back to %v\n", name, v, prev)
+				}
+				item = it.Current()
+			} else {
+				rest = append([]int{v}, rest...)
+				item = it.Prev()
+			}
+		}
+		if !verifyIntTree(t, tree, rest) {
+			t.Errorf("%s: tree broken after deleting through iterator backwards\n", name)
+		}
+		if len(rest) > 0 {
+			it.Last()
+			if next := it.Delete(); next != nil || it.Current() != nil || tree.Count() != len(rest)-1 {
+				t.Errorf("%s: delete of last item moved to %v\n", name, next)
+			}
+		}
+		if it.Delete() != nil || it.DeletePrev() != nil || tree.Count() != max(len(rest)-1, 0) {
+			t.Errorf("%s: delete at nil position changed tree\n", name)
+		}
+	}
+}
+
+//check items of seq against want, ints wrapped in T
+func checkSeq[T any](t *testing.T, name string, seq iter.Seq[T], want []int) {
+	var got []int
+	for item := range seq {
+		got = append(got, any(item).(int))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, but should be %v\n", name, got, want)
+	}
+}
+
+//s holds ints in [0, n), wrapped in T by of
+func testSeq[T any](t *testing.T, name string, s seqTab[T], of func(int) T, n int) {
+	all := intRange(0, n)
+	backward := slices.Clone(all)
+	slices.Reverse(backward)
+	k := n / 2
+	checkSeq(t, name+" all", s.All(), all)
+	checkSeq(t, name+" all again", s.All(), all)
+	checkSeq(t, name+" backward", s.Backward(), backward)
+	checkSeq(t, name+" ascend", s.Ascend(of(k)), all[k:])
+	checkSeq(t, name+" descend", s.Descend(of(k)), backward[n-k-1:])
+	checkSeq(t, name+" between", s.Between(of(k/2), of(k)), all[k/2:k])
+	checkSeq(t, name+" empty between", s.Between(of(k), of(k/2)), nil)
+	checkSeq(t, name+" ascend past end", s.Ascend(of(n)), nil)
+	checkSeq(t, name+" descend before start", s.Descend(of(-1)), nil)
+	if got := slices.Collect(s.All()); len(got) != n {
+		t.Errorf("%s: collected %d items\n", name, len(got))
+	}
+	cnt := 0
+	for range s.Backward() {
+		if cnt++; cnt == 3 {
+			break
+		}
+	}
+	if cnt != min(n, 3) {
+		t.Errorf("%s: %d items seen before break\n", name, cnt)
+	}
+}
+
+func TestSeq(t *testing.T) {
+	n := len(insertArr)
+	of := func(i int) Item { return i }
+	for typ, name := range treeNames {
+		tree, set := newIntTree(typ), newIntSet(typ)
+		for _, elem := range insertArr {
+			tree.Insert(elem)
+			set.Insert(elem)
+		}
+		testSeq(t, name, tree.(seqTab[Item]), of, n)
+		testSeq(t, name+" set", set.(seqTab[int]), func(i int) int { return i }, n)
+	}
+	pa, pr := NewPersistentAvlTree(intCmp, nil), NewPersistentRbTree(intCmp, nil)
+	rcu := NewOrderedRcuRbSet[int]()
+	for _, elem := range insertArr {
+		pa, _ = pa.Insert(elem)
+		pr, _ = pr.Insert(elem)
+		rcu.Insert(elem)
+	}
+	testSeq(t, "persistentAvl", pa, of, n)
+	testSeq(t, "persistentRb", pr, of, n)
+	testSeq(t, "rcuRb", rcu, func(i int) int { return i }, n)
+}
+
+func TestFromSorted(t *testing.T) {
+	fromSorted := func(typ int, items []Item) (SymTab, error) {
+		switch typ {
+		case avlNoParent:
+			return NewAvlTreeFromSorted(intCmp, nil, items)
+		case avlWithParent:
+			return NewPAvlTreeFromSorted(intCmp, nil, items)
+		case rbNoParent:
+			return NewRbTreeFromSorted(intCmp, nil, items)
+		case rbWithParent:
+			return NewPRbTreeFromSorted(intCmp, nil, items)
+		}
+		return nil, nil
+	}
+	for typ, name := range treeNames {
+		for _, n := range []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 15, 16, 17, 100, 1023, 1024, 1025, 5000} {
+			arr := intRange(0, n)
+			items := make([]Item, n)
+			for i := range arr {
+				arr[i] *= 2
+				items[i] = arr[i]
+			}
+			tree, err := fromSorted(typ, items)
+			if err != nil {
+				t.Fatalf("%s: build of %d items failed: %v\n", name, n, err)
+			}
+			var ok bool
+			switch typ {
+			case avlNoParent:
+				ok = verifyTree(t, tree.(*AvlTree), arr)
+			case avlWithParent:
+				ok = verifyPTree(t, tree.(*PAvlTree), arr)
+			case rbNoParent:
+				ok = verifyRbTree(t, tree.(*RbTree), arr)
+			case rbWithParent:
+				ok = verifyPRbTree(t, tree.(*PRbTree), arr)
+			}
+			if !ok {
+				t.Fatalf("%s: tree built from %d items is invalid\n", name, n)
+			}
+			//built tree keeps working
+			for i := 0; i < n; i++ {
+				tree.Insert(2*i + 1)
+			}
+			if i := n / 2; n > 0 && tree.Delete(arr[i]) == nil {
+				t.Errorf("%s: delete of %d failed\n", name, arr[i])
+			}
+		}
+		if _, err := fromSorted(typ, []Item{1, 3, 2}); err != ErrNotSorted {
+			t.Errorf("%s: build of unsorted items returned %v\n", name, err)
+		}
+		if _, err := fromSorted(typ, []Item{1, 2, 2, 3}); err != ErrNotSorted {
+			t.Errorf("%s: build of duplicate items returned %v\n", name, err)
+		}
+		if _, err := fromSorted(typ, []Item{1, nil, 3}); err != ErrNilItem {
+			t.Errorf("%s: build of nil item returned %v\n", name, err)
+		}
+	}
+	s, err := NewRbSetFromSorted(cmpInt, intRange(0, 100))
+	if err != nil || s.Count() != 100 || !slices.Equal(slices.Collect(s.All()), intRange(0, 100)) {
+		t.Errorf("NewRbSetFromSorted: got %v, %v\n", s, err)
+	}
+}
+
+func TestInsertBatch(t *testing.T) {
+	type batchTab interface {
+		SymTab
+		InsertBatch(items []Item) (inserted int, dups []Item)
+	}
+	//batch sizes relative to tree cover inserting one by one, union, parallel union and rebuild
+	for _, sizes := range [][2]int{{0, 100}, {1000, 10}, {1000, 300}, {300, 1000}, {500, 500}, {6000, 3000}} {
+		n, m := sizes[0], sizes[1]
+		for typ, name := range treeNames {
+			tree := newIntTree(typ).(batchTab)
+			var want []int
+			for _, elem := range genInsertArr(n, insRandom) {
+				tree.Insert(2 * elem)
+				want = append(want, 2*elem)
+			}
+			//half of batch is already in tree, every item appears twice, nil is skipped
+			batch := []Item{nil}
+			for i := 0; i < m; i++ {
+				batch = append(batch, i, i)
+			}
+			inserted := 0
+			var wantDups []Item
+			for i := 0; i < m; i++ {
+				wantDups = append(wantDups, i)
+				if i%2 == 1 || i >= 2*n {
+					want = append(want, i)
+					inserted++
+				} else {
+					wantDups = append(wantDups, i)
+				}
+			}
+			slices.Sort(want)
+			got, dups := tree.InsertBatch(batch)
+			if got != inserted {
+				t.Errorf("%s/%d+%d: inserted %d items, but should be %d\n", name, n, m, got, inserted)
+			}
+			if !slices.Equal(dups, wantDups) {
+				t.Errorf("%s/%d+%d: skipped %v, but should be %v\n", name, n, m, dups, wantDups)
+			}
+			var ok bool
+			switch typ {
+			case avlNoParent:
+				ok = verifyTree(t, tree.(*AvlTree), want)
+			case avlWithParent:
+				ok = verifyPTree(t, tree.(*PAvlTree), want)
+			case rbNoParent:
+				ok = verifyRbTree(t, tree.(*RbTree), want)
+			case rbWithParent:
+				ok = verifyPRbTree(t, tree.(*PRbTree), want)
+			}
+			if !ok {
+				t.Errorf("%s/%d+%d: tree is invalid after InsertBatch\n", name, n, m)
+			}
+		}
+	}
+}
+
+//check iterator walks nodes forward and backward, and wraps around nil position
+func verifyIntTraversal[N any](t *testing.T, it Iterator, nodes []N, data func(n N) Item) bool {
+	ok := true
+	i := 0
+	for item := it.First(); item != nil && i <= len(nodes); item = it.Next() {
+		if i == len(nodes) || item != data(nodes[i]) {
+			t.Errorf("Forward traversal differs at position %d.\n", i)
+			return false
+		}
+		i++
+	}
+	i = len(nodes) - 1
+	for item := it.Last(); item != nil && i >= -1; item = it.Prev() {
+		if i < 0 || item != data(nodes[i]) {
+			t.Errorf("Backward traversal differs at position %d.\n", i)
+			return false
+		}
+		i--
+	}
+	if i != -1 {
+		t.Errorf("Backward traversal stops early at position %d.\n", i)
+		ok = false
+	}
+	if len(nodes) > 0 {
+		if it.Next() != data(nodes[0]) || it.Prev() != nil || it.Prev() != data(nodes[len(nodes)-1]) {
+			t.Errorf("Iterator does not wrap around nil position.\n")
+			ok = false
+		}
+	}
+	return ok
+}
+
+//interface{} tree built on compatTree, as the fixtures below use it
+type compatTestTree[Tr any] interface {
+	SymTab
+	Copy() Tr
+}
+
+//iterator of such a tree built on compatIter, It is the pointer to I
+type compatTestIter[I, Tr any] interface {
+	*I
+	Iterator
+	HookWith(tree Tr) *I
+	Find(item Item) Item
+	Delete() Item
+	DeletePrev() Item
+	Insert(item Item) (*Item, bool)
+	CopyFrom(other *I) Item
+}
+
+func testCompatCorrectness[Tr compatTestTree[Tr], I any, It compatTestIter[I, Tr]](t *testing.T,
+	newTree func(cmp Compare, extra interface{}) Tr, verify func(t *testing.T, tree Tr, arr []int) bool, insert, delete []int) (ok bool) {
+	tree := newTree(intCmp, nil)
+	n := len(insert)
+	for i := 0; i < n; i++ {
+		if !tree.Insert(insert[i]) {
+			t.Errorf("Inserting %d failed.\n", insert[i])
+			return false
+		}
+		if !verify(t, tree, insert[:i+1]) {
+			return false
+		}
+	}
+
+	//iterator stays on its node while other items are deleted and reinserted
+	for i := 0; i < n; i++ {
+		var x, y I
+		if insert[i] == delete[i] {
+			continue
+		}
+		if It(It(&x).HookWith(tree)).Find(insert[i]) == nil {
+			t.Errorf("Can't find item %d in tree!\n", insert[i])
+			return false
+		}
+		if tree.Delete(delete[i]) != delete[i] {
+			t.Errorf("Deleting %d failed.\n", delete[i])
+			return false
+		}
+		It(&y).CopyFrom(&x)
+		if addr, succ := It(&y).Insert(delete[i]); addr == nil || !succ || It(&y).Current() != delete[i] {
+			t.Errorf("Re-inserting item %d failed.\n", delete[i])
+			return false
+		}
+		if xi := It(&x); xi.Current() != insert[i] || xi.Next() != nil && xi.Current() != insert[i]+1 ||
+			xi.Prev() != insert[i] || xi.Prev() != nil && xi.Current() != insert[i]-1 {
+			t.Errorf("Iterator moved away from %d.\n", insert[i])
+			return false
+		}
+		if !verify(t, tree, insert) {
+			return false
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		if tree.Delete(delete[i]) != delete[i] {
+			t.Errorf("Deleting %d failed.\n", delete[i])
+			return false
+		}
+		if !verify(t, tree, delete[i+1:]) {
+			return false
+		}
+		if !verify(t, tree.Copy(), delete[i+1:]) {
+			t.Errorf("Copy of tree is invalid.\n")
+			return false
+		}
+	}
+	if tree.Delete(insert[0]) != nil {
+		t.Errorf("Deletion from empty tree succeeded.\n")
+		return false
+	}
+	return true
+}
+
+func testCompatOverflow[Tr compatTestTree[Tr], I any, It compatTestIter[I, Tr]](t *testing.T,
+	newTree func(cmp Compare, extra interface{}) Tr, verify func(t *testing.T, tree Tr, arr []int) bool, insert []int) bool {
+	tree := newTree(intCmp, nil)
+	for _, elem := range insert {
+		tree.Insert(elem)
+	}
+	n := len(insert)
+	var i0 I
+	it := It(&i0)
+	it.HookWith(tree)
+	for i := 0; i < n; i++ {
+		if ret := it.Next(); ret != i {
+			t.Errorf("Next item test failed: expected %d, got %v\n", i, ret)
+			return false
+		}
+	}
+	if ret := it.Next(); ret != nil {
+		t.Errorf("Next item test failed: expected nil, got %v\n", ret)
+		return false
+	}
+	for i := n - 1; i >= 0; i-- {
+		if ret := it.Prev(); ret != i {
+			t.Errorf("Prev item test failed: expected %d, got %v\n", i, ret)
+			return false
+		}
+	}
+	//delete through iterator from both ends
+	it.First()
+	for i := 0; i < n/2; i++ {
+		if next := it.Delete(); i+1 < n && next != i+1 {
+			t.Errorf("Delete item test failed: expected %d, got %v\n", i+1, next)
+			return false
+		}
+	}
+	it.Last()
+	for i := n - 1; i >= n/2; i-- {
+		if prev := it.DeletePrev(); i > n/2 && prev != i-1 {
+			t.Errorf("DeletePrev item test failed: expected %d, got %v\n", i-1, prev)
+			return false
+		}
+	}
+	return verify(t, tree, nil)
+}
+
+//copy a tree of ascending inserts, a path in a splay tree, then read it concurrently,
+//which a splay tree only survives as ConcurrentSymTab locks its reads exclusively
+func testCompatShared[Tr compatTestTree[Tr]](t *testing.T, name string,
+	newTree func(cmp Compare, extra interface{}) Tr, verify func(t *testing.T, tree Tr, arr []int) bool) {
+	n := len(insertArr)
+	tree := newTree(intCmp, nil)
+	for i := 0; i < n; i++ {
+		tree.Insert(i)
+	}
+	if !verify(t, tree.Copy(), intRange(0, n)) {
+		t.Errorf("%s: copy of tree is invalid\n", name)
+	}
+	c := NewConcurrentSymTab(tree)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += 4 {
+				if c.Find(i) != i {
+					t.Errorf("%s: find %d failed\n", name, i)
+				}
+				if found := c.FindBatch([]Item{i, n}); found[0] != i || found[1] != nil {
+					t.Errorf("%s: find batch %d got %v\n", name, i, found)
+				}
+			}
+			checkAscending(t, name, c.Iter(), n)
+			it := c.LockedIter()
+			checkAscending(t, name, it, n)
+			it.Close()
+		}(w)
+	}
+	wg.Wait()
+	if !verify(t, tree, intRange(0, n)) {
+		t.Errorf("%s: tree broken by concurrent reads\n", name)
+	}
+}
+
+func TestCompatShared(t *testing.T) {
+	testCompatShared(t, "avlThreaded", NewTAvlTree, verifyTAvlTree)
+	testCompatShared(t, "rbThreaded", NewTRbTree, verifyTRbTree)
+	testCompatShared(t, "splay", NewSplayTree, verifySplayTree)
+	testCompatShared(t, "treap", NewTreapTree, verifyTreapTree)
+	testCompatShared(t, "scapegoat", NewScapegoatTree, verifyScapegoatTree)
+	testCompatShared(t, "wavl", NewWavlTree, verifyWavlTree)
+}
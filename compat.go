@@ -0,0 +1,193 @@
+package bbst
+
+import (
+	"iter"
+)
+
+//set of Item a compatTree wraps, P is the pointer to set S
+type compatSet[S any] interface {
+	*S
+	Count() int
+	Find(target Item) (found Item, ok bool)
+	Insert(item Item) bool
+	Replace(item Item) (old Item, ok bool)
+	Delete(item Item) (deleted Item, ok bool)
+	All() iter.Seq[Item]
+	Backward() iter.Seq[Item]
+}
+
+//iterator of Item a compatIter wraps, Q is the pointer to iterator I
+type compatSetIter[I any] interface {
+	*I
+	IteratorOf[Item]
+	Find(item Item) (found Item, ok bool)
+	Replace(new Item) (item Item, ok bool)
+	Delete() (item Item, ok bool)
+	DeletePrev() (item Item, ok bool)
+	Insert(item Item) (*Item, bool)
+}
+
+//compatTree gives a set of Item the interface{} api of SymTab, nil items are never passed to the set,
+//the interface{} trees other than AvlTree, PAvlTree, RbTree and PRbTree embed one
+type compatTree[S any, P compatSet[S]] struct {
+	set S
+}
+
+func (t *compatTree[S, P]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return P(&t.set).Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *compatTree[S, P]) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := P(&t.set).Find(target)
+	return item
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *compatTree[S, P]) Insert(item Item) bool {
+	if t == nil || item == nil {
+		return false
+	}
+	return P(&t.set).Insert(item)
+}
+
+//replace item in tree with same key item
+//return old item
+func (t *compatTree[S, P]) Replace(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	old, _ := P(&t.set).Replace(item)
+	return old
+}
+
+//delete item in tree
+//return item if find it
+//else  return nil
+func (t *compatTree[S, P]) Delete(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	deleted, _ := P(&t.set).Delete(item)
+	return deleted
+}
+
+//sequence of all items in ascending order
+func (t *compatTree[S, P]) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return P(&t.set).All()
+}
+
+//sequence of all items in descending order
+func (t *compatTree[S, P]) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return P(&t.set).Backward()
+}
+
+//reads change tree if they change the set
+func (t *compatTree[S, P]) readsMutate() bool {
+	m, ok := any(P(&t.set)).(readMutator)
+	return ok && m.readsMutate()
+}
+
+//compatIter gives an iterator of Item the interface{} api of Iterator,
+//its zero value is ready to be hooked with a tree
+type compatIter[I any, Q compatSetIter[I]] struct {
+	it I
+}
+
+func (it *compatIter[I, Q]) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).First()
+	return item
+}
+
+func (it *compatIter[I, Q]) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).Last()
+	return item
+}
+
+func (it *compatIter[I, Q]) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).Next()
+	return item
+}
+
+func (it *compatIter[I, Q]) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).Prev()
+	return item
+}
+
+func (it *compatIter[I, Q]) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).Current()
+	return item
+}
+
+func (it *compatIter[I, Q]) Find(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := Q(&it.it).Find(item)
+	return found
+}
+
+//don't change key part of item
+func (it *compatIter[I, Q]) Replace(new Item) Item {
+	if it == nil || new == nil {
+		return nil
+	}
+	old, _ := Q(&it.it).Replace(new)
+	return old
+}
+
+//delete current item and move to its successor, return the successor
+func (it *compatIter[I, Q]) Delete() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).Delete()
+	return item
+}
+
+//delete current item and move to its predecessor, return the predecessor
+func (it *compatIter[I, Q]) DeletePrev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := Q(&it.it).DeletePrev()
+	return item
+}
+
+func (it *compatIter[I, Q]) Insert(item Item) (*Item, bool) {
+	if it == nil || item == nil {
+		return nil, false
+	}
+	return Q(&it.it).Insert(item)
+}
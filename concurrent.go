@@ -0,0 +1,294 @@
+package bbst
+
+import (
+	"sync"
+)
+
+//ConcurrentSymTab makes any SymTab safe for concurrent use,
+//reads share a read lock and writes take the write lock,
+//reads of a tree they restructure, like SplayTree, take the write lock too
+type ConcurrentSymTab struct {
+	mu        sync.RWMutex
+	tab       SymTab
+	readWrite bool //reads change tab
+}
+
+//optional capability of a tree whose reads change it
+type readMutator interface {
+	readsMutate() bool
+}
+
+func NewConcurrentSymTab(tab SymTab) *ConcurrentSymTab {
+	if tab == nil {
+		return nil
+	}
+	m, ok := tab.(readMutator)
+	return &ConcurrentSymTab{tab: tab, readWrite: ok && m.readsMutate()}
+}
+
+//take the read lock, or the write lock if reads change tab
+func (c *ConcurrentSymTab) rlock() {
+	if c.readWrite {
+		c.mu.Lock()
+	} else {
+		c.mu.RLock()
+	}
+}
+
+//release the lock taken by rlock
+func (c *ConcurrentSymTab) runlock() {
+	if c.readWrite {
+		c.mu.Unlock()
+	} else {
+		c.mu.RUnlock()
+	}
+}
+
+func (c *ConcurrentSymTab) Count() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tab.Count()
+}
+
+func (c *ConcurrentSymTab) Find(target Item) Item {
+	if c == nil {
+		return nil
+	}
+	c.rlock()
+	defer c.runlock()
+	return c.tab.Find(target)
+}
+
+func (c *ConcurrentSymTab) Insert(item Item) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tab.Insert(item)
+}
+
+func (c *ConcurrentSymTab) Replace(item Item) Item {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tab.Replace(item)
+}
+
+func (c *ConcurrentSymTab) Delete(item Item) Item {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tab.Delete(item)
+}
+
+//run fn with the read lock held, fn must not change tab
+func (c *ConcurrentSymTab) View(fn func(tab SymTab)) {
+	if c == nil {
+		return
+	}
+	c.rlock()
+	defer c.runlock()
+	fn(c.tab)
+}
+
+//run fn with the write lock held,
+//other goroutines see either none or all of the changes fn makes
+func (c *ConcurrentSymTab) Update(fn func(tab SymTab)) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(c.tab)
+}
+
+//search all targets under one read lock
+//return found items, nil for the missing ones
+func (c *ConcurrentSymTab) FindBatch(targets []Item) []Item {
+	if c == nil {
+		return nil
+	}
+	c.rlock()
+	defer c.runlock()
+	found := make([]Item, len(targets))
+	for i, target := range targets {
+		found[i] = c.tab.Find(target)
+	}
+	return found
+}
+
+//insert all items under one write lock,
+//trees with their own InsertBatch merge the sorted batch in
+//return number of inserted items and skipped items,
+//skipped items are in ascending order for trees with their own InsertBatch, else in batch order
+func (c *ConcurrentSymTab) InsertBatch(items []Item) (inserted int, dups []Item) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.tab.(interface {
+		InsertBatch(items []Item) (int, []Item)
+	}); ok {
+		return b.InsertBatch(items)
+	}
+	for _, item := range items {
+		if c.tab.Insert(item) {
+			inserted++
+		} else if item != nil {
+			dups = append(dups, item)
+		}
+	}
+	return
+}
+
+//delete all items under one write lock
+//return number of deleted items
+func (c *ConcurrentSymTab) DeleteBatch(items []Item) (deleted int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range items {
+		if c.tab.Delete(item) != nil {
+			deleted++
+		}
+	}
+	return
+}
+
+//return iterator over a snapshot of tab, later changes are not seen by it,
+//no lock is held while iterating
+//taking the snapshot is O(1) for AvlTree and RbTree, other tables are copied
+func (c *ConcurrentSymTab) Iter() Iterator {
+	if c == nil {
+		return nil
+	}
+	switch t := c.tab.(type) {
+	case *AvlTree:
+		//Snapshot switches epoch of tree, so it is a write
+		c.mu.Lock()
+		s := t.Snapshot()
+		c.mu.Unlock()
+		return s.Iter()
+	case *RbTree:
+		c.mu.Lock()
+		s := t.Snapshot()
+		c.mu.Unlock()
+		return s.Iter()
+	}
+	c.rlock()
+	defer c.runlock()
+	switch t := c.tab.(type) {
+	case *PAvlTree:
+		return t.Copy().Iter()
+	case *PRbTree:
+		return t.Copy().Iter()
+	}
+	it := &sliceIter{items: make([]Item, 0, c.tab.Count()), pos: -1}
+	tit := c.tab.Iter()
+	for item := tit.First(); item != nil; item = tit.Next() {
+		it.items = append(it.items, item)
+	}
+	return it
+}
+
+//return iterator holding the read lock until Close is called,
+//writers are blocked in the meantime, so are readers of a SplayTree
+func (c *ConcurrentSymTab) LockedIter() *ConcurrentIter {
+	if c == nil {
+		return nil
+	}
+	c.rlock()
+	if !c.snapshotIter() {
+		return &ConcurrentIter{Iterator: c.tab.Iter(), c: c}
+	}
+	//hooking takes a snapshot, which switches epoch of tree, so it is a write,
+	//the snapshot does not see later writes, so they may slip in before read lock is taken again
+	c.runlock()
+	c.mu.Lock()
+	it := c.tab.Iter()
+	c.mu.Unlock()
+	c.rlock()
+	return &ConcurrentIter{Iterator: it, c: c}
+}
+
+//report whether iterators of tab are hooked with a snapshot of it
+func (c *ConcurrentSymTab) snapshotIter() bool {
+	switch t := c.tab.(type) {
+	case *AvlTree:
+		return t.iterPolicy == IterSnapshot
+	case *RbTree:
+		return t.iterPolicy == IterSnapshot
+	}
+	return false
+}
+
+//iterator returned by ConcurrentSymTab.LockedIter
+type ConcurrentIter struct {
+	Iterator
+	c *ConcurrentSymTab
+}
+
+//release the lock, the iterator must not be used afterwards
+func (it *ConcurrentIter) Close() {
+	if it == nil || it.c == nil {
+		return
+	}
+	it.c.runlock()
+	it.c = nil
+	it.Iterator = nil
+}
+
+//iterator over items copied out of a SymTab
+type sliceIter struct {
+	items []Item
+	pos   int //-1 is nil position
+}
+
+func (it *sliceIter) at(pos int) Item {
+	if pos < 0 || pos >= len(it.items) {
+		it.pos = -1
+		return nil
+	}
+	it.pos = pos
+	return it.items[pos]
+}
+
+func (it *sliceIter) First() Item {
+	return it.at(0)
+}
+
+func (it *sliceIter) Last() Item {
+	return it.at(len(it.items) - 1)
+}
+
+func (it *sliceIter) Next() Item {
+	if it.pos < 0 {
+		return it.First()
+	}
+	return it.at(it.pos + 1)
+}
+
+func (it *sliceIter) Prev() Item {
+	if it.pos < 0 {
+		return it.Last()
+	}
+	return it.at(it.pos - 1)
+}
+
+func (it *sliceIter) Current() Item {
+	if it.pos < 0 {
+		return nil
+	}
+	return it.items[it.pos]
+}
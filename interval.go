@@ -0,0 +1,203 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+	"math"
+)
+
+//closed interval [Lo, Hi] carrying a value
+type Interval[T, V any] struct {
+	Lo, Hi T
+	Value  V
+}
+
+//item of interval tree, max is the greatest Hi in the subtree of its node,
+//seq keeps intervals of the same endpoints in insertion order
+type ivItem[T, V any] struct {
+	iv  Interval[T, V]
+	max T
+	seq uint64
+}
+
+//order intervals by Lo, then by Hi, then by insertion order,
+//max and value never take part in comparison
+func ivCompare[T, V any](cmp func(a, b T) int) func(a, b ivItem[T, V]) int {
+	return func(a, b ivItem[T, V]) int {
+		if c := cmp(a.iv.Lo, b.iv.Lo); c != 0 {
+			return c
+		}
+		if c := cmp(a.iv.Hi, b.iv.Hi); c != 0 {
+			return c
+		}
+		if a.seq < b.seq {
+			return -1
+		} else if a.seq > b.seq {
+			return 1
+		}
+		return 0
+	}
+}
+
+//bounds of all items with the endpoints of iv, lo is below them and hi above them
+func ivBounds[T, V any](iv Interval[T, V]) (lo, hi ivItem[T, V]) {
+	return ivItem[T, V]{iv: iv}, ivItem[T, V]{iv: iv, seq: math.MaxUint64}
+}
+
+//keep max of n the greatest Hi of n, l and r
+func ivFix[T, V any](cmp func(a, b T) int) func(n, l, r *ivItem[T, V]) {
+	return func(n, l, r *ivItem[T, V]) {
+		n.max = n.iv.Hi
+		if l != nil && cmp(l.max, n.max) > 0 {
+			n.max = l.max
+		}
+		if r != nil && cmp(r.max, n.max) > 0 {
+			n.max = r.max
+		}
+	}
+}
+
+//operations an interval tree needs from its tree
+type ivTab[T any] interface {
+	SymTabOf[T]
+	seqTab[T]
+}
+
+//IntervalTree keeps intervals in a red black tree ordered by low endpoint,
+//every node is augmented with the max high endpoint of its subtree,
+//which rotations maintain, so overlap queries skip subtrees ending too early,
+//intervals of the same endpoints are all kept, in insertion order
+type IntervalTree[T, V any] struct {
+	tab ivTab[ivItem[T, V]]
+	cmp func(a, b T) int
+	seq uint64 //sequence number of the last inserted interval
+}
+
+func NewRbIntervalTree[T, V any](cmp func(a, b T) int) *IntervalTree[T, V] {
+	if cmp == nil {
+		return nil
+	}
+	s := NewRbSet(ivCompare[T, V](cmp))
+	s.fix = ivFix[T, V](cmp)
+	return &IntervalTree[T, V]{tab: s, cmp: cmp}
+}
+
+func NewPRbIntervalTree[T, V any](cmp func(a, b T) int) *IntervalTree[T, V] {
+	if cmp == nil {
+		return nil
+	}
+	s := NewPRbSet(ivCompare[T, V](cmp))
+	s.fix = ivFix[T, V](cmp)
+	return &IntervalTree[T, V]{tab: s, cmp: cmp}
+}
+
+func NewOrderedRbIntervalTree[T cmp.Ordered, V any]() *IntervalTree[T, V] {
+	return NewRbIntervalTree[T, V](cmp.Compare[T])
+}
+
+func NewOrderedPRbIntervalTree[T cmp.Ordered, V any]() *IntervalTree[T, V] {
+	return NewPRbIntervalTree[T, V](cmp.Compare[T])
+}
+
+func (t *IntervalTree[T, V]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.tab.Count()
+}
+
+//insert iv in tree, after all intervals with the same endpoints
+//return false if iv is empty, that is Lo > Hi
+func (t *IntervalTree[T, V]) Insert(iv Interval[T, V]) bool {
+	if t == nil || t.cmp(iv.Lo, iv.Hi) > 0 {
+		return false
+	}
+	t.seq++
+	return t.tab.Insert(ivItem[T, V]{iv: iv, seq: t.seq})
+}
+
+//delete the first inserted interval with the same endpoints as iv, Value of iv is ignored
+//return it if find it
+//else ok is false
+func (t *IntervalTree[T, V]) Delete(iv Interval[T, V]) (deleted Interval[T, V], ok bool) {
+	if t == nil {
+		return
+	}
+	for item := range t.tab.Between(ivBounds(iv)) {
+		t.tab.Delete(item)
+		return item.iv, true
+	}
+	return
+}
+
+//sequence of intervals overlapping [lo, hi] in ascending order,
+//O(log n + k) for k intervals found, tree must not be changed while ranging
+func (t *IntervalTree[T, V]) Overlapping(lo, hi T) iter.Seq[Interval[T, V]] {
+	if t == nil || t.cmp(lo, hi) > 0 {
+		return empty[Interval[T, V]]
+	}
+	return func(yield func(Interval[T, V]) bool) {
+		switch tab := t.tab.(type) {
+		case *RbSet[ivItem[T, V]]:
+			overlapRb(tab.root, t.cmp, lo, hi, yield)
+		case *PRbSet[ivItem[T, V]]:
+			overlapPRb(tab.root, t.cmp, lo, hi, yield)
+		}
+	}
+}
+
+//sequence of intervals containing point in ascending order
+func (t *IntervalTree[T, V]) Stab(point T) iter.Seq[Interval[T, V]] {
+	return t.Overlapping(point, point)
+}
+
+//sequence of all intervals in ascending order
+func (t *IntervalTree[T, V]) All() iter.Seq[Interval[T, V]] {
+	if t == nil {
+		return empty[Interval[T, V]]
+	}
+	return func(yield func(Interval[T, V]) bool) {
+		for item := range t.tab.All() {
+			if !yield(item.iv) {
+				return
+			}
+		}
+	}
+}
+
+//yield intervals of subtree n overlapping [lo, hi] in order
+//return false if yield asks to stop
+func overlapRb[T, V any](n *rbnode[ivItem[T, V]], cmp func(a, b T) int, lo, hi T, yield func(Interval[T, V]) bool) bool {
+	//no interval in subtree reaches lo
+	if n == nil || cmp(n.data.max, lo) < 0 {
+		return true
+	}
+	if !overlapRb(n.links[Left], cmp, lo, hi, yield) {
+		return false
+	}
+	//n and its right subtree start after hi
+	if cmp(n.data.iv.Lo, hi) > 0 {
+		return true
+	}
+	if cmp(lo, n.data.iv.Hi) <= 0 && !yield(n.data.iv) {
+		return false
+	}
+	return overlapRb(n.links[Right], cmp, lo, hi, yield)
+}
+
+//same as overlapRb for red black tree with parent pointer
+func overlapPRb[T, V any](n *prbnode[ivItem[T, V]], cmp func(a, b T) int, lo, hi T, yield func(Interval[T, V]) bool) bool {
+	if n == nil || cmp(n.data.max, lo) < 0 {
+		return true
+	}
+	if !overlapPRb(n.links[Left], cmp, lo, hi, yield) {
+		return false
+	}
+	if cmp(n.data.iv.Lo, hi) > 0 {
+		return true
+	}
+	if cmp(lo, n.data.iv.Hi) <= 0 && !yield(n.data.iv) {
+		return false
+	}
+	return overlapPRb(n.links[Right], cmp, lo, hi, yield)
+}
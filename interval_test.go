@@ -0,0 +1,138 @@
+package bbst
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+//check max of node is the greatest Hi of itself and its children
+func checkIvMax[T, V any](t *testing.T, name string, cmp func(a, b T) int, data ivItem[T, V], l, r *ivItem[T, V]) {
+	want := data.iv.Hi
+	if l != nil && cmp(l.max, want) > 0 {
+		want = l.max
+	}
+	if r != nil && cmp(r.max, want) > 0 {
+		want = r.max
+	}
+	if cmp(data.max, want) != 0 {
+		t.Errorf("%s: max of %v is %v, but should be %v\n", name, data.iv, data.max, want)
+	}
+}
+
+func checkRbIvMax(t *testing.T, name string, n *rbnode[ivItem[int, int]]) {
+	if n == nil {
+		return
+	}
+	checkRbIvMax(t, name, n.links[Left])
+	checkRbIvMax(t, name, n.links[Right])
+	checkIvMax(t, name, cmpInt, n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+}
+
+func checkPRbIvMax(t *testing.T, name string, n *prbnode[ivItem[int, int]]) {
+	if n == nil {
+		return
+	}
+	checkPRbIvMax(t, name, n.links[Left])
+	checkPRbIvMax(t, name, n.links[Right])
+	checkIvMax(t, name, cmpInt, n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+}
+
+func cmpInt(a, b int) int {
+	return a - b
+}
+
+func TestIntervalTree(t *testing.T) {
+	const n = 2000
+	trees := map[string]*IntervalTree[int, int]{
+		"RbIntervalTree":  NewRbIntervalTree[int, int](cmpInt),
+		"PRbIntervalTree": NewOrderedPRbIntervalTree[int, int](),
+	}
+	for name, tree := range trees {
+		var all []Interval[int, int] //in insertion order
+		checkMax := func() {
+			switch tab := tree.tab.(type) {
+			case *RbSet[ivItem[int, int]]:
+				checkRbIvMax(t, name, tab.root)
+			case *PRbSet[ivItem[int, int]]:
+				checkPRbIvMax(t, name, tab.root)
+			}
+		}
+		//intervals in the order of tree, equal endpoints keep insertion order
+		sorted := func() []Interval[int, int] {
+			return slices.SortedStableFunc(slices.Values(all), func(a, b Interval[int, int]) int {
+				if a.Lo != b.Lo {
+					return a.Lo - b.Lo
+				}
+				return a.Hi - b.Hi
+			})
+		}
+		//brute force overlap in the order of tree
+		overlapping := func(lo, hi int) []Interval[int, int] {
+			var found []Interval[int, int]
+			for _, iv := range sorted() {
+				if iv.Lo <= hi && lo <= iv.Hi {
+					found = append(found, iv)
+				}
+			}
+			return found
+		}
+		if tree.Insert(Interval[int, int]{Lo: 2, Hi: 1}) {
+			t.Errorf("%s: insert of empty interval should fail\n", name)
+		}
+		for i := 0; i < n; i++ {
+			lo := rand.Intn(n)
+			iv := Interval[int, int]{Lo: lo, Hi: lo + rand.Intn(n/10+1), Value: i}
+			if i%5 == 4 {
+				//same window, other value
+				iv.Lo, iv.Hi = all[rand.Intn(len(all))].Lo, all[rand.Intn(len(all))].Hi
+				iv.Hi = max(iv.Hi, iv.Lo)
+			}
+			if !tree.Insert(iv) {
+				t.Fatalf("%s: insert of %v failed\n", name, iv)
+			}
+			all = append(all, iv)
+		}
+		checkMax()
+		if got := slices.Collect(tree.All()); !slices.Equal(got, sorted()) {
+			t.Errorf("%s: All does not match inserted intervals\n", name)
+		}
+		for i := 0; i < 200; i++ {
+			lo := rand.Intn(n + n/10)
+			hi := lo + rand.Intn(n/20+1)
+			if got, want := slices.Collect(tree.Overlapping(lo, hi)), overlapping(lo, hi); !slices.Equal(got, want) {
+				t.Errorf("%s: Overlapping(%d, %d) is %v, but should be %v\n", name, lo, hi, got, want)
+			}
+			if got, want := slices.Collect(tree.Stab(lo)), overlapping(lo, lo); !slices.Equal(got, want) {
+				t.Errorf("%s: Stab(%d) is %v, but should be %v\n", name, lo, got, want)
+			}
+		}
+		if got := slices.Collect(tree.Overlapping(3, 2)); len(got) != 0 {
+			t.Errorf("%s: Overlapping of empty range is %v\n", name, got)
+		}
+		//delete half of them, the first inserted of the same endpoints goes first
+		for range len(all) / 2 {
+			iv := all[rand.Intn(len(all))]
+			i := slices.IndexFunc(all, func(x Interval[int, int]) bool {
+				return x.Lo == iv.Lo && x.Hi == iv.Hi
+			})
+			if got, ok := tree.Delete(Interval[int, int]{Lo: iv.Lo, Hi: iv.Hi}); !ok || got != all[i] {
+				t.Errorf("%s: delete of %v returned %v, %v, but should return %v\n", name, iv, got, ok, all[i])
+			}
+			all = slices.Delete(all, i, i+1)
+		}
+		if _, ok := tree.Delete(Interval[int, int]{Lo: n * 2, Hi: n * 2}); ok {
+			t.Errorf("%s: delete of missing interval should fail\n", name)
+		}
+		checkMax()
+		if tree.Count() != len(all) {
+			t.Errorf("%s: count is %d, but should be %d\n", name, tree.Count(), len(all))
+		}
+		for i := 0; i < 200; i++ {
+			p := rand.Intn(n + n/10)
+			if got, want := slices.Collect(tree.Stab(p)), overlapping(p, p); !slices.Equal(got, want) {
+				t.Errorf("%s: Stab(%d) is %v, but should be %v\n", name, p, got, want)
+			}
+		}
+	}
+}
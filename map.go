@@ -0,0 +1,244 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+//key value pair stored in an OrderedMap
+type Entry[K, V any] struct {
+	Key   K
+	Value V
+}
+
+//map ordered by key, backed by any of the four trees
+type OrderedMap[K, V any] struct {
+	tab SymTabOf[Entry[K, V]]
+}
+
+//order entries by key only, value never takes part in comparison
+func entryCompare[K, V any](cmp func(a, b K) int) func(a, b Entry[K, V]) int {
+	return func(a, b Entry[K, V]) int {
+		return cmp(a.Key, b.Key)
+	}
+}
+
+func NewAvlMap[K, V any](cmp func(a, b K) int) *OrderedMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &OrderedMap[K, V]{NewAvlSet(entryCompare[K, V](cmp))}
+}
+
+func NewPAvlMap[K, V any](cmp func(a, b K) int) *OrderedMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &OrderedMap[K, V]{NewPAvlSet(entryCompare[K, V](cmp))}
+}
+
+func NewRbMap[K, V any](cmp func(a, b K) int) *OrderedMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &OrderedMap[K, V]{NewRbSet(entryCompare[K, V](cmp))}
+}
+
+func NewPRbMap[K, V any](cmp func(a, b K) int) *OrderedMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &OrderedMap[K, V]{NewPRbSet(entryCompare[K, V](cmp))}
+}
+
+func NewOrderedAvlMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return NewAvlMap[K, V](cmp.Compare[K])
+}
+
+func NewOrderedPAvlMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return NewPAvlMap[K, V](cmp.Compare[K])
+}
+
+func NewOrderedRbMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return NewRbMap[K, V](cmp.Compare[K])
+}
+
+func NewOrderedPRbMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return NewPRbMap[K, V](cmp.Compare[K])
+}
+
+func (m *OrderedMap[K, V]) Count() int {
+	if m == nil {
+		return 0
+	}
+	return m.tab.Count()
+}
+
+//search key in map
+//if find it return its value
+//else ok is false
+func (m *OrderedMap[K, V]) Find(key K) (value V, ok bool) {
+	if m == nil {
+		return
+	}
+	e, ok := m.tab.Find(Entry[K, V]{Key: key})
+	return e.Value, ok
+}
+
+//insert key with value in map
+//return false if key already in map
+func (m *OrderedMap[K, V]) Insert(key K, value V) bool {
+	if m == nil {
+		return false
+	}
+	return m.tab.Insert(Entry[K, V]{key, value})
+}
+
+//set value of key, insert key if no such key
+//return old value, ok is false if key was newly inserted
+func (m *OrderedMap[K, V]) Replace(key K, value V) (old V, ok bool) {
+	if m == nil {
+		return
+	}
+	e, ok := m.tab.Replace(Entry[K, V]{key, value})
+	return e.Value, ok
+}
+
+//delete key in map
+//return its value if find it
+//else ok is false
+func (m *OrderedMap[K, V]) Delete(key K) (value V, ok bool) {
+	if m == nil {
+		return
+	}
+	e, ok := m.tab.Delete(Entry[K, V]{Key: key})
+	return e.Value, ok
+}
+
+//trees able to hand out the address of an item, inserting the item if it is missing
+type addrTab[T any] interface {
+	insert(item T) (*T, bool)
+}
+
+//return address of entry of key in tree, insert key with value if no such key
+//inserted tells whether the entry is new
+func (m *OrderedMap[K, V]) entry(key K, value V) (e *Entry[K, V], inserted bool) {
+	return m.tab.(addrTab[Entry[K, V]]).insert(Entry[K, V]{key, value})
+}
+
+//return value of key
+//ok is false if no such key
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	return m.Find(key)
+}
+
+//set value of key, insert key if no such key
+func (m *OrderedMap[K, V]) Put(key K, value V) {
+	if m == nil {
+		return
+	}
+	e, _ := m.entry(key, value)
+	e.Value = value
+}
+
+//return value of key if find it, else insert key with value and return value
+//found is false in the latter case
+func (m *OrderedMap[K, V]) GetOrInsert(key K, value V) (actual V, found bool) {
+	if m == nil {
+		return
+	}
+	e, inserted := m.entry(key, value)
+	return e.Value, !inserted
+}
+
+//set value of key to what fn returns, with a single search,
+//fn gets the old value, ok is false if key is not in map, key is inserted then
+//return the new value
+func (m *OrderedMap[K, V]) Update(key K, fn func(old V, ok bool) V) (value V) {
+	if m == nil || fn == nil {
+		return
+	}
+	e, inserted := m.entry(key, value)
+	e.Value = fn(e.Value, !inserted)
+	return e.Value
+}
+
+//iterate entries in key order
+func (m *OrderedMap[K, V]) Iter() IteratorOf[Entry[K, V]] {
+	if m == nil {
+		return nil
+	}
+	return m.tab.Iter()
+}
+
+func (m *OrderedMap[K, V]) seq() seqTab[Entry[K, V]] {
+	return m.tab.(seqTab[Entry[K, V]])
+}
+
+//sequence of all key value pairs in ascending key order
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.seq().All())
+}
+
+//sequence of all key value pairs in descending key order
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.seq().Backward())
+}
+
+//sequence of pairs with key greater than or equal to from in ascending key order
+func (m *OrderedMap[K, V]) Ascend(from K) iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.seq().Ascend(Entry[K, V]{Key: from}))
+}
+
+//sequence of pairs with key less than or equal to from in descending key order
+func (m *OrderedMap[K, V]) Descend(from K) iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.seq().Descend(Entry[K, V]{Key: from}))
+}
+
+//sequence of pairs with key in [lo, hi) in ascending key order
+func (m *OrderedMap[K, V]) Between(lo, hi K) iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.seq().Between(Entry[K, V]{Key: lo}, Entry[K, V]{Key: hi}))
+}
+
+//sequence of all keys in ascending order
+func (m *OrderedMap[K, V]) Keys() iter.Seq[K] {
+	if m == nil {
+		return empty[K]
+	}
+	return func(yield func(K) bool) {
+		for e := range m.seq().All() {
+			if !yield(e.Key) {
+				return
+			}
+		}
+	}
+}
+
+//sequence of all values in ascending key order
+func (m *OrderedMap[K, V]) Values() iter.Seq[V] {
+	if m == nil {
+		return empty[V]
+	}
+	return func(yield func(V) bool) {
+		for e := range m.seq().All() {
+			if !yield(e.Value) {
+				return
+			}
+		}
+	}
+}
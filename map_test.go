@@ -0,0 +1,147 @@
+package bbst
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newStringIntMap(typ int) *OrderedMap[string, int] {
+	switch typ {
+	case avlNoParent:
+		return NewOrderedAvlMap[string, int]()
+	case avlWithParent:
+		return NewOrderedPAvlMap[string, int]()
+	case rbNoParent:
+		return NewOrderedRbMap[string, int]()
+	case rbWithParent:
+		return NewOrderedPRbMap[string, int]()
+	}
+	return nil
+}
+
+func TestOrderedMap(t *testing.T) {
+	for typ, name := range treeNames {
+		m := newStringIntMap(typ)
+		m.Insert("GPU", 15)
+		m.Insert("RAM", 20)
+		m.Insert("CPU", 10)
+		if m.Insert("CPU", 99) {
+			t.Errorf("%s: duplicate key inserted\n", name)
+		}
+		if old, ok := m.Replace("CPU", 25); !ok || old != 10 {
+			t.Errorf("%s: replace returned %d, %v\n", name, old, ok)
+		}
+		if _, ok := m.Replace("SSD", 30); ok {
+			t.Errorf("%s: replace of new key reported old value\n", name)
+		}
+		if v, ok := m.Find("CPU"); !ok || v != 25 {
+			t.Errorf("%s: find CPU returned %d, %v\n", name, v, ok)
+		}
+		want := []Entry[string, int]{{"CPU", 25}, {"GPU", 15}, {"RAM", 20}, {"SSD", 30}}
+		it := m.Iter()
+		i := 0
+		for e, ok := it.First(); ok; e, ok = it.Next() {
+			if i >= len(want) || e != want[i] {
+				t.Errorf("%s: entry %d is %v\n", name, i, e)
+			}
+			i++
+		}
+		if v, ok := m.Delete("GPU"); !ok || v != 15 {
+			t.Errorf("%s: delete GPU returned %d, %v\n", name, v, ok)
+		}
+		if _, ok := m.Find("GPU"); ok || m.Count() != 3 {
+			t.Errorf("%s: GPU still in map, count %d\n", name, m.Count())
+		}
+	}
+}
+
+func TestOrderedMapSeq(t *testing.T) {
+	want := map[string]int{"CPU": 10, "GPU": 15, "RAM": 20, "SSD": 30}
+	keys := slices.Sorted(maps.Keys(want))
+	for typ, name := range treeNames {
+		m := newStringIntMap(typ)
+		for k, v := range want {
+			m.Insert(k, v)
+		}
+		if got := maps.Collect(m.All()); !maps.Equal(got, want) {
+			t.Errorf("%s: collected %v\n", name, got)
+		}
+		var got []string
+		for k, v := range m.Backward() {
+			if v != want[k] {
+				t.Errorf("%s: key %s has value %d\n", name, k, v)
+			}
+			got = append(got, k)
+		}
+		if !slices.Equal(got, []string{"SSD", "RAM", "GPU", "CPU"}) {
+			t.Errorf("%s: backward keys %v\n", name, got)
+		}
+		got = got[:0]
+		for k := range m.Ascend("D") {
+			got = append(got, k)
+		}
+		if !slices.Equal(got, keys[1:]) {
+			t.Errorf("%s: keys from D are %v\n", name, got)
+		}
+		got = got[:0]
+		for k := range m.Descend("GPU") {
+			got = append(got, k)
+		}
+		if !slices.Equal(got, []string{"GPU", "CPU"}) {
+			t.Errorf("%s: keys down from GPU are %v\n", name, got)
+		}
+		got = got[:0]
+		for k := range m.Between("CPU", "RAM") {
+			got = append(got, k)
+		}
+		if !slices.Equal(got, keys[:2]) {
+			t.Errorf("%s: keys in [CPU, RAM) are %v\n", name, got)
+		}
+	}
+}
+
+func TestOrderedMapUpdate(t *testing.T) {
+	words := strings.Fields("the quick fox jumps over the lazy dog the end")
+	for typ, name := range treeNames {
+		m := newStringIntMap(typ)
+		for _, w := range words {
+			m.Update(w, func(old int, ok bool) int {
+				if ok != (old > 0) {
+					t.Errorf("%s: update of %s got %d, %v\n", name, w, old, ok)
+				}
+				return old + 1
+			})
+		}
+		if v, ok := m.Get("the"); !ok || v != 3 {
+			t.Errorf("%s: the counted %d times\n", name, v)
+		}
+		if v, found := m.GetOrInsert("fox", 99); !found || v != 1 {
+			t.Errorf("%s: get or insert fox returned %d, %v\n", name, v, found)
+		}
+		if v, found := m.GetOrInsert("cat", 7); found || v != 7 {
+			t.Errorf("%s: get or insert cat returned %d, %v\n", name, v, found)
+		}
+		m.Put("cat", 8)
+		m.Put("bird", 9)
+		if v, _ := m.Get("cat"); v != 8 || m.Count() != 10 {
+			t.Errorf("%s: cat is %d after put, count %d\n", name, v, m.Count())
+		}
+		if v, ok := m.Delete("bird"); !ok || v != 9 {
+			t.Errorf("%s: delete bird returned %d, %v\n", name, v, ok)
+		}
+		if _, ok := m.Get("bird"); ok {
+			t.Errorf("%s: bird still in map\n", name)
+		}
+		keys, values := slices.Collect(m.Keys()), slices.Collect(m.Values())
+		if !slices.IsSorted(keys) || len(keys) != m.Count() || len(values) != m.Count() {
+			t.Errorf("%s: keys %v\n", name, keys)
+		}
+		for i, k := range keys {
+			if v, _ := m.Get(k); v != values[i] {
+				t.Errorf("%s: value of %s is %d, but sequence has %d\n", name, k, v, values[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+	"math"
+)
+
+//item of a multiset, seq keeps equal items in insertion order
+type multiItem[T any] struct {
+	item T
+	seq  uint64
+}
+
+//order items by cmp, then equal items by insertion order
+func multiCompare[T any](cmp func(a, b T) int) func(a, b multiItem[T]) int {
+	return func(a, b multiItem[T]) int {
+		if c := cmp(a.item, b.item); c != 0 {
+			return c
+		}
+		if a.seq < b.seq {
+			return -1
+		} else if a.seq > b.seq {
+			return 1
+		}
+		return 0
+	}
+}
+
+//operations a multiset needs from its tree
+type multiTab[T any] interface {
+	SymTabOf[T]
+	seqTab[T]
+	Rank(item T) int
+	DeleteRange(lo, hi T) int
+}
+
+//set allowing equal items, backed by any of the four trees,
+//equal items are kept in insertion order
+type MultiSet[T any] struct {
+	tab multiTab[multiItem[T]]
+	seq uint64 //sequence number of the last inserted item
+}
+
+func NewAvlMultiSet[T any](cmp func(a, b T) int) *MultiSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiSet[T]{tab: NewAvlSet(multiCompare(cmp))}
+}
+
+func NewPAvlMultiSet[T any](cmp func(a, b T) int) *MultiSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiSet[T]{tab: NewPAvlSet(multiCompare(cmp))}
+}
+
+func NewRbMultiSet[T any](cmp func(a, b T) int) *MultiSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiSet[T]{tab: NewRbSet(multiCompare(cmp))}
+}
+
+func NewPRbMultiSet[T any](cmp func(a, b T) int) *MultiSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiSet[T]{tab: NewPRbSet(multiCompare(cmp))}
+}
+
+func NewOrderedAvlMultiSet[T cmp.Ordered]() *MultiSet[T] {
+	return NewAvlMultiSet(cmp.Compare[T])
+}
+
+func NewOrderedPAvlMultiSet[T cmp.Ordered]() *MultiSet[T] {
+	return NewPAvlMultiSet(cmp.Compare[T])
+}
+
+func NewOrderedRbMultiSet[T cmp.Ordered]() *MultiSet[T] {
+	return NewRbMultiSet(cmp.Compare[T])
+}
+
+func NewOrderedPRbMultiSet[T cmp.Ordered]() *MultiSet[T] {
+	return NewPRbMultiSet(cmp.Compare[T])
+}
+
+//bounds of all items equal to key, lo is below them and hi above them
+func bounds[T any](key T) (lo, hi multiItem[T]) {
+	return multiItem[T]{key, 0}, multiItem[T]{key, math.MaxUint64}
+}
+
+//turn a sequence of multiset items into a sequence of the items
+func unwrap[T any](seq iter.Seq[multiItem[T]]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for m := range seq {
+			if !yield(m.item) {
+				return
+			}
+		}
+	}
+}
+
+//return number of all items
+func (s *MultiSet[T]) Len() int {
+	if s == nil {
+		return 0
+	}
+	return s.tab.Count()
+}
+
+//return number of items equal to key, O(log n)
+func (s *MultiSet[T]) Count(key T) int {
+	if s == nil {
+		return 0
+	}
+	lo, hi := bounds(key)
+	return s.tab.Rank(hi) - s.tab.Rank(lo)
+}
+
+//search key in set
+//if find it return the first inserted of the equal items
+//else ok is false
+func (s *MultiSet[T]) Find(key T) (item T, ok bool) {
+	if s == nil {
+		return
+	}
+	for item := range s.EqualRange(key) {
+		return item, true
+	}
+	return
+}
+
+//insert item in set, after all items equal to it
+func (s *MultiSet[T]) Insert(item T) {
+	if s == nil {
+		return
+	}
+	s.seq++
+	s.tab.Insert(multiItem[T]{item, s.seq})
+}
+
+//sequence of items equal to key in insertion order
+func (s *MultiSet[T]) EqualRange(key T) iter.Seq[T] {
+	if s == nil {
+		return empty[T]
+	}
+	return unwrap(s.tab.Between(bounds(key)))
+}
+
+//delete the first inserted item equal to key
+//return item if find it
+//else ok is false
+func (s *MultiSet[T]) DeleteOne(key T) (deleted T, ok bool) {
+	if s == nil {
+		return
+	}
+	lo, hi := bounds(key)
+	for m := range s.tab.Between(lo, hi) {
+		s.tab.Delete(m)
+		return m.item, true
+	}
+	return
+}
+
+//delete all items equal to key, O(log n)
+//return number of deleted items
+func (s *MultiSet[T]) DeleteAll(key T) int {
+	if s == nil {
+		return 0
+	}
+	return s.tab.DeleteRange(bounds(key))
+}
+
+//sequence of all items in ascending order
+func (s *MultiSet[T]) All() iter.Seq[T] {
+	if s == nil {
+		return empty[T]
+	}
+	return unwrap(s.tab.All())
+}
+
+//sequence of all items in descending order,
+//equal items come in reverse insertion order
+func (s *MultiSet[T]) Backward() iter.Seq[T] {
+	if s == nil {
+		return empty[T]
+	}
+	return unwrap(s.tab.Backward())
+}
+
+//map allowing many values of one key, backed by any of the four trees,
+//values of a key are kept in insertion order
+type MultiMap[K, V any] struct {
+	set *MultiSet[Entry[K, V]]
+}
+
+func NewAvlMultiMap[K, V any](cmp func(a, b K) int) *MultiMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiMap[K, V]{NewAvlMultiSet(entryCompare[K, V](cmp))}
+}
+
+func NewPAvlMultiMap[K, V any](cmp func(a, b K) int) *MultiMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiMap[K, V]{NewPAvlMultiSet(entryCompare[K, V](cmp))}
+}
+
+func NewRbMultiMap[K, V any](cmp func(a, b K) int) *MultiMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiMap[K, V]{NewRbMultiSet(entryCompare[K, V](cmp))}
+}
+
+func NewPRbMultiMap[K, V any](cmp func(a, b K) int) *MultiMap[K, V] {
+	if cmp == nil {
+		return nil
+	}
+	return &MultiMap[K, V]{NewPRbMultiSet(entryCompare[K, V](cmp))}
+}
+
+func NewOrderedAvlMultiMap[K cmp.Ordered, V any]() *MultiMap[K, V] {
+	return NewAvlMultiMap[K, V](cmp.Compare[K])
+}
+
+func NewOrderedPAvlMultiMap[K cmp.Ordered, V any]() *MultiMap[K, V] {
+	return NewPAvlMultiMap[K, V](cmp.Compare[K])
+}
+
+func NewOrderedRbMultiMap[K cmp.Ordered, V any]() *MultiMap[K, V] {
+	return NewRbMultiMap[K, V](cmp.Compare[K])
+}
+
+func NewOrderedPRbMultiMap[K cmp.Ordered, V any]() *MultiMap[K, V] {
+	return NewPRbMultiMap[K, V](cmp.Compare[K])
+}
+
+//return number of all key value pairs
+func (m *MultiMap[K, V]) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.set.Len()
+}
+
+//return number of values of key, O(log n)
+func (m *MultiMap[K, V]) Count(key K) int {
+	if m == nil {
+		return 0
+	}
+	return m.set.Count(Entry[K, V]{Key: key})
+}
+
+//search key in map
+//if find it return its first inserted value
+//else ok is false
+func (m *MultiMap[K, V]) Find(key K) (value V, ok bool) {
+	if m == nil {
+		return
+	}
+	e, ok := m.set.Find(Entry[K, V]{Key: key})
+	return e.Value, ok
+}
+
+//add value to key, after all values of key
+func (m *MultiMap[K, V]) Insert(key K, value V) {
+	if m == nil {
+		return
+	}
+	m.set.Insert(Entry[K, V]{key, value})
+}
+
+//sequence of pairs of key in insertion order
+func (m *MultiMap[K, V]) EqualRange(key K) iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.set.EqualRange(Entry[K, V]{Key: key}))
+}
+
+//delete the first inserted value of key
+//return the value if find it
+//else ok is false
+func (m *MultiMap[K, V]) DeleteOne(key K) (value V, ok bool) {
+	if m == nil {
+		return
+	}
+	e, ok := m.set.DeleteOne(Entry[K, V]{Key: key})
+	return e.Value, ok
+}
+
+//delete all values of key, O(log n)
+//return number of deleted values
+func (m *MultiMap[K, V]) DeleteAll(key K) int {
+	if m == nil {
+		return 0
+	}
+	return m.set.DeleteAll(Entry[K, V]{Key: key})
+}
+
+//sequence of all key value pairs in ascending key order
+func (m *MultiMap[K, V]) All() iter.Seq2[K, V] {
+	if m == nil {
+		return empty2[K, V]
+	}
+	return pairs(m.set.All())
+}
@@ -0,0 +1,117 @@
+package bbst
+
+import (
+	"slices"
+	"testing"
+)
+
+func newIntMultiSet(typ int) *MultiSet[int] {
+	switch typ {
+	case avlNoParent:
+		return NewOrderedAvlMultiSet[int]()
+	case avlWithParent:
+		return NewOrderedPAvlMultiSet[int]()
+	case rbNoParent:
+		return NewOrderedRbMultiSet[int]()
+	case rbWithParent:
+		return NewOrderedPRbMultiSet[int]()
+	}
+	return nil
+}
+
+func newRuneIntMultiMap(typ int) *MultiMap[rune, int] {
+	switch typ {
+	case avlNoParent:
+		return NewOrderedAvlMultiMap[rune, int]()
+	case avlWithParent:
+		return NewOrderedPAvlMultiMap[rune, int]()
+	case rbNoParent:
+		return NewOrderedRbMultiMap[rune, int]()
+	case rbWithParent:
+		return NewOrderedPRbMultiMap[rune, int]()
+	}
+	return nil
+}
+
+func TestMultiSet(t *testing.T) {
+	n := len(insertArr)
+	for typ, name := range treeNames {
+		s := newIntMultiSet(typ)
+		//i%5 is inserted once for every i
+		for _, elem := range insertArr {
+			s.Insert(elem % 5)
+		}
+		if s.Len() != n {
+			t.Errorf("%s: len is %d, but should be %d\n", name, s.Len(), n)
+		}
+		want := make([]int, 0, n)
+		for _, elem := range insertArr {
+			want = append(want, elem%5)
+		}
+		slices.Sort(want)
+		if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+			t.Errorf("%s: items %v\n", name, got)
+		}
+		for k := 0; k < 5; k++ {
+			cnt := (n - k + 4) / 5
+			if s.Count(k) != cnt || len(slices.Collect(s.EqualRange(k))) != cnt {
+				t.Errorf("%s: %d counted %d times, but should be %d\n", name, k, s.Count(k), cnt)
+			}
+		}
+		if s.Count(5) != 0 || s.DeleteAll(5) != 0 {
+			t.Errorf("%s: missing key found\n", name)
+		}
+		if _, ok := s.DeleteOne(5); ok {
+			t.Errorf("%s: missing key deleted\n", name)
+		}
+		cnt := s.Count(0)
+		if v, ok := s.DeleteOne(0); cnt > 0 && (!ok || v != 0 || s.Count(0) != cnt-1) {
+			t.Errorf("%s: delete one left %d items\n", name, s.Count(0))
+		}
+		cnt, left := s.Count(1), s.Len()
+		if del := s.DeleteAll(1); del != cnt || s.Count(1) != 0 || s.Len() != left-cnt {
+			t.Errorf("%s: delete all deleted %d items, but should be %d\n", name, del, cnt)
+		}
+	}
+}
+
+func TestMultiMapOf(t *testing.T) {
+	str := "this is it"
+	for typ, name := range treeNames {
+		m := newRuneIntMultiMap(typ)
+		for pos, char := range str {
+			if char != ' ' {
+				m.Insert(char, pos)
+			}
+		}
+		if m.Len() != 8 || m.Count('i') != 3 || m.Count('s') != 2 || m.Count('x') != 0 {
+			t.Errorf("%s: len %d, i counted %d times\n", name, m.Len(), m.Count('i'))
+		}
+		var pos []int
+		for _, p := range m.EqualRange('i') {
+			pos = append(pos, p)
+		}
+		if !slices.Equal(pos, []int{2, 5, 8}) {
+			t.Errorf("%s: positions of i are %v\n", name, pos)
+		}
+		if p, ok := m.Find('t'); !ok || p != 0 {
+			t.Errorf("%s: first t at %d\n", name, p)
+		}
+		if p, ok := m.DeleteOne('i'); !ok || p != 2 {
+			t.Errorf("%s: delete one i returned %d\n", name, p)
+		}
+		if p, _ := m.Find('i'); p != 5 || m.Count('i') != 2 {
+			t.Errorf("%s: first i at %d after delete\n", name, p)
+		}
+		if m.DeleteAll('s') != 2 || m.Count('s') != 0 || m.Len() != 5 {
+			t.Errorf("%s: delete all s left len %d\n", name, m.Len())
+		}
+		var keys []rune
+		for k := range m.All() {
+			keys = append(keys, k)
+		}
+		if string(keys) != "hiitt" {
+			t.Errorf("%s: keys are %q\n", name, string(keys))
+		}
+	}
+}
@@ -1,34 +1,90 @@
 package bbst
 
 import (
+	"cmp"
+	"iter"
 	"unsafe"
 )
 
-type pnode struct {
-	links   [ChildNum]*pnode //child node
-	parent  *pnode           //parent node
-	data    Item             //data item
-	balance int8             //balance factor
+type pnode[T any] struct {
+	links   [ChildNum]*pnode[T] //child node
+	parent  *pnode[T]           //parent node
+	data    T                   //data item
+	balance int8                //balance factor
+	size    int                 //number of node in subtree
 }
 
-type PAvlTree struct {
-	root       *pnode      //root of  tree
-	cmpFunc    Compare     //compare function
-	extraParam interface{} //extra param for cmpFunc
-	count      int         // number of item in tree
+//number of node in subtree rooted at n
+func (n *pnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+//recompute size of n from its children
+func (n *pnode[T]) updateSize() {
+	n.size = n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+//address of data of n, nil if n is nil
+func (n *pnode[T]) dataAddr() *T {
+	if n == nil {
+		return nil
+	}
+	return &n.data
+}
+
+//child of n in direction dir
+func (n *pnode[T]) child(dir int) *pnode[T] {
+	return n.links[dir]
 }
 
-func NewPAvlTree(cmp Compare, extra interface{}) *PAvlTree {
+//balance bits of shape byte of n, see marshalTree
+func (n *pnode[T]) shape() byte {
+	return byte(n.balance + 1)
+}
+
+type PAvlSet[T any] struct {
+	root       *pnode[T]        //root of  tree
+	cmpFunc    func(a, b T) int //compare function
+	count      int              // number of item in tree
+	generation int              // generation number
+	iterPolicy IterPolicy       //policy of iterators hooked with tree
+	codec      Codec[T]         //codec of items for MarshalBinary and UnmarshalBinary
+	fix        func(n, l, r *T) //recompute augmented data of item n from children l and r, nil if tree is not augmented
+}
+
+func NewPAvlSet[T any](cmp func(a, b T) int) *PAvlSet[T] {
 	if cmp == nil {
 		return nil
 	}
-	return &PAvlTree{
-		cmpFunc:    cmp,
-		extraParam: extra,
+	return &PAvlSet[T]{
+		cmpFunc: cmp,
 	}
 }
 
-func (t *PAvlTree) Count() int {
+//create a set holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n) without rebalancing
+//return ErrNotSorted if items are out of order or have duplicates
+func NewPAvlSetFromSorted[T any](cmp func(a, b T) int, items []T) (*PAvlSet[T], error) {
+	t := NewPAvlSet(cmp)
+	if t == nil {
+		return nil, nil
+	}
+	if err := t.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedPAvlSet[T cmp.Ordered]() *PAvlSet[T] {
+	return NewPAvlSet(cmp.Compare[T])
+}
+
+func (t *PAvlSet[T]) Count() int {
 	if t == nil {
 		return 0
 	}
@@ -37,39 +93,215 @@ func (t *PAvlTree) Count() int {
 
 //search target in tree
 //if find it return item
-//else return nil
-func (t *PAvlTree) Find(target Item) Item {
-	if t == nil || target == nil {
-		return nil
+//else ok is false
+func (t *PAvlSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	for w := t.root; w != nil; {
+		ret := t.cmpFunc(target, w.data)
+		if ret < 0 {
+			w = w.links[Left]
+		} else if ret > 0 {
+			w = w.links[Right]
+		} else {
+			return w.data, true
+		}
+	}
+	return
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *PAvlSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil || k < 0 || k >= t.count {
+		return
+	}
+	w := t.root
+	for {
+		ls := w.links[Left].subSize()
+		if k < ls {
+			w = w.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			w = w.links[Right]
+		} else {
+			return w.data, true
+		}
+	}
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *PAvlSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
 	}
+	r := 0
 	for w := t.root; w != nil; {
-		ret := t.cmpFunc(target, w.data, t.extraParam)
+		ret := t.cmpFunc(item, w.data)
 		if ret < 0 {
 			w = w.links[Left]
 		} else if ret > 0 {
+			r += w.links[Left].subSize() + 1
 			w = w.links[Right]
 		} else {
-			return w.data
+			return r + w.links[Left].subSize()
+		}
+	}
+	return r
+}
+
+//return the nearest node to item in direction dir,
+//dir Right looks for the least node greater than item,
+//dir Left looks for the greatest node less than item,
+//node equal to item is accepted if eq is true
+func (t *PAvlSet[T]) near(item T, dir int, eq bool) *pnode[T] {
+	var c *pnode[T] //candidate node
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			return w
+		}
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
 		}
 	}
+	return c
+}
+
+//return the greatest item less than or equal to item
+//ok is false if there is no such item
+func (t *PAvlSet[T]) Floor(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Left, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return the least item greater than or equal to item
+//ok is false if there is no such item
+func (t *PAvlSet[T]) Ceiling(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Right, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return iterator at the first item not less than item
+func (t *PAvlSet[T]) LowerBound(item T) IteratorOf[T] {
+	it := NewPAvlSetIter[T]()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *PAvlSet[T]) UpperBound(item T) IteratorOf[T] {
+	it := NewPAvlSetIter[T]()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *PAvlSet[T]) Range(lo, hi T, fn func(item T) bool) {
+	if t == nil || fn == nil {
+		return
+	}
+	var it PAvlSetIter[T]
+	for item, ok := it.HookWith(t).SeekGE(lo); ok && t.cmpFunc(item, hi) < 0; item, ok = it.Next() {
+		if !fn(item) {
+			return
+		}
+	}
+}
+
+//recompute size and augmented data of n from its children
+func (t *PAvlSet[T]) update(n *pnode[T]) {
+	n.updateSize()
+	if t.fix != nil {
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of n and all its ancestors
+func (t *PAvlSet[T]) fixUp(n *pnode[T]) {
+	if t.fix == nil {
+		return
+	}
+	for ; n != nil; n = n.parent {
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of nodes on the path from root to item,
+//after item was changed in place
+func (t *PAvlSet[T]) fixKey(item T) {
+	if t.fix == nil {
+		return
+	}
+	w := t.root
+	for w != nil {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			break
+		}
+		if cmp > 0 {
+			w = w.links[Right]
+		} else {
+			w = w.links[Left]
+		}
+	}
+	t.fixUp(w)
+}
+
+//replace items of t with items, which are sorted in strictly ascending order
+func (t *PAvlSet[T]) loadSorted(items []T) error {
+	if err := checkSorted(t.cmpFunc, items); err != nil {
+		return err
+	}
+	t.root, _ = t.build(items)
+	t.count = len(items)
+	t.generation++
 	return nil
 }
 
-func (t *PAvlTree) insert(item Item) (*Item, bool) {
-	if t == nil || item == nil {
+//build a perfectly balanced subtree of items, return its root and height
+func (t *PAvlSet[T]) build(items []T) (*pnode[T], int) {
+	if len(items) == 0 {
+		return nil, 0
+	}
+	m := len(items) / 2
+	n := &pnode[T]{data: items[m]}
+	l, lh := t.build(items[:m])
+	r, rh := t.build(items[m+1:])
+	return n, t.link(n, l, lh, r, rh)
+}
+
+func (t *PAvlSet[T]) insert(item T) (*T, bool) {
+	if t == nil {
 		return nil, false
 	}
 	var (
-		y   *pnode //待更新平衡因子的最顶层节点
-		w   *pnode //current walk node
-		p   *pnode //w's  parent
-		n   *pnode //new node
-		r   *pnode //new root node of rebalanced subtree
-		dir byte   //下降方向
+		y   *pnode[T] //待更新平衡因子的最顶层节点
+		w   *pnode[T] //current walk node
+		p   *pnode[T] //w's  parent
+		n   *pnode[T] //new node
+		r   *pnode[T] //new root node of rebalanced subtree
+		dir byte      //下降方向
 	)
 	y = t.root
 	for p, w = nil, t.root; w != nil; p, w = w, w.links[dir] {
-		cmp := t.cmpFunc(item, w.data, t.extraParam)
+		cmp := t.cmpFunc(item, w.data)
 		if cmp == 0 {
 			return &w.data, false
 		}
@@ -82,13 +314,18 @@ func (t *PAvlTree) insert(item Item) (*Item, bool) {
 			y = w
 		}
 	}
-	n = &pnode{data: item, parent: p}
+	n = &pnode[T]{data: item, parent: p, size: 1}
 	t.count++
+	t.generation++
+	for w = p; w != nil; w = w.parent {
+		w.size++
+	}
 	if p != nil {
 		p.links[dir] = n
 	} else {
 		t.root = n
 	}
+	t.fixUp(n)
 	if t.root == n {
 		return &n.data, true
 	}
@@ -107,6 +344,8 @@ func (t *PAvlTree) insert(item Item) (*Item, bool) {
 			r = x
 			y.links[Left] = x.links[Right]
 			x.links[Right] = y
+			t.update(y)
+			t.update(x)
 			x.balance = 0
 			y.balance = 0
 			x.parent = y.parent
@@ -120,6 +359,9 @@ func (t *PAvlTree) insert(item Item) (*Item, bool) {
 			r.links[Left] = x
 			y.links[Left] = r.links[Right]
 			r.links[Right] = y
+			t.update(x)
+			t.update(y)
+			t.update(r)
 			if r.balance == -1 {
 				x.balance = 0
 				y.balance = 1
@@ -148,6 +390,8 @@ func (t *PAvlTree) insert(item Item) (*Item, bool) {
 			r = x
 			y.links[Right] = x.links[Left]
 			x.links[Left] = y
+			t.update(y)
+			t.update(x)
 			x.balance = 0
 			y.balance = 0
 			x.parent = y.parent
@@ -161,6 +405,9 @@ func (t *PAvlTree) insert(item Item) (*Item, bool) {
 			r.links[Right] = x
 			y.links[Right] = r.links[Left]
 			r.links[Left] = y
+			t.update(x)
+			t.update(y)
+			t.update(r)
 			if r.balance == 1 {
 				x.balance = 0
 				y.balance = -1
@@ -202,37 +449,49 @@ func (t *PAvlTree) insert(item Item) (*Item, bool) {
 //insert item in tree
 //return true if item was successfully inserted
 //return false if item already in tree
-func (t *PAvlTree) Insert(item Item) bool {
+func (t *PAvlSet[T]) Insert(item T) bool {
 	_, succ := t.insert(item)
 	return succ
 }
 
+//insert all items in tree, items equal to one in tree or to an earlier one in items are skipped
+//the batch is sorted and merged into tree, O(m log(n/m + 1)) for m items in a tree of n items
+//return number of inserted items and skipped items in ascending order
+func (t *PAvlSet[T]) InsertBatch(items []T) (inserted int, dups []T) {
+	if t == nil {
+		return
+	}
+	return insertBatch(t, t.cmpFunc, items)
+}
+
 //replace item in tree with same key item
+//insert item if no such key, ok is false in that case
 //return old item
-func (t *PAvlTree) Replace(item Item) Item {
+func (t *PAvlSet[T]) Replace(item T) (old T, ok bool) {
 	addr, succ := t.insert(item)
 	if addr == nil || succ {
-		return nil
+		return
 	}
 	r := *addr
 	*addr = item
-	return r
+	t.fixKey(item)
+	return r, true
 }
 
 //delete item in tree
 //return item if find it
-//else  return nil
-func (t *PAvlTree) Delete(item Item) Item {
-	if t == nil || item == nil {
-		return nil
+//else ok is false
+func (t *PAvlSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
 	}
 	if t.root == nil {
-		return nil
+		return
 	}
 	var dir int
 	w := t.root //walk node
 	for {
-		cmp := t.cmpFunc(item, w.data, t.extraParam)
+		cmp := t.cmpFunc(item, w.data)
 		if cmp == 0 {
 			break
 		}
@@ -243,14 +502,24 @@ func (t *PAvlTree) Delete(item Item) Item {
 		}
 		w = w.links[dir]
 		if w == nil {
-			return nil
+			return
 		}
 	}
+	return t.deleteNode(w), true
+}
+
+//unlink node w from tree and rebalance
+//return item of w
+func (t *PAvlSet[T]) deleteNode(w *pnode[T]) T {
+	dir := Left
 	ret := w.data
 	p := w.parent
+	//header node stands for parent of root, its left link is root
+	head := pnode[T]{links: [ChildNum]*pnode[T]{Left: t.root}}
 	if p == nil {
-		p = (*pnode)(unsafe.Pointer(&t.root))
-		dir = Left
+		p = &head
+	} else if p.links[Right] == w {
+		dir = Right
 	}
 	if w.links[Right] == nil { //case 1, w has no right child
 		p.links[dir] = w.links[Left]
@@ -267,6 +536,7 @@ func (t *PAvlTree) Delete(item Item) Item {
 				r.links[Left].parent = r
 			}
 			r.balance = w.balance
+			r.size = w.size
 			p = r
 			dir = Right
 		} else { //case 3, w's right child has left child
@@ -288,17 +558,24 @@ func (t *PAvlTree) Delete(item Item) Item {
 				r.links[Left].parent = r
 			}
 			s.balance = w.balance
+			s.size = w.size
 			p = r
 			dir = Left
 		}
 	}
 	w = nil
-	for p != (*pnode)(unsafe.Pointer(&t.root)) {
+	if p != &head {
+		for q := p; q != nil; q = q.parent {
+			q.size--
+		}
+		t.fixUp(p)
+	}
+	for p != &head {
 		y := p
 		if y.parent != nil {
 			p = y.parent
 		} else {
-			p = (*pnode)(unsafe.Pointer(&t.root))
+			p = &head
 		}
 		if dir == Left {
 			if p.links[Left] != y {
@@ -317,6 +594,9 @@ func (t *PAvlTree) Delete(item Item) Item {
 					r.links[Right] = x
 					y.links[Right] = r.links[Left]
 					r.links[Left] = y
+					t.update(x)
+					t.update(y)
+					t.update(r)
 					if r.balance == 1 {
 						x.balance = 0
 						y.balance = -1
@@ -341,6 +621,8 @@ func (t *PAvlTree) Delete(item Item) Item {
 				} else { /*  x.balance == 0  ||  x.balance == 1 */
 					y.links[Right] = x.links[Left]
 					x.links[Left] = y
+					t.update(y)
+					t.update(x)
 					x.parent = y.parent
 					y.parent = x
 					if y.links[Right] != nil {
@@ -376,6 +658,9 @@ func (t *PAvlTree) Delete(item Item) Item {
 					r.links[Left] = x
 					y.links[Left] = r.links[Right]
 					r.links[Right] = y
+					t.update(x)
+					t.update(y)
+					t.update(r)
 					if r.balance == -1 {
 						x.balance = 0
 						y.balance = 1
@@ -400,6 +685,8 @@ func (t *PAvlTree) Delete(item Item) Item {
 				} else {
 					y.links[Left] = x.links[Right]
 					x.links[Right] = y
+					t.update(y)
+					t.update(x)
 					x.parent = y.parent
 					y.parent = x
 					if y.links[Left] != nil {
@@ -419,31 +706,496 @@ func (t *PAvlTree) Delete(item Item) Item {
 			}
 		}
 	}
+	t.root = head.links[Left]
 	t.count--
+	t.generation++
 	return ret
 }
 
-func (t *PAvlTree) Copy() *PAvlTree {
+//delete all items in [lo, hi)
+//the range is split out of tree and the rest joined back, O(log n)
+//return number of deleted items
+func (t *PAvlSet[T]) DeleteRange(lo, hi T) int {
+	if t == nil || t.cmpFunc(lo, hi) >= 0 {
+		return 0
+	}
+	i, j := t.Rank(lo), t.Rank(hi)
+	if i == j {
+		return 0
+	}
+	l, lh, r, rh := t.splitAt(t.root, t.root.height(), i)
+	_, _, r, rh = t.splitAt(r, rh, j-i)
+	root, _ := t.concat(l, lh, r, rh)
+	t.setRoot(root)
+	t.count -= j - i
+	t.generation++
+	return j - i
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied, O(log n)
+//return false and change nothing if items are out of order
+func (t *PAvlSet[T]) Join(pivot T, right *PAvlSet[T]) bool {
+	if t == nil || right == t {
+		return false
+	}
+	if n := t.root.extreme(Right); n != nil && t.cmpFunc(n.data, pivot) >= 0 {
+		return false
+	}
+	var (
+		r     *pnode[T]
+		count int
+	)
+	if right != nil {
+		r, count = right.root, right.count
+	}
+	if n := r.extreme(Left); n != nil && t.cmpFunc(pivot, n.data) >= 0 {
+		return false
+	}
+	root, _ := t.join(t.root, t.root.height(), &pnode[T]{data: pivot}, r, r.height())
+	t.setRoot(root)
+	t.count += count + 1
+	t.generation++
+	if right != nil {
+		right.clear()
+	}
+	return true
+}
+
+//split t into items less than item and items greater than item, O(log n)
+//eq is the item equal to item, found is false if there is no such item
+//t is emptied
+func (t *PAvlSet[T]) Split(item T) (lt *PAvlSet[T], eq T, found bool, gt *PAvlSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, e, r, _ := t.split(t.root, t.root.height(), item)
+	if e != nil {
+		eq, found = e.data, true
+	}
+	lt, gt = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//split t into the first k items and the rest, O(log n)
+//t is emptied
+func (t *PAvlSet[T]) SplitAt(k int) (lt, ge *PAvlSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, r, _ := t.splitAt(t.root, t.root.height(), k)
+	lt, ge = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//height of subtree rooted at n, walking down the higher side
+func (n *pnode[T]) height() int {
+	h := 0
+	for n != nil {
+		h++
+		if n.balance < 0 {
+			n = n.links[Left]
+		} else {
+			n = n.links[Right]
+		}
+	}
+	return h
+}
+
+//heights of left and right subtree of n, h is height of n
+func (n *pnode[T]) childHeights(h int) (lh, rh int) {
+	lh, rh = h-1, h-1
+	if n.balance > 0 {
+		lh--
+	} else if n.balance < 0 {
+		rh--
+	}
+	return
+}
+
+//the last node of subtree rooted at n in direction dir
+func (n *pnode[T]) extreme(dir int) *pnode[T] {
+	for n != nil && n.links[dir] != nil {
+		n = n.links[dir]
+	}
+	return n
+}
+
+//make l and r, whose heights are lh and rh, children of n
+//return height of n
+func (t *PAvlSet[T]) link(n *pnode[T], l *pnode[T], lh int, r *pnode[T], rh int) int {
+	n.links[Left] = l
+	n.links[Right] = r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	n.balance = int8(rh - lh)
+	t.update(n)
+	return max(lh, rh) + 1
+}
+
+//set root of t to n
+func (t *PAvlSet[T]) setRoot(n *pnode[T]) {
+	if n != nil {
+		n.parent = nil
+	}
+	t.root = n
+}
+
+//make an empty tree with the same order, augmentation and settings as t
+func (t *PAvlSet[T]) emptyLike() *PAvlSet[T] {
+	s := t.withRoot(nil)
+	s.iterPolicy = t.iterPolicy
+	s.codec = t.codec
+	return s
+}
+
+//make a tree with the same order as t rooted at n
+func (t *PAvlSet[T]) withRoot(n *pnode[T]) *PAvlSet[T] {
+	s := &PAvlSet[T]{cmpFunc: t.cmpFunc, fix: t.fix}
+	s.setRoot(n)
+	s.count = n.subSize()
+	return s
+}
+
+//join subtree l, node n and subtree r, whose items are in ascending order
+//heights of l and r may be of any difference
+//return root and height of the joined tree
+func (t *PAvlSet[T]) join(l *pnode[T], lh int, n *pnode[T], r *pnode[T], rh int) (*pnode[T], int) {
+	switch {
+	case lh > rh+1:
+		return t.joinRight(l, lh, n, r, rh)
+	case rh > lh+1:
+		return t.joinLeft(l, lh, n, r, rh)
+	}
+	return n, t.link(n, l, lh, r, rh)
+}
+
+//join n and r into right spine of l, l is higher than r by more than one
+func (t *PAvlSet[T]) joinRight(l *pnode[T], lh int, n *pnode[T], r *pnode[T], rh int) (*pnode[T], int) {
+	a, c := l.links[Left], l.links[Right]
+	ah, ch := l.childHeights(lh)
+	var (
+		m  *pnode[T]
+		mh int
+	)
+	if ch > rh+1 {
+		m, mh = t.joinRight(c, ch, n, r, rh)
+	} else {
+		m, mh = n, t.link(n, c, ch, r, rh)
+	}
+	if mh <= ah+1 {
+		return l, t.link(l, a, ah, m, mh)
+	}
+	//m is higher than a by two
+	ml, mr := m.links[Left], m.links[Right]
+	mlh, mrh := m.childHeights(mh)
+	if mlh <= mrh {
+		return m, t.link(m, l, t.link(l, a, ah, ml, mlh), mr, mrh)
+	}
+	x, y := ml.links[Left], ml.links[Right]
+	xh, yh := ml.childHeights(mlh)
+	return ml, t.link(ml, l, t.link(l, a, ah, x, xh), m, t.link(m, y, yh, mr, mrh))
+}
+
+//join l and n into left spine of r, r is higher than l by more than one
+func (t *PAvlSet[T]) joinLeft(l *pnode[T], lh int, n *pnode[T], r *pnode[T], rh int) (*pnode[T], int) {
+	c, b := r.links[Left], r.links[Right]
+	ch, bh := r.childHeights(rh)
+	var (
+		m  *pnode[T]
+		mh int
+	)
+	if ch > lh+1 {
+		m, mh = t.joinLeft(l, lh, n, c, ch)
+	} else {
+		m, mh = n, t.link(n, l, lh, c, ch)
+	}
+	if mh <= bh+1 {
+		return r, t.link(r, m, mh, b, bh)
+	}
+	//m is higher than b by two
+	ml, mr := m.links[Left], m.links[Right]
+	mlh, mrh := m.childHeights(mh)
+	if mrh <= mlh {
+		return m, t.link(m, ml, mlh, r, t.link(r, mr, mrh, b, bh))
+	}
+	x, y := mr.links[Left], mr.links[Right]
+	xh, yh := mr.childHeights(mrh)
+	return mr, t.link(mr, m, t.link(m, ml, mlh, x, xh), r, t.link(r, y, yh, b, bh))
+}
+
+//join subtree l and subtree r, every item in l is less than every item in r
+func (t *PAvlSet[T]) concat(l *pnode[T], lh int, r *pnode[T], rh int) (*pnode[T], int) {
+	if l == nil {
+		return r, rh
+	}
+	l, lh, n := t.splitLast(l, lh)
+	return t.join(l, lh, n, r, rh)
+}
+
+//unlink the greatest node of nonempty subtree n, whose height is h
+//return the rest subtree with its height and the greatest node
+func (t *PAvlSet[T]) splitLast(n *pnode[T], h int) (l *pnode[T], lh int, last *pnode[T]) {
+	a, b := n.links[Left], n.links[Right]
+	ah, bh := n.childHeights(h)
+	if b == nil {
+		return a, ah, n
+	}
+	l, lh, last = t.splitLast(b, bh)
+	l, lh = t.join(a, ah, n, l, lh)
+	return
+}
+
+//split subtree n, whose height is h, into subtree less than item and subtree greater than item
+//eq is the node equal to item, or nil if there is no such node
+func (t *PAvlSet[T]) split(n *pnode[T], h int, item T) (l *pnode[T], lh int, eq *pnode[T], r *pnode[T], rh int) {
+	if n == nil {
+		return
+	}
+	a, b := n.links[Left], n.links[Right]
+	ah, bh := n.childHeights(h)
+	switch c := t.cmpFunc(item, n.data); {
+	case c < 0:
+		l, lh, eq, r, rh = t.split(a, ah, item)
+		r, rh = t.join(r, rh, n, b, bh)
+	case c > 0:
+		l, lh, eq, r, rh = t.split(b, bh, item)
+		l, lh = t.join(a, ah, n, l, lh)
+	default:
+		l, lh, eq, r, rh = a, ah, n, b, bh
+	}
+	return
+}
+
+//split subtree n, whose height is h, into subtree of the first k nodes and subtree of the rest
+func (t *PAvlSet[T]) splitAt(n *pnode[T], h int, k int) (l *pnode[T], lh int, r *pnode[T], rh int) {
+	if n == nil {
+		return
+	}
+	a, b := n.links[Left], n.links[Right]
+	ah, bh := n.childHeights(h)
+	if s := a.subSize(); k <= s {
+		l, lh, r, rh = t.splitAt(a, ah, k)
+		r, rh = t.join(r, rh, n, b, bh)
+	} else {
+		l, lh, r, rh = t.splitAt(b, bh, k-s-1)
+		l, lh = t.join(a, ah, n, l, lh)
+	}
+	return
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+//O(m log(n/m + 1)) for sizes m <= n, big trees are merged in parallel,
+//so compare function and resolve must be safe for concurrent use
+func (t *PAvlSet[T]) Union(other *PAvlSet[T], resolve func(a, b T) T) {
+	if t == nil || other == nil || other == t {
+		return
+	}
+	root, _ := t.union(t.root, t.root.height(), other.root, other.root.height(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *PAvlSet[T]) Intersect(other *PAvlSet[T], resolve func(a, b T) T) {
+	if t == nil || other == t {
+		return
+	}
+	if other == nil {
+		t.clear()
+		return
+	}
+	root, _ := t.intersect(t.root, t.root.height(), other.root, other.root.height(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//delete all items of other from t, other is emptied
+func (t *PAvlSet[T]) Difference(other *PAvlSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.difference(t.root, t.root.height(), other.root, other.root.height())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *PAvlSet[T]) SymmetricDifference(other *PAvlSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.symmetricDifference(t.root, t.root.height(), other.root, other.root.height())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//drop all items of t
+func (t *PAvlSet[T]) clear() {
+	t.root = nil
+	t.count = 0
+	t.generation++
+}
+
+//union of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PAvlSet[T]) union(a *pnode[T], ah int, b *pnode[T], bh int, resolve func(a, b T) T) (*pnode[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *pnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.union(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.union(r1, rh1, r2, rh2, resolve)
+	})
+	if eq != nil && resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//intersection of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PAvlSet[T]) intersect(a *pnode[T], ah int, b *pnode[T], bh int, resolve func(a, b T) T) (*pnode[T], int) {
+	if a == nil || b == nil {
+		return nil, 0
+	}
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *pnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.intersect(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.intersect(r1, rh1, r2, rh2, resolve)
+	})
+	if eq == nil {
+		return t.concat(l, lh, r, rh)
+	}
+	if resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//subtree a without items of subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PAvlSet[T]) difference(a *pnode[T], ah int, b *pnode[T], bh int) (*pnode[T], int) {
+	if a == nil || b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l2, r2 := b.links[Left], b.links[Right]
+	lh2, rh2 := b.childHeights(bh)
+	l1, lh1, _, r1, rh1 := t.split(a, ah, b.data)
+	var (
+		l, r   *pnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.difference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.difference(r1, rh1, r2, rh2)
+	})
+	return t.concat(l, lh, r, rh)
+}
+
+//items in exactly one of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PAvlSet[T]) symmetricDifference(a *pnode[T], ah int, b *pnode[T], bh int) (*pnode[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *pnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.symmetricDifference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.symmetricDifference(r1, rh1, r2, rh2)
+	})
+	if eq != nil {
+		return t.concat(l, lh, r, rh)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+func (t *PAvlSet[T]) Copy() *PAvlSet[T] {
 	if t == nil {
 		return nil
 	}
-	n := NewPAvlTree(t.cmpFunc, t.extraParam)
+	n := NewPAvlSet(t.cmpFunc)
 	if n == nil {
 		return nil
 	}
 	n.count = t.count
+	n.iterPolicy = t.iterPolicy
+	n.codec = t.codec
+	n.fix = t.fix
 	if n.count == 0 {
 		return n
 	}
 	var (
-		x *pnode
-		y *pnode
+		x *pnode[T]
+		y *pnode[T]
 	)
-	x = (*pnode)(unsafe.Pointer(&t.root))
-	y = (*pnode)(unsafe.Pointer(&n.root))
+	//header nodes stand for parents of roots
+	var hx, hy pnode[T]
+	hx.links[Left] = t.root
+	x, y = &hx, &hy
 	for {
 		for x.links[Left] != nil {
-			y.links[Left] = &pnode{}
+			y.links[Left] = &pnode[T]{}
 			y.links[Left].parent = y
 			x = x.links[Left]
 			y = y.links[Left]
@@ -452,8 +1204,9 @@ func (t *PAvlTree) Copy() *PAvlTree {
 		for {
 			y.data = x.data
 			y.balance = x.balance
+			y.size = x.size
 			if x.links[Right] != nil {
-				y.links[Right] = &pnode{}
+				y.links[Right] = &pnode[T]{}
 				y.links[Right].parent = y
 				x = x.links[Right]
 				y = y.links[Right]
@@ -465,6 +1218,7 @@ func (t *PAvlTree) Copy() *PAvlTree {
 				w := x
 				x = x.parent
 				if x == nil {
+					n.root = hy.links[Left]
 					n.root.parent = nil
 					return n
 				}
@@ -477,72 +1231,181 @@ func (t *PAvlTree) Copy() *PAvlTree {
 	}
 }
 
-func (t *PAvlTree) Iter() Iterator {
-	it := NewPAvlIter()
+func (t *PAvlSet[T]) Iter() IteratorOf[T] {
+	it := NewPAvlSetIter[T]()
 	return it.HookWith(t)
 }
 
-type PAvlIter struct {
-	tree *PAvlTree //the tree be iterated
-	node *pnode    //current node in tree
+//set policy of iterators hooked with tree from now on, see IterPolicy
+func (t *PAvlSet[T]) SetIterPolicy(p IterPolicy) {
+	if t == nil {
+		return
+	}
+	t.iterPolicy = p
+}
+
+//set codec of items used by MarshalBinary and UnmarshalBinary
+func (t *PAvlSet[T]) SetCodec(c Codec[T]) {
+	if t == nil {
+		return
+	}
+	t.codec = c
+}
+
+//encode items of t in ascending order along with the shape of tree,
+//implements encoding.BinaryMarshaler
+func (t *PAvlSet[T]) MarshalBinary() ([]byte, error) {
+	if t == nil {
+		return nil, ErrNoCodec
+	}
+	return marshalTree(encPAvl, t.root, t.count, t.codec)
+}
+
+//replace items of t with the tree encoded by MarshalBinary of a PAvlSet,
+//the same tree is rebuilt in O(n) without comparisons,
+//so compare function of t must order items as the encoding tree did
+//implements encoding.BinaryUnmarshaler
+func (t *PAvlSet[T]) UnmarshalBinary(data []byte) error {
+	if t == nil {
+		return ErrNoCodec
+	}
+	root, count, err := unmarshalTree(encPAvl, data, t.codec, func(c byte, l *pnode[T], item T, r *pnode[T]) *pnode[T] {
+		n := &pnode[T]{links: [ChildNum]*pnode[T]{l, r}, data: item, balance: int8(c) - 1}
+		if l != nil {
+			l.parent = n
+		}
+		if r != nil {
+			r.parent = n
+		}
+		t.update(n)
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	t.root = root
+	t.count = count
+	t.generation++
+	return nil
+}
+
+func (t *PAvlSet[T]) seekIter() seekIterOf[T] {
+	return NewPAvlSetIter[T]().HookWith(t)
+}
+
+//sequence of all items in ascending order
+func (t *PAvlSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAll(t.seekIter)
+}
+
+//sequence of all items in descending order
+func (t *PAvlSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBackward(t.seekIter)
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PAvlSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAscend(t.seekIter, from)
 }
 
-func NewPAvlIter() *PAvlIter {
-	return &PAvlIter{}
+//sequence of items less than or equal to from in descending order
+func (t *PAvlSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqDescend(t.seekIter, from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PAvlSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBetween(t.seekIter, t.cmpFunc, lo, hi)
 }
 
-func (it *PAvlIter) HookWith(tree *PAvlTree) *PAvlIter {
+type PAvlSetIter[T any] struct {
+	tree       *PAvlSet[T] //the tree be iterated
+	node       *pnode[T]   //current node in tree
+	generation int         // generation number
+	policy     IterPolicy  //how to react to changes of tree
+}
+
+func NewPAvlSetIter[T any]() *PAvlSetIter[T] {
+	return &PAvlSetIter[T]{}
+}
+
+func (it *PAvlSetIter[T]) HookWith(tree *PAvlSet[T]) *PAvlSetIter[T] {
 	if it == nil {
 		return nil
 	}
+	it.policy = tree.iterPolicy
+	if it.policy == IterSnapshot {
+		tree = tree.Copy()
+	}
 	it.tree = tree
 	it.node = nil
+	it.generation = tree.generation
 	return it
 }
 
-func (it *PAvlIter) First() Item {
+func (it *PAvlSetIter[T]) First() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Left] != nil {
 		w = w.links[Left]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *PAvlIter) Last() Item {
+func (it *PAvlSetIter[T]) Last() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Right] != nil {
 		w = w.links[Right]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *PAvlIter) Find(item Item) Item {
-	if it == nil || it.tree == nil || item == nil {
-		return nil
+func (it *PAvlSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
 	}
+	it.generation = it.tree.generation
 	var (
-		w *pnode //walk node
-		n *pnode //child of w
+		w *pnode[T] //walk node
+		n *pnode[T] //child of w
 	)
 	for w = it.tree.root; w != nil; w = n {
-		cmp := it.tree.cmpFunc(item, w.data, it.tree.extraParam)
+		cmp := it.tree.cmpFunc(item, w.data)
 		if cmp == 0 {
 			it.node = w
-			return w.data
+			return w.data, true
 		}
 		if cmp < 0 {
 			n = w.links[Left]
@@ -551,12 +1414,80 @@ func (it *PAvlIter) Find(item Item) Item {
 		}
 	}
 	it.node = nil
-	return nil
+	return
 }
 
-func (it *PAvlIter) Next() Item {
+//move iterator to item at index k in sorted order
+//if k is out of range, iterator is moved to nil position
+func (it *PAvlSetIter[T]) Seek(k int) (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
+	}
+	it.generation = it.tree.generation
+	it.node = nil
+	if k < 0 || k >= it.tree.count {
+		return
+	}
+	w := it.tree.root
+	for {
+		ls := w.links[Left].subSize()
+		if k < ls {
+			w = w.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			w = w.links[Right]
+		} else {
+			break
+		}
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move iterator to the nearest item to item in direction dir, see near
+func (it *PAvlSetIter[T]) seekNear(item T, dir int, eq bool) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	it.generation = it.tree.generation
+	it.node = it.tree.near(item, dir, eq)
+	if it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//move iterator to the least item greater than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PAvlSetIter[T]) SeekGE(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, true)
+}
+
+//move iterator to the least item greater than item
+//if there is no such item, iterator is moved to nil position
+func (it *PAvlSetIter[T]) SeekGT(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, false)
+}
+
+//move iterator to the greatest item less than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PAvlSetIter[T]) SeekLE(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, true)
+}
+
+//move iterator to the greatest item less than item
+//if there is no such item, iterator is moved to nil position
+func (it *PAvlSetIter[T]) SeekLT(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, false)
+}
+
+func (it *PAvlSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Right, false)
 	}
 	w := it.node
 	if w == nil {
@@ -570,21 +1501,25 @@ func (it *PAvlIter) Next() Item {
 		for p := w.parent; ; w, p = p, p.parent {
 			if p == nil {
 				it.node = p
-				return nil
+				return
 			}
 			if w == p.links[Left] {
 				it.node = p
-				return p.data
+				return p.data, true
 			}
 		}
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *PAvlIter) Prev() Item {
+func (it *PAvlSetIter[T]) Prev() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
+	}
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Left, false)
 	}
 	w := it.node
 	if w == nil {
@@ -598,52 +1533,134 @@ func (it *PAvlIter) Prev() Item {
 		for p := w.parent; ; w, p = p, p.parent {
 			if p == nil {
 				it.node = p
-				return nil
+				return
 			}
 			if w == p.links[Right] {
 				it.node = p
-				return p.data
+				return p.data, true
 			}
 		}
 	}
 	it.node = w
-	return w.data
+	return w.data, true
+}
+
+//locate current item again after tree is changed
+//return false if current item is deleted, iterator is left alone then
+func (it *PAvlSetIter[T]) refresh() bool {
+	n := it.tree.near(it.node.data, Right, true)
+	if n == nil || it.tree.cmpFunc(n.data, it.node.data) != 0 {
+		return false
+	}
+	it.node = n
+	it.generation = it.tree.generation
+	return true
 }
 
-func (it *PAvlIter) Current() Item {
+//report whether tree is unchanged since iterator was positioned,
+//panic with ErrConcurrentModification if not and policy is IterFailFast
+func (it *PAvlSetIter[T]) synced() bool {
+	if it.generation == it.tree.generation {
+		return true
+	}
+	if it.policy == IterFailFast {
+		panic(ErrConcurrentModification)
+	}
+	return false
+}
+
+func (it *PAvlSetIter[T]) Current() (item T, ok bool) {
 	if it == nil || it.node == nil {
-		return nil
+		return
+	}
+	return it.node.data, true
+}
+
+//return index of current item in sorted order
+//return -1 if iterator is at nil position
+func (it *PAvlSetIter[T]) Index() int {
+	if it == nil || it.tree == nil || it.node == nil {
+		return -1
+	}
+	if !it.synced() && !it.refresh() {
+		return -1
+	}
+	idx := it.node.links[Left].subSize()
+	for n, p := it.node, it.node.parent; p != nil; n, p = p, p.parent {
+		if p.links[Right] == n {
+			idx += p.links[Left].subSize() + 1
+		}
 	}
-	return it.node.data
+	return idx
 }
 
 //don't change key part of item
-func (it *PAvlIter) Replace(new Item) Item {
-	if it == nil || it.node == nil || new == nil {
-		return nil
+func (it *PAvlSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
 	}
 	old := it.node.data
 	it.node.data = new
-	return old
+	it.tree.fixUp(it.node)
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *PAvlSetIter[T]) Delete() (item T, ok bool) {
+	return it.delete(Right)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *PAvlSetIter[T]) DeletePrev() (item T, ok bool) {
+	return it.delete(Left)
 }
 
-func (it *PAvlIter) CopyFrom(other *PAvlIter) Item {
+//delete current item and move to its neighbour in direction dir,
+//the tree is changed by the iterator itself, so it stays valid
+func (it *PAvlSetIter[T]) delete(dir int) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
+	}
+	w := it.node
+	if dir == Right {
+		item, ok = it.Next()
+	} else {
+		item, ok = it.Prev()
+	}
+	//nodes are relinked but never copied, so the neighbour stays in tree
+	it.tree.deleteNode(w)
+	it.generation = it.tree.generation
+	return
+}
+
+func (it *PAvlSetIter[T]) CopyFrom(other *PAvlSetIter[T]) (item T, ok bool) {
 	if it == nil || other == nil {
-		return nil
+		return
 	}
 	it.tree = other.tree
 	it.node = other.node
+	it.generation = other.generation
+	it.policy = other.policy
 	if it.node == nil {
-		return nil
+		return
 	}
-	return it.node.data
+	return it.node.data, true
 }
 
-func (it *PAvlIter) Insert(item Item) (*Item, bool) {
-	if it == nil || it.tree == nil || item == nil {
+func (it *PAvlSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
 		return nil, false
 	}
 	addr, ok := it.tree.insert(item)
-	it.node = (*pnode)(unsafe.Pointer(uintptr(unsafe.Pointer(addr)) - unsafe.Offsetof(it.node.data)))
+	it.node = (*pnode[T])(unsafe.Pointer(uintptr(unsafe.Pointer(addr)) - unsafe.Offsetof(it.node.data)))
+	it.generation = it.tree.generation
 	return addr, ok
 }
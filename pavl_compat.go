@@ -0,0 +1,459 @@
+package bbst
+
+import (
+	"iter"
+	"slices"
+)
+
+//PAvlTree is the interface{} flavour of PAvlSet,
+//kept as a thin layer over PAvlSet[Item] for existing callers
+type PAvlTree struct {
+	PAvlSet[Item]
+}
+
+func NewPAvlTree(cmp Compare, extra interface{}) *PAvlTree {
+	if cmp == nil {
+		return nil
+	}
+	return &PAvlTree{PAvlSet[Item]{cmpFunc: compareOf(cmp, extra)}}
+}
+
+//create a tree holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n)
+//return ErrNilItem if any item is nil,
+//ErrNotSorted if items are out of order or have duplicates
+func NewPAvlTreeFromSorted(cmp Compare, extra interface{}, items []Item) (*PAvlTree, error) {
+	t := NewPAvlTree(cmp, extra)
+	if t == nil {
+		return nil, nil
+	}
+	if err := checkNil(items); err != nil {
+		return nil, err
+	}
+	if err := t.PAvlSet.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *PAvlTree) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.PAvlSet.Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *PAvlTree) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := t.PAvlSet.Find(target)
+	return item
+}
+
+//return item at index k in sorted order, k counts from 0
+//return nil if k is out of range
+func (t *PAvlTree) Select(k int) Item {
+	if t == nil {
+		return nil
+	}
+	item, _ := t.PAvlSet.Select(k)
+	return item
+}
+
+//return number of items in tree less than item
+func (t *PAvlTree) Rank(item Item) int {
+	if t == nil || item == nil {
+		return 0
+	}
+	return t.PAvlSet.Rank(item)
+}
+
+//return the greatest item less than or equal to item
+//return nil if there is no such item
+func (t *PAvlTree) Floor(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.PAvlSet.Floor(item)
+	return found
+}
+
+//return the least item greater than or equal to item
+//return nil if there is no such item
+func (t *PAvlTree) Ceiling(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.PAvlSet.Ceiling(item)
+	return found
+}
+
+//return iterator at the first item not less than item
+func (t *PAvlTree) LowerBound(item Item) Iterator {
+	it := NewPAvlIter()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *PAvlTree) UpperBound(item Item) Iterator {
+	it := NewPAvlIter()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+func (t *PAvlTree) insert(item Item) (*Item, bool) {
+	if t == nil || item == nil {
+		return nil, false
+	}
+	return t.PAvlSet.insert(item)
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *PAvlTree) Insert(item Item) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//insert all items in tree, nil items and items equal to one in tree
+//or to an earlier one in items are skipped
+//return number of inserted items and skipped non nil items in ascending order
+func (t *PAvlTree) InsertBatch(items []Item) (inserted int, dups []Item) {
+	if t == nil {
+		return
+	}
+	return t.PAvlSet.InsertBatch(slices.DeleteFunc(slices.Clone(items), func(item Item) bool {
+		return item == nil
+	}))
+}
+
+//replace item in tree with same key item
+//return old item
+func (t *PAvlTree) Replace(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	old, _ := t.PAvlSet.Replace(item)
+	return old
+}
+
+//delete item in tree
+//return item if find it
+//else  return nil
+func (t *PAvlTree) Delete(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	deleted, _ := t.PAvlSet.Delete(item)
+	return deleted
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *PAvlTree) Range(lo, hi Item, fn func(item Item) bool) {
+	if t == nil || lo == nil || hi == nil {
+		return
+	}
+	t.PAvlSet.Range(lo, hi, fn)
+}
+
+//delete all items in [lo, hi)
+//return number of deleted items
+func (t *PAvlTree) DeleteRange(lo, hi Item) int {
+	if t == nil || lo == nil || hi == nil {
+		return 0
+	}
+	return t.PAvlSet.DeleteRange(lo, hi)
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied
+//return false and change nothing if items are out of order
+func (t *PAvlTree) Join(pivot Item, right *PAvlTree) bool {
+	if t == nil || pivot == nil {
+		return false
+	}
+	if right == nil {
+		return t.PAvlSet.Join(pivot, nil)
+	}
+	return t.PAvlSet.Join(pivot, &right.PAvlSet)
+}
+
+//split t into items less than item and items greater than item
+//eq is the item equal to item, or nil if there is no such item
+//t is emptied
+func (t *PAvlTree) Split(item Item) (lt *PAvlTree, eq Item, gt *PAvlTree) {
+	if t == nil || item == nil {
+		return
+	}
+	l, eq, _, r := t.PAvlSet.Split(item)
+	return &PAvlTree{*l}, eq, &PAvlTree{*r}
+}
+
+//split t into the first k items and the rest
+//t is emptied
+func (t *PAvlTree) SplitAt(k int) (lt, ge *PAvlTree) {
+	if t == nil {
+		return
+	}
+	l, r := t.PAvlSet.SplitAt(k)
+	return &PAvlTree{*l}, &PAvlTree{*r}
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *PAvlTree) Union(other *PAvlTree, resolve func(a, b Item) Item) {
+	if t == nil || other == nil {
+		return
+	}
+	t.PAvlSet.Union(&other.PAvlSet, resolve)
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *PAvlTree) Intersect(other *PAvlTree, resolve func(a, b Item) Item) {
+	if t == nil {
+		return
+	}
+	if other == nil {
+		t.PAvlSet.Intersect(nil, resolve)
+		return
+	}
+	t.PAvlSet.Intersect(&other.PAvlSet, resolve)
+}
+
+//delete all items of other from t, other is emptied
+func (t *PAvlTree) Difference(other *PAvlTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.PAvlSet.Difference(&other.PAvlSet)
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *PAvlTree) SymmetricDifference(other *PAvlTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.PAvlSet.SymmetricDifference(&other.PAvlSet)
+}
+
+func (t *PAvlTree) Copy() *PAvlTree {
+	if t == nil {
+		return nil
+	}
+	return &PAvlTree{*t.PAvlSet.Copy()}
+}
+
+//sequence of all items in ascending order
+func (t *PAvlTree) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PAvlSet.All()
+}
+
+//sequence of all items in descending order
+func (t *PAvlTree) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PAvlSet.Backward()
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PAvlTree) Ascend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PAvlSet.Ascend(from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PAvlTree) Descend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PAvlSet.Descend(from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PAvlTree) Between(lo, hi Item) iter.Seq[Item] {
+	if t == nil || lo == nil || hi == nil {
+		return empty[Item]
+	}
+	return t.PAvlSet.Between(lo, hi)
+}
+
+func (t *PAvlTree) Iter() Iterator {
+	it := NewPAvlIter()
+	return it.HookWith(t)
+}
+
+type PAvlIter struct {
+	PAvlSetIter[Item]
+}
+
+func NewPAvlIter() *PAvlIter {
+	return &PAvlIter{}
+}
+
+func (it *PAvlIter) HookWith(tree *PAvlTree) *PAvlIter {
+	if it == nil {
+		return nil
+	}
+	it.PAvlSetIter.HookWith(&tree.PAvlSet)
+	return it
+}
+
+func (it *PAvlIter) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.First()
+	return item
+}
+
+func (it *PAvlIter) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.Last()
+	return item
+}
+
+func (it *PAvlIter) Find(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PAvlSetIter.Find(item)
+	return found
+}
+
+//move iterator to item at index k in sorted order
+func (it *PAvlIter) Seek(k int) Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.Seek(k)
+	return item
+}
+
+//return index of current item, -1 at nil position
+func (it *PAvlIter) Index() int {
+	if it == nil {
+		return -1
+	}
+	return it.PAvlSetIter.Index()
+}
+
+//move iterator to the least item greater than or equal to item
+func (it *PAvlIter) SeekGE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PAvlSetIter.SeekGE(item)
+	return found
+}
+
+//move iterator to the least item greater than item
+func (it *PAvlIter) SeekGT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PAvlSetIter.SeekGT(item)
+	return found
+}
+
+//move iterator to the greatest item less than or equal to item
+func (it *PAvlIter) SeekLE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PAvlSetIter.SeekLE(item)
+	return found
+}
+
+//move iterator to the greatest item less than item
+func (it *PAvlIter) SeekLT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PAvlSetIter.SeekLT(item)
+	return found
+}
+
+func (it *PAvlIter) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.Next()
+	return item
+}
+
+func (it *PAvlIter) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.Prev()
+	return item
+}
+
+func (it *PAvlIter) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.Current()
+	return item
+}
+
+//don't change key part of item
+func (it *PAvlIter) Replace(new Item) Item {
+	if it == nil || new == nil {
+		return nil
+	}
+	old, _ := it.PAvlSetIter.Replace(new)
+	return old
+}
+
+//delete current item and move to its successor, return the successor
+func (it *PAvlIter) Delete() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.Delete()
+	return item
+}
+
+//delete current item and move to its predecessor, return the predecessor
+func (it *PAvlIter) DeletePrev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.DeletePrev()
+	return item
+}
+
+func (it *PAvlIter) CopyFrom(other *PAvlIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.PAvlSetIter.CopyFrom(&other.PAvlSetIter)
+	return item
+}
+
+func (it *PAvlIter) Insert(item Item) (*Item, bool) {
+	if it == nil || item == nil {
+		return nil, false
+	}
+	return it.PAvlSetIter.Insert(item)
+}
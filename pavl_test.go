@@ -6,7 +6,7 @@ import (
 	"testing"
 )
 
-func (n *pnode) print(lvl int) {
+func (n *pnode[T]) print(lvl int) {
 	if n == nil {
 		return
 	}
@@ -83,17 +83,17 @@ func (it *PAvlIter) check(t *testing.T, i, n int, title string) bool {
 	return ok
 }
 
-func comparePAvlTrees(t *testing.T, a, b *pnode) bool {
+func comparePAvlTrees(t *testing.T, a, b *pnode[Item]) bool {
 	if a == nil && b == nil {
 		return true
 	}
-	pf := func(n *pnode) Item {
+	pf := func(n *pnode[Item]) Item {
 		if n.parent != nil {
 			return n.parent.data
 		}
 		return -1
 	}
-	cf := func(n *pnode, dir int) string {
+	cf := func(n *pnode[Item], dir int) string {
 		if n.links[dir] != nil {
 			return "has"
 		}
@@ -104,7 +104,7 @@ func comparePAvlTrees(t *testing.T, a, b *pnode) bool {
 		((a.links[Right] != nil) != (b.links[Right] != nil)) ||
 		((a.parent != nil) != (b.parent != nil)) ||
 		(a.parent != nil && b.parent != nil && a.parent.data != b.parent.data) ||
-		a.balance != b.balance {
+		a.balance != b.balance || a.size != b.size {
 
 		t.Logf("Copied nodes differ:\n"+
 			"a=%d, bal %d, parent %d, %s left child, %s right child\n"+
@@ -123,7 +123,7 @@ func comparePAvlTrees(t *testing.T, a, b *pnode) bool {
 	return ok
 }
 
-func recurseVerifyPTree(t *testing.T, node *pnode, ok *bool, count *int, min, max int, height *int) {
+func recurseVerifyPTree(t *testing.T, node *pnode[Item], ok *bool, count *int, min, max int, height *int) {
 	var (
 		d         int
 		subcount  [ChildNum]int
@@ -147,6 +147,10 @@ func recurseVerifyPTree(t *testing.T, node *pnode, ok *bool, count *int, min, ma
 	recurseVerifyPTree(t, node.links[Right], ok, &subcount[Right], d+1, max, &subheight[Right])
 
 	*count = 1 + subcount[Left] + subcount[Right]
+	if node.size != *count {
+		t.Errorf("Node %d has size %d, but subtree has %d nodes.\n", d, node.size, *count)
+		*ok = false
+	}
 	maxHeight := 0
 	if subheight[Left] > subheight[Right] {
 		maxHeight = subheight[Left]
@@ -0,0 +1,477 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+//node of persistent avl tree, never changed once it is linked into a tree
+type pstnode[T any] struct {
+	links  [ChildNum]*pstnode[T] //child node
+	data   T                     //data item
+	height int8                  //height of subtree
+	size   int                   //number of node in subtree
+}
+
+func (n *pstnode[T]) subHeight() int8 {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *pstnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+//make a new node with children l and r
+func newPstNode[T any](l *pstnode[T], data T, r *pstnode[T]) *pstnode[T] {
+	return &pstnode[T]{
+		links:  [ChildNum]*pstnode[T]{l, r},
+		data:   data,
+		height: max(l.subHeight(), r.subHeight()) + 1,
+		size:   l.subSize() + r.subSize() + 1,
+	}
+}
+
+//make a new balanced subtree of l, data and r,
+//heights of l and r differ at most by two
+func balancePstNode[T any](l *pstnode[T], data T, r *pstnode[T]) *pstnode[T] {
+	lh, rh := l.subHeight(), r.subHeight()
+	if lh > rh+1 {
+		ll, lr := l.links[Left], l.links[Right]
+		if ll.subHeight() >= lr.subHeight() {
+			return newPstNode(ll, l.data, newPstNode(lr, data, r))
+		}
+		return newPstNode(newPstNode(ll, l.data, lr.links[Left]), lr.data, newPstNode(lr.links[Right], data, r))
+	}
+	if rh > lh+1 {
+		rl, rr := r.links[Left], r.links[Right]
+		if rr.subHeight() >= rl.subHeight() {
+			return newPstNode(newPstNode(l, data, rl), r.data, rr)
+		}
+		return newPstNode(newPstNode(l, data, rl.links[Left]), rl.data, newPstNode(rl.links[Right], r.data, rr))
+	}
+	return newPstNode(l, data, r)
+}
+
+//PersistentAvlSet is an immutable avl tree,
+//Insert, Replace and Delete leave the tree alone and return a new version,
+//which shares all nodes off the changed path with the old one,
+//so every version stays valid and a snapshot is just a pointer
+type PersistentAvlSet[T any] struct {
+	root    *pstnode[T]      //root of  tree
+	cmpFunc func(a, b T) int //compare function
+}
+
+func NewPersistentAvlSet[T any](cmp func(a, b T) int) *PersistentAvlSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &PersistentAvlSet[T]{
+		cmpFunc: cmp,
+	}
+}
+
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedPersistentAvlSet[T cmp.Ordered]() *PersistentAvlSet[T] {
+	return NewPersistentAvlSet(cmp.Compare[T])
+}
+
+//return a version of tree rooted at root
+func (t *PersistentAvlSet[T]) with(root *pstnode[T]) *PersistentAvlSet[T] {
+	return &PersistentAvlSet[T]{root: root, cmpFunc: t.cmpFunc}
+}
+
+func (t *PersistentAvlSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.root.subSize()
+}
+
+//search target in tree
+//if find it return item
+//else ok is false
+func (t *PersistentAvlSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	for p := t.root; p != nil; {
+		cmp := t.cmpFunc(target, p.data)
+		if cmp < 0 {
+			p = p.links[Left]
+		} else if cmp > 0 {
+			p = p.links[Right]
+		} else {
+			return p.data, true
+		}
+	}
+	return
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *PersistentAvlSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil || k < 0 || k >= t.Count() {
+		return
+	}
+	p := t.root
+	for {
+		ls := p.links[Left].subSize()
+		if k < ls {
+			p = p.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			p = p.links[Right]
+		} else {
+			return p.data, true
+		}
+	}
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *PersistentAvlSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
+	}
+	rank := 0
+	for p := t.root; p != nil; {
+		if t.cmpFunc(item, p.data) > 0 {
+			rank += p.links[Left].subSize() + 1
+			p = p.links[Right]
+		} else {
+			p = p.links[Left]
+		}
+	}
+	return rank
+}
+
+//insert item in tree
+//return the new version and true if item was successfully inserted
+//return t itself and false if item already in tree
+func (t *PersistentAvlSet[T]) Insert(item T) (*PersistentAvlSet[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	root, ok := t.insert(t.root, item)
+	if !ok {
+		return t, false
+	}
+	return t.with(root), true
+}
+
+func (t *PersistentAvlSet[T]) insert(n *pstnode[T], item T) (*pstnode[T], bool) {
+	if n == nil {
+		return newPstNode(nil, item, nil), true
+	}
+	cmp := t.cmpFunc(item, n.data)
+	if cmp == 0 {
+		return n, false
+	}
+	if cmp < 0 {
+		l, ok := t.insert(n.links[Left], item)
+		if !ok {
+			return n, false
+		}
+		return balancePstNode(l, n.data, n.links[Right]), true
+	}
+	r, ok := t.insert(n.links[Right], item)
+	if !ok {
+		return n, false
+	}
+	return balancePstNode(n.links[Left], n.data, r), true
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+//return the new version and the replaced item
+func (t *PersistentAvlSet[T]) Replace(item T) (_ *PersistentAvlSet[T], old T, ok bool) {
+	if t == nil {
+		return
+	}
+	root, old, ok := t.replace(t.root, item)
+	return t.with(root), old, ok
+}
+
+func (t *PersistentAvlSet[T]) replace(n *pstnode[T], item T) (_ *pstnode[T], old T, ok bool) {
+	if n == nil {
+		return newPstNode(nil, item, nil), old, false
+	}
+	cmp := t.cmpFunc(item, n.data)
+	if cmp == 0 {
+		return newPstNode(n.links[Left], item, n.links[Right]), n.data, true
+	}
+	var c *pstnode[T]
+	if cmp < 0 {
+		c, old, ok = t.replace(n.links[Left], item)
+		return balancePstNode(c, n.data, n.links[Right]), old, ok
+	}
+	c, old, ok = t.replace(n.links[Right], item)
+	return balancePstNode(n.links[Left], n.data, c), old, ok
+}
+
+//delete item in tree
+//return the new version and item if find it
+//else return t itself and ok is false
+func (t *PersistentAvlSet[T]) Delete(item T) (_ *PersistentAvlSet[T], deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	root, deleted, ok := t.delete(t.root, item)
+	if !ok {
+		return t, deleted, false
+	}
+	return t.with(root), deleted, true
+}
+
+func (t *PersistentAvlSet[T]) delete(n *pstnode[T], item T) (_ *pstnode[T], deleted T, ok bool) {
+	if n == nil {
+		return
+	}
+	cmp := t.cmpFunc(item, n.data)
+	var c *pstnode[T]
+	if cmp < 0 {
+		if c, deleted, ok = t.delete(n.links[Left], item); !ok {
+			return n, deleted, false
+		}
+		return balancePstNode(c, n.data, n.links[Right]), deleted, true
+	}
+	if cmp > 0 {
+		if c, deleted, ok = t.delete(n.links[Right], item); !ok {
+			return n, deleted, false
+		}
+		return balancePstNode(n.links[Left], n.data, c), deleted, true
+	}
+	if n.links[Left] == nil {
+		return n.links[Right], n.data, true
+	}
+	if n.links[Right] == nil {
+		return n.links[Left], n.data, true
+	}
+	r, min := deletePstMin(n.links[Right])
+	return balancePstNode(n.links[Left], min, r), n.data, true
+}
+
+//delete the least node of nonempty subtree n
+//return the new subtree and the deleted item
+func deletePstMin[T any](n *pstnode[T]) (*pstnode[T], T) {
+	if n.links[Left] == nil {
+		return n.links[Right], n.data
+	}
+	l, min := deletePstMin(n.links[Left])
+	return balancePstNode(l, n.data, n.links[Right]), min
+}
+
+func (t *PersistentAvlSet[T]) Iter() IteratorOf[T] {
+	return NewPersistentAvlSetIter[T]().HookWith(t)
+}
+
+func (t *PersistentAvlSet[T]) seekIter() seekIterOf[T] {
+	return NewPersistentAvlSetIter[T]().HookWith(t)
+}
+
+//sequence of all items in ascending order
+func (t *PersistentAvlSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAll(t.seekIter)
+}
+
+//sequence of all items in descending order
+func (t *PersistentAvlSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBackward(t.seekIter)
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PersistentAvlSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAscend(t.seekIter, from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PersistentAvlSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqDescend(t.seekIter, from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PersistentAvlSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBetween(t.seekIter, t.cmpFunc, lo, hi)
+}
+
+//iterator of one version of PersistentAvlSet,
+//versions never change, so the iterator is never invalidated
+type PersistentAvlSetIter[T any] struct {
+	tree   *PersistentAvlSet[T]      //the tree be iterated
+	node   *pstnode[T]               //current node in tree
+	stack  [avlMaxHeight]*pstnode[T] //all node above current node
+	height int                       //current depth of stack
+}
+
+func NewPersistentAvlSetIter[T any]() *PersistentAvlSetIter[T] {
+	return &PersistentAvlSetIter[T]{}
+}
+
+func (it *PersistentAvlSetIter[T]) HookWith(tree *PersistentAvlSet[T]) *PersistentAvlSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	it.height = 0
+	return it
+}
+
+//move to the outmost node in direction dir
+func (it *PersistentAvlSetIter[T]) edge(dir int) (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	it.height = 0
+	w := it.tree.root
+	if w == nil {
+		it.node = nil
+		return
+	}
+	for w.links[dir] != nil {
+		it.stack[it.height] = w
+		it.height++
+		w = w.links[dir]
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move to the neighbour node in direction dir
+func (it *PersistentAvlSetIter[T]) step(dir int) (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	w := it.node
+	if w == nil {
+		return it.edge(1 - dir)
+	} else if w.links[dir] != nil {
+		it.stack[it.height] = w
+		it.height++
+		w = w.links[dir]
+		for w.links[1-dir] != nil {
+			it.stack[it.height] = w
+			it.height++
+			w = w.links[1-dir]
+		}
+	} else {
+		for {
+			if it.height == 0 {
+				it.node = nil
+				return
+			}
+			n := w
+			it.height--
+			w = it.stack[it.height]
+			if w.links[dir] != n {
+				break
+			}
+		}
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move iterator to the nearest item to item in direction dir,
+//eq tells whether item itself may be taken
+func (it *PersistentAvlSetIter[T]) seekNear(item T, dir int, eq bool) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	var (
+		c  *pstnode[T] //candidate node
+		ch int         //stack height of candidate node
+	)
+	it.height = 0
+	for w := it.tree.root; w != nil; {
+		cmp := it.tree.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			c, ch = w, it.height
+			break
+		}
+		it.stack[it.height] = w
+		it.height++
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c, ch = w, it.height-1
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	it.node = c
+	if c == nil {
+		it.height = 0
+		return
+	}
+	it.height = ch
+	return c.data, true
+}
+
+//move iterator to the least item greater than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentAvlSetIter[T]) SeekGE(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, true)
+}
+
+//move iterator to the least item greater than item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentAvlSetIter[T]) SeekGT(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, false)
+}
+
+//move iterator to the greatest item less than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentAvlSetIter[T]) SeekLE(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, true)
+}
+
+//move iterator to the greatest item less than item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentAvlSetIter[T]) SeekLT(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, false)
+}
+
+func (it *PersistentAvlSetIter[T]) First() (item T, ok bool) {
+	return it.edge(Left)
+}
+
+func (it *PersistentAvlSetIter[T]) Last() (item T, ok bool) {
+	return it.edge(Right)
+}
+
+func (it *PersistentAvlSetIter[T]) Next() (item T, ok bool) {
+	return it.step(Right)
+}
+
+func (it *PersistentAvlSetIter[T]) Prev() (item T, ok bool) {
+	return it.step(Left)
+}
+
+func (it *PersistentAvlSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
@@ -0,0 +1,196 @@
+package bbst
+
+import (
+	"iter"
+)
+
+//PersistentAvlTree is the interface{} flavour of PersistentAvlSet
+type PersistentAvlTree struct {
+	PersistentAvlSet[Item]
+}
+
+func NewPersistentAvlTree(cmp Compare, extra interface{}) *PersistentAvlTree {
+	if cmp == nil {
+		return nil
+	}
+	return &PersistentAvlTree{PersistentAvlSet[Item]{cmpFunc: compareOf(cmp, extra)}}
+}
+
+func (t *PersistentAvlTree) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.PersistentAvlSet.Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *PersistentAvlTree) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := t.PersistentAvlSet.Find(target)
+	return item
+}
+
+//return item at index k in sorted order, k counts from 0
+//return nil if k is out of range
+func (t *PersistentAvlTree) Select(k int) Item {
+	if t == nil {
+		return nil
+	}
+	item, _ := t.PersistentAvlSet.Select(k)
+	return item
+}
+
+//return number of items in tree less than item
+func (t *PersistentAvlTree) Rank(item Item) int {
+	if t == nil || item == nil {
+		return 0
+	}
+	return t.PersistentAvlSet.Rank(item)
+}
+
+//insert item in tree
+//return the new version and true if item was successfully inserted
+//return t itself and false if item already in tree
+func (t *PersistentAvlTree) Insert(item Item) (*PersistentAvlTree, bool) {
+	if t == nil || item == nil {
+		return t, false
+	}
+	n, ok := t.PersistentAvlSet.Insert(item)
+	if !ok {
+		return t, false
+	}
+	return &PersistentAvlTree{*n}, true
+}
+
+//replace item in tree with same key item
+//return the new version and old item
+func (t *PersistentAvlTree) Replace(item Item) (*PersistentAvlTree, Item) {
+	if t == nil || item == nil {
+		return t, nil
+	}
+	n, old, _ := t.PersistentAvlSet.Replace(item)
+	return &PersistentAvlTree{*n}, old
+}
+
+//delete item in tree
+//return the new version and item if find it
+//else return t itself and nil
+func (t *PersistentAvlTree) Delete(item Item) (*PersistentAvlTree, Item) {
+	if t == nil || item == nil {
+		return t, nil
+	}
+	n, deleted, ok := t.PersistentAvlSet.Delete(item)
+	if !ok {
+		return t, nil
+	}
+	return &PersistentAvlTree{*n}, deleted
+}
+
+//sequence of all items in ascending order
+func (t *PersistentAvlTree) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PersistentAvlSet.All()
+}
+
+//sequence of all items in descending order
+func (t *PersistentAvlTree) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PersistentAvlSet.Backward()
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PersistentAvlTree) Ascend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PersistentAvlSet.Ascend(from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PersistentAvlTree) Descend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PersistentAvlSet.Descend(from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PersistentAvlTree) Between(lo, hi Item) iter.Seq[Item] {
+	if t == nil || lo == nil || hi == nil {
+		return empty[Item]
+	}
+	return t.PersistentAvlSet.Between(lo, hi)
+}
+
+func (t *PersistentAvlTree) Iter() Iterator {
+	it := NewPersistentAvlIter()
+	return it.HookWith(t)
+}
+
+type PersistentAvlIter struct {
+	PersistentAvlSetIter[Item]
+}
+
+func NewPersistentAvlIter() *PersistentAvlIter {
+	return &PersistentAvlIter{}
+}
+
+func (it *PersistentAvlIter) HookWith(tree *PersistentAvlTree) *PersistentAvlIter {
+	if it == nil {
+		return nil
+	}
+	if tree == nil {
+		it.PersistentAvlSetIter.HookWith(nil)
+	} else {
+		it.PersistentAvlSetIter.HookWith(&tree.PersistentAvlSet)
+	}
+	return it
+}
+
+func (it *PersistentAvlIter) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentAvlSetIter.First()
+	return item
+}
+
+func (it *PersistentAvlIter) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentAvlSetIter.Last()
+	return item
+}
+
+func (it *PersistentAvlIter) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentAvlSetIter.Next()
+	return item
+}
+
+func (it *PersistentAvlIter) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentAvlSetIter.Prev()
+	return item
+}
+
+func (it *PersistentAvlIter) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentAvlSetIter.Current()
+	return item
+}
@@ -0,0 +1,505 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+//node of persistent red black tree, never changed once it is linked into a tree
+type pstrbnode[T any] struct {
+	links [ChildNum]*pstrbnode[T] //child node
+	data  T                       //data item
+	color byte                    //node color
+	size  int                     //number of node in subtree
+}
+
+func (n *pstrbnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *pstrbnode[T]) updateSize() {
+	n.size = n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+func (n *pstrbnode[T]) isRed() bool {
+	return n != nil && n.color == red
+}
+
+//copy n before changing it, the copy is owned by the caller
+func (n *pstrbnode[T]) clone() *pstrbnode[T] {
+	c := *n
+	return &c
+}
+
+//the balancing below follows the left-leaning red black tree,
+//every helper takes an owned node and clones any other node it changes
+
+//rotate owned node h in direction dir, return the new owned subtree root
+func (h *pstrbnode[T]) rotate(dir int) *pstrbnode[T] {
+	x := h.links[1-dir].clone()
+	h.links[1-dir] = x.links[dir]
+	x.links[dir] = h
+	x.color = h.color
+	h.color = red
+	h.updateSize()
+	x.updateSize()
+	return x
+}
+
+//flip colors of owned node h and both its children
+func (h *pstrbnode[T]) flipColors() {
+	h.color ^= red
+	for i := 0; i < ChildNum; i++ {
+		h.links[i] = h.links[i].clone()
+		h.links[i].color ^= red
+	}
+}
+
+//restore left-leaning invariants of owned node h on the way up
+func (h *pstrbnode[T]) fixUp() *pstrbnode[T] {
+	if h.links[Right].isRed() && !h.links[Left].isRed() {
+		h = h.rotate(Left)
+	}
+	if h.links[Left].isRed() && h.links[Left].links[Left].isRed() {
+		h = h.rotate(Right)
+	}
+	if h.links[Left].isRed() && h.links[Right].isRed() {
+		h.flipColors()
+	}
+	h.updateSize()
+	return h
+}
+
+//make left child of owned node h or one of its children red
+func (h *pstrbnode[T]) moveRedLeft() *pstrbnode[T] {
+	h.flipColors()
+	if h.links[Right].links[Left].isRed() {
+		h.links[Right] = h.links[Right].rotate(Right)
+		h = h.rotate(Left)
+		h.flipColors()
+	}
+	return h
+}
+
+//make right child of owned node h or one of its children red
+func (h *pstrbnode[T]) moveRedRight() *pstrbnode[T] {
+	h.flipColors()
+	if h.links[Left].links[Left].isRed() {
+		h = h.rotate(Right)
+		h.flipColors()
+	}
+	return h
+}
+
+//PersistentRbSet is an immutable red black tree,
+//Insert, Replace and Delete leave the tree alone and return a new version,
+//which shares all nodes off the changed path with the old one,
+//so every version stays valid and a snapshot is just a pointer
+type PersistentRbSet[T any] struct {
+	root    *pstrbnode[T]    //root of  tree
+	cmpFunc func(a, b T) int //compare function
+}
+
+func NewPersistentRbSet[T any](cmp func(a, b T) int) *PersistentRbSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &PersistentRbSet[T]{
+		cmpFunc: cmp,
+	}
+}
+
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedPersistentRbSet[T cmp.Ordered]() *PersistentRbSet[T] {
+	return NewPersistentRbSet(cmp.Compare[T])
+}
+
+//return a version of tree rooted at root, root is owned and painted black
+func (t *PersistentRbSet[T]) with(root *pstrbnode[T]) *PersistentRbSet[T] {
+	if root != nil {
+		root.color = black
+	}
+	return &PersistentRbSet[T]{root: root, cmpFunc: t.cmpFunc}
+}
+
+func (t *PersistentRbSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.root.subSize()
+}
+
+//search target in tree
+//if find it return item
+//else ok is false
+func (t *PersistentRbSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	for p := t.root; p != nil; {
+		cmp := t.cmpFunc(target, p.data)
+		if cmp < 0 {
+			p = p.links[Left]
+		} else if cmp > 0 {
+			p = p.links[Right]
+		} else {
+			return p.data, true
+		}
+	}
+	return
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *PersistentRbSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil || k < 0 || k >= t.Count() {
+		return
+	}
+	p := t.root
+	for {
+		ls := p.links[Left].subSize()
+		if k < ls {
+			p = p.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			p = p.links[Right]
+		} else {
+			return p.data, true
+		}
+	}
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *PersistentRbSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
+	}
+	rank := 0
+	for p := t.root; p != nil; {
+		if t.cmpFunc(item, p.data) > 0 {
+			rank += p.links[Left].subSize() + 1
+			p = p.links[Right]
+		} else {
+			p = p.links[Left]
+		}
+	}
+	return rank
+}
+
+//insert item in tree
+//return the new version and true if item was successfully inserted
+//return t itself and false if item already in tree
+func (t *PersistentRbSet[T]) Insert(item T) (*PersistentRbSet[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	if _, ok := t.Find(item); ok {
+		return t, false
+	}
+	root, _, _ := t.put(t.root, item)
+	return t.with(root), true
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+//return the new version and the replaced item
+func (t *PersistentRbSet[T]) Replace(item T) (_ *PersistentRbSet[T], old T, ok bool) {
+	if t == nil {
+		return
+	}
+	root, old, ok := t.put(t.root, item)
+	return t.with(root), old, ok
+}
+
+//put item in subtree n, replacing the same key item
+//return the new owned subtree and the replaced item
+func (t *PersistentRbSet[T]) put(n *pstrbnode[T], item T) (_ *pstrbnode[T], old T, ok bool) {
+	if n == nil {
+		return &pstrbnode[T]{data: item, color: red, size: 1}, old, false
+	}
+	h := n.clone()
+	cmp := t.cmpFunc(item, h.data)
+	if cmp < 0 {
+		h.links[Left], old, ok = t.put(h.links[Left], item)
+	} else if cmp > 0 {
+		h.links[Right], old, ok = t.put(h.links[Right], item)
+	} else {
+		old, ok = h.data, true
+		h.data = item
+		return h, old, ok
+	}
+	return h.fixUp(), old, ok
+}
+
+//delete item in tree
+//return the new version and item if find it
+//else return t itself and ok is false
+func (t *PersistentRbSet[T]) Delete(item T) (_ *PersistentRbSet[T], deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	if deleted, ok = t.Find(item); !ok {
+		return t, deleted, false
+	}
+	root := t.root.clone()
+	if !root.links[Left].isRed() && !root.links[Right].isRed() {
+		root.color = red
+	}
+	return t.with(t.delete(root, item)), deleted, true
+}
+
+//delete item, which must be in subtree rooted at owned node h
+//return the new owned subtree
+func (t *PersistentRbSet[T]) delete(h *pstrbnode[T], item T) *pstrbnode[T] {
+	if t.cmpFunc(item, h.data) < 0 {
+		if !h.links[Left].isRed() && !h.links[Left].links[Left].isRed() {
+			h = h.moveRedLeft()
+		}
+		h.links[Left] = t.delete(h.links[Left].clone(), item)
+		return h.fixUp()
+	}
+	if h.links[Left].isRed() {
+		h = h.rotate(Right)
+	}
+	if t.cmpFunc(item, h.data) == 0 && h.links[Right] == nil {
+		return nil
+	}
+	if !h.links[Right].isRed() && !h.links[Right].links[Left].isRed() {
+		h = h.moveRedRight()
+	}
+	if t.cmpFunc(item, h.data) == 0 {
+		var min T
+		h.links[Right], min = deletePstRbMin(h.links[Right].clone())
+		h.data = min
+	} else {
+		h.links[Right] = t.delete(h.links[Right].clone(), item)
+	}
+	return h.fixUp()
+}
+
+//delete the least node of subtree rooted at owned node h
+//return the new owned subtree and the deleted item
+func deletePstRbMin[T any](h *pstrbnode[T]) (*pstrbnode[T], T) {
+	if h.links[Left] == nil {
+		return nil, h.data
+	}
+	if !h.links[Left].isRed() && !h.links[Left].links[Left].isRed() {
+		h = h.moveRedLeft()
+	}
+	var min T
+	h.links[Left], min = deletePstRbMin(h.links[Left].clone())
+	return h.fixUp(), min
+}
+
+func (t *PersistentRbSet[T]) Iter() IteratorOf[T] {
+	return NewPersistentRbSetIter[T]().HookWith(t)
+}
+
+func (t *PersistentRbSet[T]) seekIter() seekIterOf[T] {
+	return NewPersistentRbSetIter[T]().HookWith(t)
+}
+
+//sequence of all items in ascending order
+func (t *PersistentRbSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAll(t.seekIter)
+}
+
+//sequence of all items in descending order
+func (t *PersistentRbSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBackward(t.seekIter)
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PersistentRbSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAscend(t.seekIter, from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PersistentRbSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqDescend(t.seekIter, from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PersistentRbSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBetween(t.seekIter, t.cmpFunc, lo, hi)
+}
+
+//iterator of one version of PersistentRbSet,
+//versions never change, so the iterator is never invalidated
+type PersistentRbSetIter[T any] struct {
+	tree   *PersistentRbSet[T]        //the tree be iterated
+	node   *pstrbnode[T]              //current node in tree
+	stack  [rbMaxHeight]*pstrbnode[T] //all node above current node
+	height int                        //current depth of stack
+}
+
+func NewPersistentRbSetIter[T any]() *PersistentRbSetIter[T] {
+	return &PersistentRbSetIter[T]{}
+}
+
+func (it *PersistentRbSetIter[T]) HookWith(tree *PersistentRbSet[T]) *PersistentRbSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	it.height = 0
+	return it
+}
+
+//move to the outmost node in direction dir
+func (it *PersistentRbSetIter[T]) edge(dir int) (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	it.height = 0
+	w := it.tree.root
+	if w == nil {
+		it.node = nil
+		return
+	}
+	for w.links[dir] != nil {
+		it.stack[it.height] = w
+		it.height++
+		w = w.links[dir]
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move to the neighbour node in direction dir
+func (it *PersistentRbSetIter[T]) step(dir int) (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	w := it.node
+	if w == nil {
+		return it.edge(1 - dir)
+	} else if w.links[dir] != nil {
+		it.stack[it.height] = w
+		it.height++
+		w = w.links[dir]
+		for w.links[1-dir] != nil {
+			it.stack[it.height] = w
+			it.height++
+			w = w.links[1-dir]
+		}
+	} else {
+		for {
+			if it.height == 0 {
+				it.node = nil
+				return
+			}
+			n := w
+			it.height--
+			w = it.stack[it.height]
+			if w.links[dir] != n {
+				break
+			}
+		}
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move iterator to the nearest item to item in direction dir,
+//eq tells whether item itself may be taken
+func (it *PersistentRbSetIter[T]) seekNear(item T, dir int, eq bool) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	var (
+		c  *pstrbnode[T] //candidate node
+		ch int           //stack height of candidate node
+	)
+	it.height = 0
+	for w := it.tree.root; w != nil; {
+		cmp := it.tree.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			c, ch = w, it.height
+			break
+		}
+		it.stack[it.height] = w
+		it.height++
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c, ch = w, it.height-1
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	it.node = c
+	if c == nil {
+		it.height = 0
+		return
+	}
+	it.height = ch
+	return c.data, true
+}
+
+//move iterator to the least item greater than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentRbSetIter[T]) SeekGE(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, true)
+}
+
+//move iterator to the least item greater than item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentRbSetIter[T]) SeekGT(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, false)
+}
+
+//move iterator to the greatest item less than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentRbSetIter[T]) SeekLE(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, true)
+}
+
+//move iterator to the greatest item less than item
+//if there is no such item, iterator is moved to nil position
+func (it *PersistentRbSetIter[T]) SeekLT(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, false)
+}
+
+func (it *PersistentRbSetIter[T]) First() (item T, ok bool) {
+	return it.edge(Left)
+}
+
+func (it *PersistentRbSetIter[T]) Last() (item T, ok bool) {
+	return it.edge(Right)
+}
+
+func (it *PersistentRbSetIter[T]) Next() (item T, ok bool) {
+	return it.step(Right)
+}
+
+func (it *PersistentRbSetIter[T]) Prev() (item T, ok bool) {
+	return it.step(Left)
+}
+
+func (it *PersistentRbSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
@@ -0,0 +1,196 @@
+package bbst
+
+import (
+	"iter"
+)
+
+//PersistentRbTree is the interface{} flavour of PersistentRbSet
+type PersistentRbTree struct {
+	PersistentRbSet[Item]
+}
+
+func NewPersistentRbTree(cmp Compare, extra interface{}) *PersistentRbTree {
+	if cmp == nil {
+		return nil
+	}
+	return &PersistentRbTree{PersistentRbSet[Item]{cmpFunc: compareOf(cmp, extra)}}
+}
+
+func (t *PersistentRbTree) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.PersistentRbSet.Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *PersistentRbTree) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := t.PersistentRbSet.Find(target)
+	return item
+}
+
+//return item at index k in sorted order, k counts from 0
+//return nil if k is out of range
+func (t *PersistentRbTree) Select(k int) Item {
+	if t == nil {
+		return nil
+	}
+	item, _ := t.PersistentRbSet.Select(k)
+	return item
+}
+
+//return number of items in tree less than item
+func (t *PersistentRbTree) Rank(item Item) int {
+	if t == nil || item == nil {
+		return 0
+	}
+	return t.PersistentRbSet.Rank(item)
+}
+
+//insert item in tree
+//return the new version and true if item was successfully inserted
+//return t itself and false if item already in tree
+func (t *PersistentRbTree) Insert(item Item) (*PersistentRbTree, bool) {
+	if t == nil || item == nil {
+		return t, false
+	}
+	n, ok := t.PersistentRbSet.Insert(item)
+	if !ok {
+		return t, false
+	}
+	return &PersistentRbTree{*n}, true
+}
+
+//replace item in tree with same key item
+//return the new version and old item
+func (t *PersistentRbTree) Replace(item Item) (*PersistentRbTree, Item) {
+	if t == nil || item == nil {
+		return t, nil
+	}
+	n, old, _ := t.PersistentRbSet.Replace(item)
+	return &PersistentRbTree{*n}, old
+}
+
+//delete item in tree
+//return the new version and item if find it
+//else return t itself and nil
+func (t *PersistentRbTree) Delete(item Item) (*PersistentRbTree, Item) {
+	if t == nil || item == nil {
+		return t, nil
+	}
+	n, deleted, ok := t.PersistentRbSet.Delete(item)
+	if !ok {
+		return t, nil
+	}
+	return &PersistentRbTree{*n}, deleted
+}
+
+//sequence of all items in ascending order
+func (t *PersistentRbTree) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PersistentRbSet.All()
+}
+
+//sequence of all items in descending order
+func (t *PersistentRbTree) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PersistentRbSet.Backward()
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PersistentRbTree) Ascend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PersistentRbSet.Ascend(from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PersistentRbTree) Descend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PersistentRbSet.Descend(from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PersistentRbTree) Between(lo, hi Item) iter.Seq[Item] {
+	if t == nil || lo == nil || hi == nil {
+		return empty[Item]
+	}
+	return t.PersistentRbSet.Between(lo, hi)
+}
+
+func (t *PersistentRbTree) Iter() Iterator {
+	it := NewPersistentRbIter()
+	return it.HookWith(t)
+}
+
+type PersistentRbIter struct {
+	PersistentRbSetIter[Item]
+}
+
+func NewPersistentRbIter() *PersistentRbIter {
+	return &PersistentRbIter{}
+}
+
+func (it *PersistentRbIter) HookWith(tree *PersistentRbTree) *PersistentRbIter {
+	if it == nil {
+		return nil
+	}
+	if tree == nil {
+		it.PersistentRbSetIter.HookWith(nil)
+	} else {
+		it.PersistentRbSetIter.HookWith(&tree.PersistentRbSet)
+	}
+	return it
+}
+
+func (it *PersistentRbIter) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentRbSetIter.First()
+	return item
+}
+
+func (it *PersistentRbIter) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentRbSetIter.Last()
+	return item
+}
+
+func (it *PersistentRbIter) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentRbSetIter.Next()
+	return item
+}
+
+func (it *PersistentRbIter) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentRbSetIter.Prev()
+	return item
+}
+
+func (it *PersistentRbIter) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PersistentRbSetIter.Current()
+	return item
+}
@@ -0,0 +1,262 @@
+package bbst
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func recurseVerifyPstTree(t *testing.T, node *pstnode[Item], ok *bool, count *int, min, max int, height *int) {
+	var (
+		subcount  [ChildNum]int
+		subheight [ChildNum]int
+	)
+	if node == nil {
+		*count = 0
+		*height = 0
+		return
+	}
+	d := node.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	recurseVerifyPstTree(t, node.links[Left], ok, &subcount[Left], min, d-1, &subheight[Left])
+	recurseVerifyPstTree(t, node.links[Right], ok, &subcount[Right], d+1, max, &subheight[Right])
+	*count = 1 + subcount[Left] + subcount[Right]
+	*height = 1 + subheight[Left]
+	if subheight[Right] > subheight[Left] {
+		*height = 1 + subheight[Right]
+	}
+	if node.size != *count {
+		t.Errorf("Node %d has size %d, but subtree has %d nodes.\n", d, node.size, *count)
+		*ok = false
+	}
+	if int(node.height) != *height {
+		t.Errorf("Node %d has height %d, but should be %d.\n", d, node.height, *height)
+		*ok = false
+	}
+	if b := subheight[Right] - subheight[Left]; b < -1 || b > 1 {
+		t.Errorf("Node %d has balance %d.\n", d, b)
+		*ok = false
+	}
+}
+
+func recurseVerifyPstRbTree(t *testing.T, node *pstrbnode[Item], ok *bool, count *int, min, max int, bh *int) {
+	var (
+		subcount [ChildNum]int
+		subbh    [ChildNum]int
+	)
+	if node == nil {
+		*count = 0
+		*bh = 0
+		return
+	}
+	d := node.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	recurseVerifyPstRbTree(t, node.links[Left], ok, &subcount[Left], min, d-1, &subbh[Left])
+	recurseVerifyPstRbTree(t, node.links[Right], ok, &subcount[Right], d+1, max, &subbh[Right])
+	*count = 1 + subcount[Left] + subcount[Right]
+	*bh = subbh[Left]
+	if node.color == black {
+		*bh++
+	}
+	if node.size != *count {
+		t.Errorf("Node %d has size %d, but subtree has %d nodes.\n", d, node.size, *count)
+		*ok = false
+	}
+	if node.color == red && (node.links[Left].isRed() || node.links[Right].isRed()) {
+		t.Errorf("Red node %d has red child\n", d)
+		*ok = false
+	}
+	if subbh[Left] != subbh[Right] {
+		t.Errorf("Node %d has two different black-heights: left bh=%d, right bh=%d\n", d, subbh[Left], subbh[Right])
+		*ok = false
+	}
+}
+
+//check items of tree in both directions against arr, which is sorted
+func verifyPstTraversal(t *testing.T, it Iterator, arr []int) bool {
+	ok := true
+	i := 0
+	for item := it.First(); item != nil; item = it.Next() {
+		if i >= len(arr) || item.(int) != arr[i] {
+			t.Errorf("Tree has %v at %d in traversal\n", item, i)
+			return false
+		}
+		i++
+	}
+	if i != len(arr) {
+		t.Errorf("Tree should have %d items, but has %d in traversal\n", len(arr), i)
+		ok = false
+	}
+	for item := it.Last(); item != nil; item = it.Prev() {
+		i--
+		if i < 0 || item.(int) != arr[i] {
+			t.Errorf("Tree has %v at %d in reverse traversal\n", item, i)
+			return false
+		}
+	}
+	return ok
+}
+
+func verifyPstTree(t *testing.T, tree *PersistentAvlTree, arr []int) bool {
+	ok := true
+	count, height := 0, 0
+	recurseVerifyPstTree(t, tree.root, &ok, &count, 0, math.MaxInt64, &height)
+	if count != len(arr) || tree.Count() != len(arr) {
+		t.Errorf("Tree has %d nodes and count %d, but should have %d.\n", count, tree.Count(), len(arr))
+		ok = false
+	}
+	return ok && verifyPstTraversal(t, tree.Iter(), arr)
+}
+
+func verifyPstRbTree(t *testing.T, tree *PersistentRbTree, arr []int) bool {
+	ok := true
+	if tree.root.isRed() {
+		t.Errorf("Tree root is not black.\n")
+		ok = false
+	}
+	count, bh := 0, 0
+	recurseVerifyPstRbTree(t, tree.root, &ok, &count, 0, math.MaxInt64, &bh)
+	if count != len(arr) || tree.Count() != len(arr) {
+		t.Errorf("Tree has %d nodes and count %d, but should have %d.\n", count, tree.Count(), len(arr))
+		ok = false
+	}
+	return ok && verifyPstTraversal(t, tree.Iter(), arr)
+}
+
+type persistentTab[S any] interface {
+	*S
+	Count() int
+	Find(target Item) Item
+	Insert(item Item) (*S, bool)
+	Replace(item Item) (*S, Item)
+	Delete(item Item) (*S, Item)
+}
+
+func testPersistent[S any, P persistentTab[S]](t *testing.T, name string, newTree func(cmp Compare) P, verify func(*testing.T, P, []int) bool) {
+	sorted := func(arr []int) []int {
+		arr = append([]int(nil), arr...)
+		sort.Ints(arr)
+		return arr
+	}
+	versions := []P{newTree(intCmp)}
+	contents := [][]int{nil}
+	for i, elem := range insertArr {
+		next, ok := versions[i].Insert(elem)
+		if !ok {
+			t.Fatalf("%s: insert %d failed\n", name, elem)
+		}
+		if again, ok := P(next).Insert(elem); ok || P(again) != P(next) {
+			t.Errorf("%s: duplicate %d inserted\n", name, elem)
+		}
+		versions = append(versions, next)
+		contents = append(contents, sorted(insertArr[:i+1]))
+	}
+	for i, elem := range deleteArr {
+		last := versions[len(versions)-1]
+		next, deleted := last.Delete(elem)
+		if deleted == nil || deleted.(int) != elem {
+			t.Fatalf("%s: delete %d returned %v\n", name, elem, deleted)
+		}
+		if again, deleted := P(next).Delete(elem); deleted != nil || P(again) != P(next) {
+			t.Errorf("%s: deleted %d found again\n", name, elem)
+		}
+		versions = append(versions, next)
+		contents = append(contents, sorted(deleteArr[i+1:]))
+	}
+	//every version is still intact after all the updates
+	for i, v := range versions {
+		if !verify(t, v, contents[i]) {
+			t.Fatalf("%s: version %d broken, should have %v\n", name, i, contents[i])
+		}
+	}
+
+	old := newTree(mapCmp)
+	old, _ = old.Insert(kv{"CPU", 10})
+	old, _ = old.Insert(kv{"GPU", 15})
+	cur, prev := old.Replace(kv{"GPU", 25})
+	if prev.(kv).v != 15 || P(cur).Find(kv{k: "GPU"}).(kv).v != 25 || old.Find(kv{k: "GPU"}).(kv).v != 15 {
+		t.Errorf("%s: replace changed the old version\n", name)
+	}
+	if cur, prev = old.Replace(kv{"RAM", 20}); prev != nil || P(cur).Count() != 3 || old.Count() != 2 {
+		t.Errorf("%s: replace of new key changed the old version\n", name)
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	testPersistent(t, "persistentAvl", func(cmp Compare) *PersistentAvlTree { return NewPersistentAvlTree(cmp, nil) }, verifyPstTree)
+	testPersistent(t, "persistentRb", func(cmp Compare) *PersistentRbTree { return NewPersistentRbTree(cmp, nil) }, verifyPstRbTree)
+}
+
+func testRcuSet(t *testing.T, name string, set *RcuSet[int]) {
+	const readers = 4
+	var (
+		wg       sync.WaitGroup
+		inserted atomic.Int64 //number of insertArr items published
+		done     atomic.Bool
+	)
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				if p := inserted.Load(); p > 0 && p <= int64(len(insertArr)) {
+					elem := insertArr[p-1]
+					if _, ok := set.Find(elem); !ok {
+						t.Errorf("%s: published item %d not found\n", name, elem)
+						return
+					}
+				}
+				it := set.Iter()
+				last := -1
+				for v, ok := it.First(); ok; v, ok = it.Next() {
+					if v <= last {
+						t.Errorf("%s: iterator got %d after %d\n", name, v, last)
+						return
+					}
+					last = v
+				}
+			}
+		}()
+	}
+	for i, elem := range insertArr {
+		if !set.Insert(elem) {
+			t.Errorf("%s: insert %d failed\n", name, elem)
+		}
+		inserted.Store(int64(i + 1))
+	}
+	var odd []int
+	for _, elem := range deleteArr {
+		if elem%2 == 1 {
+			odd = append(odd, elem)
+		} else if v, ok := set.Delete(elem); !ok || v != elem {
+			t.Errorf("%s: delete %d returned %v\n", name, elem, v)
+		}
+	}
+	done.Store(true)
+	wg.Wait()
+	sort.Ints(odd)
+	if set.Count() != len(odd) {
+		t.Errorf("%s: set has %d items, but should have %d\n", name, set.Count(), len(odd))
+	}
+	for i, v := range odd {
+		if got, ok := set.Select(i); !ok || got != v || set.Rank(v) != i {
+			t.Errorf("%s: select %d got %d, but should be %d\n", name, i, got, v)
+		}
+	}
+	if old, ok := set.Replace(1); len(odd) > 0 && (!ok || old != 1) {
+		t.Errorf("%s: replace 1 returned %d\n", name, old)
+	}
+}
+
+func TestRcuSet(t *testing.T) {
+	testRcuSet(t, "rcuAvl", NewOrderedRcuAvlSet[int]())
+	testRcuSet(t, "rcuRb", NewOrderedRcuRbSet[int]())
+}
@@ -1,34 +1,90 @@
 package bbst
 
 import (
+	"cmp"
+	"iter"
 	"unsafe"
 )
 
-type prbnode struct {
-	links  [ChildNum]*prbnode //child node
-	parent *prbnode           //parent node
-	data   Item               //data item
-	color  byte               //node color
+type prbnode[T any] struct {
+	links  [ChildNum]*prbnode[T] //child node
+	parent *prbnode[T]           //parent node
+	data   T                     //data item
+	color  byte                  //node color
+	size   int                   //number of node in subtree
 }
 
-type PRbTree struct {
-	root       *prbnode    //root of  tree
-	cmpFunc    Compare     //compare function
-	extraParam interface{} //extra param for cmpFunc
-	count      int         // number of item in tree
+//number of node in subtree rooted at n
+func (n *prbnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+//recompute size of n from its children
+func (n *prbnode[T]) updateSize() {
+	n.size = n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+//address of data of n, nil if n is nil
+func (n *prbnode[T]) dataAddr() *T {
+	if n == nil {
+		return nil
+	}
+	return &n.data
+}
+
+//child of n in direction dir
+func (n *prbnode[T]) child(dir int) *prbnode[T] {
+	return n.links[dir]
 }
 
-func NewPRbTree(cmp Compare, extra interface{}) *PRbTree {
+//color bit of shape byte of n, see marshalTree
+func (n *prbnode[T]) shape() byte {
+	return n.color
+}
+
+type PRbSet[T any] struct {
+	root       *prbnode[T]      //root of  tree
+	cmpFunc    func(a, b T) int //compare function
+	count      int              // number of item in tree
+	generation int              // generation number
+	iterPolicy IterPolicy       //policy of iterators hooked with tree
+	codec      Codec[T]         //codec of items for MarshalBinary and UnmarshalBinary
+	fix        func(n, l, r *T) //recompute augmented data of item n from children l and r, nil if tree is not augmented
+}
+
+func NewPRbSet[T any](cmp func(a, b T) int) *PRbSet[T] {
 	if cmp == nil {
 		return nil
 	}
-	return &PRbTree{
-		cmpFunc:    cmp,
-		extraParam: extra,
+	return &PRbSet[T]{
+		cmpFunc: cmp,
+	}
+}
+
+//create a set holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n) without rebalancing
+//return ErrNotSorted if items are out of order or have duplicates
+func NewPRbSetFromSorted[T any](cmp func(a, b T) int, items []T) (*PRbSet[T], error) {
+	t := NewPRbSet(cmp)
+	if t == nil {
+		return nil, nil
+	}
+	if err := t.loadSorted(items); err != nil {
+		return nil, err
 	}
+	return t, nil
 }
 
-func (t *PRbTree) Count() int {
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedPRbSet[T cmp.Ordered]() *PRbSet[T] {
+	return NewPRbSet(cmp.Compare[T])
+}
+
+func (t *PRbSet[T]) Count() int {
 	if t == nil {
 		return 0
 	}
@@ -37,36 +93,214 @@ func (t *PRbTree) Count() int {
 
 //search target in tree
 //if find it return item
-//else return nil
-func (t *PRbTree) Find(target Item) Item {
-	if t == nil || target == nil {
-		return nil
+//else ok is false
+func (t *PRbSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	for w := t.root; w != nil; {
+		ret := t.cmpFunc(target, w.data)
+		if ret < 0 {
+			w = w.links[Left]
+		} else if ret > 0 {
+			w = w.links[Right]
+		} else {
+			return w.data, true
+		}
+	}
+	return
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *PRbSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil || k < 0 || k >= t.count {
+		return
+	}
+	w := t.root
+	for {
+		ls := w.links[Left].subSize()
+		if k < ls {
+			w = w.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			w = w.links[Right]
+		} else {
+			return w.data, true
+		}
+	}
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *PRbSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
 	}
+	r := 0
 	for w := t.root; w != nil; {
-		ret := t.cmpFunc(target, w.data, t.extraParam)
+		ret := t.cmpFunc(item, w.data)
 		if ret < 0 {
 			w = w.links[Left]
 		} else if ret > 0 {
+			r += w.links[Left].subSize() + 1
 			w = w.links[Right]
 		} else {
-			return w.data
+			return r + w.links[Left].subSize()
 		}
 	}
+	return r
+}
+
+//return the nearest node to item in direction dir,
+//dir Right looks for the least node greater than item,
+//dir Left looks for the greatest node less than item,
+//node equal to item is accepted if eq is true
+func (t *PRbSet[T]) near(item T, dir int, eq bool) *prbnode[T] {
+	var c *prbnode[T] //candidate node
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			return w
+		}
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	return c
+}
+
+//return the greatest item less than or equal to item
+//ok is false if there is no such item
+func (t *PRbSet[T]) Floor(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Left, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return the least item greater than or equal to item
+//ok is false if there is no such item
+func (t *PRbSet[T]) Ceiling(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Right, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return iterator at the first item not less than item
+func (t *PRbSet[T]) LowerBound(item T) IteratorOf[T] {
+	it := NewPRbSetIter[T]()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *PRbSet[T]) UpperBound(item T) IteratorOf[T] {
+	it := NewPRbSetIter[T]()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *PRbSet[T]) Range(lo, hi T, fn func(item T) bool) {
+	if t == nil || fn == nil {
+		return
+	}
+	var it PRbSetIter[T]
+	for item, ok := it.HookWith(t).SeekGE(lo); ok && t.cmpFunc(item, hi) < 0; item, ok = it.Next() {
+		if !fn(item) {
+			return
+		}
+	}
+}
+
+//recompute size and augmented data of n from its children
+func (t *PRbSet[T]) update(n *prbnode[T]) {
+	n.updateSize()
+	if t.fix != nil {
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of n and all its ancestors
+func (t *PRbSet[T]) fixUp(n *prbnode[T]) {
+	if t.fix == nil {
+		return
+	}
+	for ; n != nil; n = n.parent {
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of nodes on the path from root to item,
+//after item was changed in place
+func (t *PRbSet[T]) fixKey(item T) {
+	if t.fix == nil {
+		return
+	}
+	w := t.root
+	for w != nil {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			break
+		}
+		if cmp > 0 {
+			w = w.links[Right]
+		} else {
+			w = w.links[Left]
+		}
+	}
+	t.fixUp(w)
+}
+
+//replace items of t with items, which are sorted in strictly ascending order
+func (t *PRbSet[T]) loadSorted(items []T) error {
+	if err := checkSorted(t.cmpFunc, items); err != nil {
+		return err
+	}
+	t.root = t.build(items, 0, redDepth(len(items)))
+	t.count = len(items)
+	t.generation++
 	return nil
 }
 
-func (t *PRbTree) insert(item Item) (*Item, bool) {
-	if t == nil || item == nil {
+//build a perfectly balanced subtree of items at depth d, return its root
+//nodes at depth rd are painted red, see RbSet.build
+func (t *PRbSet[T]) build(items []T, d, rd int) *prbnode[T] {
+	if len(items) == 0 {
+		return nil
+	}
+	m := len(items) / 2
+	n := &prbnode[T]{data: items[m]}
+	if d == rd {
+		n.color = red
+	}
+	return t.link(n, t.build(items[:m], d+1, rd), t.build(items[m+1:], d+1, rd))
+}
+
+func (t *PRbSet[T]) insert(item T) (*T, bool) {
+	if t == nil {
 		return nil, false
 	}
 	var (
-		w   *prbnode //walk node
-		p   *prbnode //parent of w
-		n   *prbnode //new node
-		dir int      //direction of p
+		w   *prbnode[T] //walk node
+		p   *prbnode[T] //parent of w
+		n   *prbnode[T] //new node
+		dir int         //direction of p
 	)
 	for w = t.root; w != nil; p, w = w, w.links[dir] {
-		cmp := t.cmpFunc(item, w.data, t.extraParam)
+		cmp := t.cmpFunc(item, w.data)
 		if cmp == 0 {
 			return &w.data, false
 		}
@@ -76,13 +310,18 @@ func (t *PRbTree) insert(item Item) (*Item, bool) {
 			dir = Left
 		}
 	}
-	n = &prbnode{parent: p, data: item, color: red}
+	n = &prbnode[T]{parent: p, data: item, color: red, size: 1}
+	for w = p; w != nil; w = w.parent {
+		w.size++
+	}
 	if p != nil {
 		p.links[dir] = n
 	} else {
 		t.root = n
 	}
+	t.fixUp(n)
 	t.count++
+	t.generation++
 
 	w = n
 	for {
@@ -102,13 +341,11 @@ func (t *PRbTree) insert(item Item) (*Item, bool) {
 				g.color = red
 				w = g
 			} else {
-				pg := g.parent
-				if pg == nil {
-					pg = (*prbnode)(unsafe.Pointer(&t.root))
-				}
 				if p.links[Right] == w {
 					p.links[Right] = w.links[Left]
 					w.links[Left] = p
+					t.update(p)
+					t.update(w)
 					g.links[Left] = w
 					p.parent = w
 					if p.links[Right] != nil {
@@ -120,12 +357,10 @@ func (t *PRbTree) insert(item Item) (*Item, bool) {
 				p.color = black
 				g.links[Left] = p.links[Right]
 				p.links[Right] = g
+				t.update(g)
+				t.update(p)
 
-				d := Left
-				if pg.links[Left] != g {
-					d = Right
-				}
-				pg.links[d] = p
+				t.replaceChild(g.parent, g, p)
 				p.parent = g.parent
 				g.parent = p
 				if g.links[Left] != nil {
@@ -141,13 +376,11 @@ func (t *PRbTree) insert(item Item) (*Item, bool) {
 				g.color = red
 				w = g
 			} else {
-				pg := g.parent
-				if pg == nil {
-					pg = (*prbnode)(unsafe.Pointer(&t.root))
-				}
 				if p.links[Left] == w {
 					p.links[Left] = w.links[Right]
 					w.links[Right] = p
+					t.update(p)
+					t.update(w)
 					g.links[Right] = w
 					p.parent = w
 					if p.links[Left] != nil {
@@ -159,12 +392,10 @@ func (t *PRbTree) insert(item Item) (*Item, bool) {
 				p.color = black
 				g.links[Right] = p.links[Left]
 				p.links[Left] = g
+				t.update(g)
+				t.update(p)
 
-				d := Left
-				if pg.links[Left] != g {
-					d = Right
-				}
-				pg.links[d] = p
+				t.replaceChild(g.parent, g, p)
 
 				p.parent = g.parent
 				g.parent = p
@@ -182,40 +413,50 @@ func (t *PRbTree) insert(item Item) (*Item, bool) {
 //insert item in tree
 //return true if item was successfully inserted
 //return false if item already in tree
-func (t *PRbTree) Insert(item Item) bool {
+func (t *PRbSet[T]) Insert(item T) bool {
 	_, succ := t.insert(item)
 	return succ
 }
 
+//insert all items in tree, items equal to one in tree or to an earlier one in items are skipped
+//the batch is sorted and merged into tree, O(m log(n/m + 1)) for m items in a tree of n items
+//return number of inserted items and skipped items in ascending order
+func (t *PRbSet[T]) InsertBatch(items []T) (inserted int, dups []T) {
+	if t == nil {
+		return
+	}
+	return insertBatch(t, t.cmpFunc, items)
+}
+
 //replace item in tree with same key item
-func (t *PRbTree) Replace(item Item) Item {
+//insert item if no such key, ok is false in that case
+func (t *PRbSet[T]) Replace(item T) (old T, ok bool) {
 	addr, succ := t.insert(item)
 	if addr == nil || succ {
-		return nil
+		return
 	}
 	r := *addr
 	*addr = item
-	return r
+	t.fixKey(item)
+	return r, true
 }
 
 //delete item in tree
 //return item if find it
-//else  return nil
-func (t *PRbTree) Delete(item Item) Item {
-	if t == nil || item == nil {
-		return nil
+//else ok is false
+func (t *PRbSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
 	}
 	if t.root == nil {
-		return nil
+		return
 	}
 	var (
-		w   *prbnode //node to delete
-		p   *prbnode //parent of w
-		f   *prbnode //rebalancing node
-		dir int      //direction of p or f
+		w   *prbnode[T] //node to delete
+		dir int         //direction of w
 	)
 	for w = t.root; ; {
-		cmp := t.cmpFunc(item, w.data, t.extraParam)
+		cmp := t.cmpFunc(item, w.data)
 		if cmp == 0 {
 			break
 		}
@@ -226,14 +467,28 @@ func (t *PRbTree) Delete(item Item) Item {
 		}
 		w = w.links[dir]
 		if w == nil {
-			return nil
+			return
 		}
 	}
+	return t.deleteNode(w), true
+}
+
+//unlink node w from tree and rebalance
+//return item of w
+func (t *PRbSet[T]) deleteNode(w *prbnode[T]) T {
+	var (
+		p   *prbnode[T] //parent of w
+		f   *prbnode[T] //rebalancing node
+		dir int         //direction of p or f
+	)
 	ret := w.data
 	p = w.parent
+	//header node stands for parent of root, its left link is root
+	head := prbnode[T]{links: [ChildNum]*prbnode[T]{Left: t.root}}
 	if p == nil {
-		p = (*prbnode)(unsafe.Pointer(&t.root))
-		dir = Left
+		p = &head
+	} else if p.links[Right] == w {
+		dir = Right
 	}
 	if w.links[Right] == nil { //case 1, node to delete has no right child
 		p.links[dir] = w.links[Left]
@@ -252,6 +507,7 @@ func (t *PRbTree) Delete(item Item) Item {
 				r.links[Left].parent = r
 			}
 			w.color, r.color = r.color, w.color
+			r.size = w.size
 			f = r
 			dir = Right
 		} else { //case 3, node to delete w's right child has left child
@@ -274,24 +530,31 @@ func (t *PRbTree) Delete(item Item) Item {
 				r.links[Left].parent = r
 			}
 			w.color, s.color = s.color, w.color
+			s.size = w.size
 			f = r
 			dir = Left
 		}
 	}
+	if f != &head {
+		for q := f; q != nil; q = q.parent {
+			q.size--
+		}
+		t.fixUp(f)
+	}
 	if w.color == black {
 		for {
-			var tmp *prbnode
+			var tmp *prbnode[T]
 			x := f.links[dir]
 			if x != nil && x.color == red {
 				x.color = black
 				break
 			}
-			if f == (*prbnode)(unsafe.Pointer(&t.root)) {
+			if f == &head {
 				break
 			}
 			g := f.parent
 			if g == nil {
-				g = (*prbnode)(unsafe.Pointer(&t.root))
+				g = &head
 			}
 			if dir == Left {
 				//node x's sibling
@@ -301,6 +564,8 @@ func (t *PRbTree) Delete(item Item) Item {
 					f.color = red
 					f.links[Right] = s.links[Left]
 					s.links[Left] = f
+					t.update(f)
+					t.update(s)
 
 					d := Left
 					if g.links[Left] != f {
@@ -325,6 +590,8 @@ func (t *PRbTree) Delete(item Item) Item {
 						s.color = red
 						s.links[Left] = y.links[Right]
 						y.links[Right] = s
+						t.update(s)
+						t.update(y)
 						if s.links[Left] != nil {
 							s.links[Left].parent = s
 						}
@@ -338,6 +605,8 @@ func (t *PRbTree) Delete(item Item) Item {
 
 					f.links[Right] = s.links[Left]
 					s.links[Left] = f
+					t.update(f)
+					t.update(s)
 
 					d := Left
 					if g.links[Left] != f {
@@ -360,6 +629,8 @@ func (t *PRbTree) Delete(item Item) Item {
 					f.color = red
 					f.links[Left] = s.links[Right]
 					s.links[Right] = f
+					t.update(f)
+					t.update(s)
 
 					d := Left
 					if g.links[Left] != f {
@@ -384,6 +655,8 @@ func (t *PRbTree) Delete(item Item) Item {
 						s.color = red
 						s.links[Right] = y.links[Left]
 						y.links[Left] = s
+						t.update(s)
+						t.update(y)
 						if s.links[Right] != nil {
 							s.links[Right].parent = s
 						}
@@ -397,6 +670,8 @@ func (t *PRbTree) Delete(item Item) Item {
 
 					f.links[Left] = s.links[Right]
 					s.links[Right] = f
+					t.update(f)
+					t.update(s)
 
 					d := Left
 					if g.links[Left] != f {
@@ -415,7 +690,7 @@ func (t *PRbTree) Delete(item Item) Item {
 			tmp = f
 			f = f.parent
 			if f == nil {
-				f = (*prbnode)(unsafe.Pointer(&t.root))
+				f = &head
 			}
 			d := Left
 			if f.links[Left] != tmp {
@@ -425,32 +700,510 @@ func (t *PRbTree) Delete(item Item) Item {
 		}
 	}
 	w = nil
+	t.root = head.links[Left]
 	t.count--
+	t.generation++
 
 	return ret
 }
 
-func (t *PRbTree) Copy() *PRbTree {
+//delete all items in [lo, hi)
+//the range is split out of tree and the rest joined back, O(log n)
+//return number of deleted items
+func (t *PRbSet[T]) DeleteRange(lo, hi T) int {
+	if t == nil || t.cmpFunc(lo, hi) >= 0 {
+		return 0
+	}
+	i, j := t.Rank(lo), t.Rank(hi)
+	if i == j {
+		return 0
+	}
+	l, lh, r, rh := t.splitAt(t.root, t.root.blackHeight(), i)
+	_, _, r, rh = t.splitAt(r, rh, j-i)
+	root, _ := t.concat(l, lh, r, rh)
+	t.setRoot(root)
+	t.count -= j - i
+	t.generation++
+	return j - i
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied, O(log n)
+//return false and change nothing if items are out of order
+func (t *PRbSet[T]) Join(pivot T, right *PRbSet[T]) bool {
+	if t == nil || right == t {
+		return false
+	}
+	if n := t.root.extreme(Right); n != nil && t.cmpFunc(n.data, pivot) >= 0 {
+		return false
+	}
+	var (
+		r     *prbnode[T]
+		count int
+	)
+	if right != nil {
+		r, count = right.root, right.count
+	}
+	if n := r.extreme(Left); n != nil && t.cmpFunc(pivot, n.data) >= 0 {
+		return false
+	}
+	root, _ := t.join(t.root, t.root.blackHeight(), &prbnode[T]{data: pivot}, r, r.blackHeight())
+	t.setRoot(root)
+	t.count += count + 1
+	t.generation++
+	if right != nil {
+		right.clear()
+	}
+	return true
+}
+
+//split t into items less than item and items greater than item, O(log n)
+//eq is the item equal to item, found is false if there is no such item
+//t is emptied
+func (t *PRbSet[T]) Split(item T) (lt *PRbSet[T], eq T, found bool, gt *PRbSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, e, r, _ := t.split(t.root, t.root.blackHeight(), item)
+	if e != nil {
+		eq, found = e.data, true
+	}
+	lt, gt = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//split t into the first k items and the rest, O(log n)
+//t is emptied
+func (t *PRbSet[T]) SplitAt(k int) (lt, ge *PRbSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, r, _ := t.splitAt(t.root, t.root.blackHeight(), k)
+	lt, ge = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//number of black node on the path from n down to nil
+func (n *prbnode[T]) blackHeight() int {
+	h := 0
+	for ; n != nil; n = n.links[Left] {
+		if n.color == black {
+			h++
+		}
+	}
+	return h
+}
+
+//black heights of left and right subtree of n, h is black height of n
+func (n *prbnode[T]) childHeights(h int) (lh, rh int) {
+	if n.color == black {
+		h--
+	}
+	return h, h
+}
+
+//the last node of subtree rooted at n in direction dir
+func (n *prbnode[T]) extreme(dir int) *prbnode[T] {
+	for n != nil && n.links[dir] != nil {
+		n = n.links[dir]
+	}
+	return n
+}
+
+//make l and r children of n
+func (t *PRbSet[T]) link(n, l, r *prbnode[T]) *prbnode[T] {
+	n.links[Left] = l
+	n.links[Right] = r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	t.update(n)
+	return n
+}
+
+//set root of t to n, a red root is painted black
+func (t *PRbSet[T]) setRoot(n *prbnode[T]) {
+	if n != nil {
+		n.color = black
+		n.parent = nil
+	}
+	t.root = n
+}
+
+//put n in place of child old of p, p is nil if old is root
+func (t *PRbSet[T]) replaceChild(p, old, n *prbnode[T]) {
+	if p == nil {
+		t.root = n
+	} else if p.links[Left] == old {
+		p.links[Left] = n
+	} else {
+		p.links[Right] = n
+	}
+}
+
+//make an empty tree with the same order, augmentation and settings as t
+func (t *PRbSet[T]) emptyLike() *PRbSet[T] {
+	s := t.withRoot(nil)
+	s.iterPolicy = t.iterPolicy
+	s.codec = t.codec
+	return s
+}
+
+//make a tree with the same order as t rooted at n
+func (t *PRbSet[T]) withRoot(n *prbnode[T]) *PRbSet[T] {
+	s := &PRbSet[T]{cmpFunc: t.cmpFunc, fix: t.fix}
+	s.setRoot(n)
+	s.count = n.subSize()
+	return s
+}
+
+//join subtree l, node n and subtree r, whose items are in ascending order
+//lh and rh are black heights of l and r, which may be of any difference
+//return root and black height of the joined tree
+func (t *PRbSet[T]) join(l *prbnode[T], lh int, n *prbnode[T], r *prbnode[T], rh int) (*prbnode[T], int) {
+	//a red root can always be painted black
+	if l != nil && l.color == red {
+		l.color = black
+		lh++
+	}
+	if r != nil && r.color == red {
+		r.color = black
+		rh++
+	}
+	var root *prbnode[T]
+	switch {
+	case lh > rh:
+		root = t.joinRight(l, lh, n, r, rh)
+	case rh > lh:
+		root = t.joinLeft(l, lh, n, r, rh)
+	default:
+		n.color = red
+		return t.link(n, l, r), lh
+	}
+	h := max(lh, rh)
+	//red violation may be pushed up to root
+	if root.color == red {
+		root.color = black
+		h++
+	}
+	return root, h
+}
+
+//join n and r into right spine of l, black height of l is greater than r,
+//root of r is black
+func (t *PRbSet[T]) joinRight(l *prbnode[T], lh int, n *prbnode[T], r *prbnode[T], rh int) *prbnode[T] {
+	if (l == nil || l.color == black) && lh == rh {
+		n.color = red
+		return t.link(n, l, r)
+	}
+	if l.color == black {
+		lh--
+	}
+	c := t.joinRight(l.links[Right], lh, n, r, rh)
+	if l.color == black && c.color == red && c.links[Right] != nil && c.links[Right].color == red {
+		//rotate left at l
+		c.links[Right].color = black
+		return t.link(c, t.link(l, l.links[Left], c.links[Left]), c.links[Right])
+	}
+	return t.link(l, l.links[Left], c)
+}
+
+//join l and n into left spine of r, black height of r is greater than l,
+//root of l is black
+func (t *PRbSet[T]) joinLeft(l *prbnode[T], lh int, n *prbnode[T], r *prbnode[T], rh int) *prbnode[T] {
+	if (r == nil || r.color == black) && lh == rh {
+		n.color = red
+		return t.link(n, l, r)
+	}
+	if r.color == black {
+		rh--
+	}
+	c := t.joinLeft(l, lh, n, r.links[Left], rh)
+	if r.color == black && c.color == red && c.links[Left] != nil && c.links[Left].color == red {
+		//rotate right at r
+		c.links[Left].color = black
+		return t.link(c, c.links[Left], t.link(r, c.links[Right], r.links[Right]))
+	}
+	return t.link(r, c, r.links[Right])
+}
+
+//join subtree l and subtree r, every item in l is less than every item in r
+func (t *PRbSet[T]) concat(l *prbnode[T], lh int, r *prbnode[T], rh int) (*prbnode[T], int) {
+	if l == nil {
+		return r, rh
+	}
+	l, lh, n := t.splitLast(l, lh)
+	return t.join(l, lh, n, r, rh)
+}
+
+//unlink the greatest node of nonempty subtree n, whose black height is h
+//return the rest subtree with its black height and the greatest node
+func (t *PRbSet[T]) splitLast(n *prbnode[T], h int) (l *prbnode[T], lh int, last *prbnode[T]) {
+	a, b := n.links[Left], n.links[Right]
+	if n.color == black {
+		h--
+	}
+	if b == nil {
+		return a, h, n
+	}
+	l, lh, last = t.splitLast(b, h)
+	l, lh = t.join(a, h, n, l, lh)
+	return
+}
+
+//split subtree n, whose black height is h, into subtree less than item and subtree greater than item
+//eq is the node equal to item, or nil if there is no such node
+func (t *PRbSet[T]) split(n *prbnode[T], h int, item T) (l *prbnode[T], lh int, eq *prbnode[T], r *prbnode[T], rh int) {
+	if n == nil {
+		return
+	}
+	a, b := n.links[Left], n.links[Right]
+	if n.color == black {
+		h--
+	}
+	switch c := t.cmpFunc(item, n.data); {
+	case c < 0:
+		l, lh, eq, r, rh = t.split(a, h, item)
+		r, rh = t.join(r, rh, n, b, h)
+	case c > 0:
+		l, lh, eq, r, rh = t.split(b, h, item)
+		l, lh = t.join(a, h, n, l, lh)
+	default:
+		l, lh, eq, r, rh = a, h, n, b, h
+	}
+	return
+}
+
+//split subtree n, whose black height is h, into subtree of the first k nodes and subtree of the rest
+func (t *PRbSet[T]) splitAt(n *prbnode[T], h int, k int) (l *prbnode[T], lh int, r *prbnode[T], rh int) {
+	if n == nil {
+		return
+	}
+	a, b := n.links[Left], n.links[Right]
+	if n.color == black {
+		h--
+	}
+	if s := a.subSize(); k <= s {
+		l, lh, r, rh = t.splitAt(a, h, k)
+		r, rh = t.join(r, rh, n, b, h)
+	} else {
+		l, lh, r, rh = t.splitAt(b, h, k-s-1)
+		l, lh = t.join(a, h, n, l, lh)
+	}
+	return
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+//O(m log(n/m + 1)) for sizes m <= n, big trees are merged in parallel,
+//so compare function and resolve must be safe for concurrent use
+func (t *PRbSet[T]) Union(other *PRbSet[T], resolve func(a, b T) T) {
+	if t == nil || other == nil || other == t {
+		return
+	}
+	root, _ := t.union(t.root, t.root.blackHeight(), other.root, other.root.blackHeight(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *PRbSet[T]) Intersect(other *PRbSet[T], resolve func(a, b T) T) {
+	if t == nil || other == t {
+		return
+	}
+	if other == nil {
+		t.clear()
+		return
+	}
+	root, _ := t.intersect(t.root, t.root.blackHeight(), other.root, other.root.blackHeight(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//delete all items of other from t, other is emptied
+func (t *PRbSet[T]) Difference(other *PRbSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.difference(t.root, t.root.blackHeight(), other.root, other.root.blackHeight())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *PRbSet[T]) SymmetricDifference(other *PRbSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.symmetricDifference(t.root, t.root.blackHeight(), other.root, other.root.blackHeight())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//drop all items of t
+func (t *PRbSet[T]) clear() {
+	t.root = nil
+	t.count = 0
+	t.generation++
+}
+
+//union of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PRbSet[T]) union(a *prbnode[T], ah int, b *prbnode[T], bh int, resolve func(a, b T) T) (*prbnode[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *prbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.union(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.union(r1, rh1, r2, rh2, resolve)
+	})
+	if eq != nil && resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//intersection of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PRbSet[T]) intersect(a *prbnode[T], ah int, b *prbnode[T], bh int, resolve func(a, b T) T) (*prbnode[T], int) {
+	if a == nil || b == nil {
+		return nil, 0
+	}
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *prbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.intersect(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.intersect(r1, rh1, r2, rh2, resolve)
+	})
+	if eq == nil {
+		return t.concat(l, lh, r, rh)
+	}
+	if resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//subtree a without items of subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PRbSet[T]) difference(a *prbnode[T], ah int, b *prbnode[T], bh int) (*prbnode[T], int) {
+	if a == nil || b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l2, r2 := b.links[Left], b.links[Right]
+	lh2, rh2 := b.childHeights(bh)
+	l1, lh1, _, r1, rh1 := t.split(a, ah, b.data)
+	var (
+		l, r   *prbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.difference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.difference(r1, rh1, r2, rh2)
+	})
+	return t.concat(l, lh, r, rh)
+}
+
+//items in exactly one of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *PRbSet[T]) symmetricDifference(a *prbnode[T], ah int, b *prbnode[T], bh int) (*prbnode[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *prbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.symmetricDifference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.symmetricDifference(r1, rh1, r2, rh2)
+	})
+	if eq != nil {
+		return t.concat(l, lh, r, rh)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+func (t *PRbSet[T]) Copy() *PRbSet[T] {
 	if t == nil {
 		return nil
 	}
-	n := NewPRbTree(t.cmpFunc, t.extraParam)
+	n := NewPRbSet(t.cmpFunc)
 	if n == nil {
 		return nil
 	}
 	n.count = t.count
+	n.iterPolicy = t.iterPolicy
+	n.codec = t.codec
+	n.fix = t.fix
 	if n.count == 0 {
 		return n
 	}
 	var (
-		x *prbnode
-		y *prbnode
+		x *prbnode[T]
+		y *prbnode[T]
 	)
-	x = (*prbnode)(unsafe.Pointer(&t.root))
-	y = (*prbnode)(unsafe.Pointer(&n.root))
+	//header nodes stand for parents of roots
+	var hx, hy prbnode[T]
+	hx.links[Left] = t.root
+	x, y = &hx, &hy
 	for {
 		for x.links[Left] != nil {
-			y.links[Left] = &prbnode{}
+			y.links[Left] = &prbnode[T]{}
 			y.links[Left].parent = y
 			x = x.links[Left]
 			y = y.links[Left]
@@ -459,8 +1212,9 @@ func (t *PRbTree) Copy() *PRbTree {
 		for {
 			y.data = x.data
 			y.color = x.color
+			y.size = x.size
 			if x.links[Right] != nil {
-				y.links[Right] = &prbnode{}
+				y.links[Right] = &prbnode[T]{}
 				y.links[Right].parent = y
 				x = x.links[Right]
 				y = y.links[Right]
@@ -472,6 +1226,7 @@ func (t *PRbTree) Copy() *PRbTree {
 				w := x
 				x = x.parent
 				if x == nil {
+					n.root = hy.links[Left]
 					n.root.parent = nil
 					return n
 				}
@@ -484,72 +1239,181 @@ func (t *PRbTree) Copy() *PRbTree {
 	}
 }
 
-func (t *PRbTree) Iter() Iterator {
-	it := NewPRbIter()
+func (t *PRbSet[T]) Iter() IteratorOf[T] {
+	it := NewPRbSetIter[T]()
 	return it.HookWith(t)
 }
 
-type PRbIter struct {
-	tree *PRbTree //the tree be iterated
-	node *prbnode //current node in tree
+//set policy of iterators hooked with tree from now on, see IterPolicy
+func (t *PRbSet[T]) SetIterPolicy(p IterPolicy) {
+	if t == nil {
+		return
+	}
+	t.iterPolicy = p
+}
+
+//set codec of items used by MarshalBinary and UnmarshalBinary
+func (t *PRbSet[T]) SetCodec(c Codec[T]) {
+	if t == nil {
+		return
+	}
+	t.codec = c
 }
 
-func NewPRbIter() *PRbIter {
-	return &PRbIter{}
+//encode items of t in ascending order along with the shape of tree,
+//implements encoding.BinaryMarshaler
+func (t *PRbSet[T]) MarshalBinary() ([]byte, error) {
+	if t == nil {
+		return nil, ErrNoCodec
+	}
+	return marshalTree(encPRb, t.root, t.count, t.codec)
 }
 
-func (it *PRbIter) HookWith(tree *PRbTree) *PRbIter {
+//replace items of t with the tree encoded by MarshalBinary of a PRbSet,
+//the same tree is rebuilt in O(n) without comparisons,
+//so compare function of t must order items as the encoding tree did
+//implements encoding.BinaryUnmarshaler
+func (t *PRbSet[T]) UnmarshalBinary(data []byte) error {
+	if t == nil {
+		return ErrNoCodec
+	}
+	root, count, err := unmarshalTree(encPRb, data, t.codec, func(c byte, l *prbnode[T], item T, r *prbnode[T]) *prbnode[T] {
+		n := &prbnode[T]{links: [ChildNum]*prbnode[T]{l, r}, data: item, color: c}
+		if l != nil {
+			l.parent = n
+		}
+		if r != nil {
+			r.parent = n
+		}
+		t.update(n)
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	t.root = root
+	t.count = count
+	t.generation++
+	return nil
+}
+
+func (t *PRbSet[T]) seekIter() seekIterOf[T] {
+	return NewPRbSetIter[T]().HookWith(t)
+}
+
+//sequence of all items in ascending order
+func (t *PRbSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAll(t.seekIter)
+}
+
+//sequence of all items in descending order
+func (t *PRbSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBackward(t.seekIter)
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PRbSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAscend(t.seekIter, from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PRbSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqDescend(t.seekIter, from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PRbSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBetween(t.seekIter, t.cmpFunc, lo, hi)
+}
+
+type PRbSetIter[T any] struct {
+	tree       *PRbSet[T]  //the tree be iterated
+	node       *prbnode[T] //current node in tree
+	generation int         // generation number
+	policy     IterPolicy  //how to react to changes of tree
+}
+
+func NewPRbSetIter[T any]() *PRbSetIter[T] {
+	return &PRbSetIter[T]{}
+}
+
+func (it *PRbSetIter[T]) HookWith(tree *PRbSet[T]) *PRbSetIter[T] {
 	if it == nil {
 		return nil
 	}
+	it.policy = tree.iterPolicy
+	if it.policy == IterSnapshot {
+		tree = tree.Copy()
+	}
 	it.tree = tree
 	it.node = nil
+	it.generation = tree.generation
 	return it
 }
 
-func (it *PRbIter) First() Item {
+func (it *PRbSetIter[T]) First() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Left] != nil {
 		w = w.links[Left]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *PRbIter) Last() Item {
+func (it *PRbSetIter[T]) Last() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Right] != nil {
 		w = w.links[Right]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *PRbIter) Find(item Item) Item {
-	if it == nil || it.tree == nil || item == nil {
-		return nil
+func (it *PRbSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
 	}
+	it.generation = it.tree.generation
 	var (
-		w *prbnode //walk node
-		n *prbnode //child of w
+		w *prbnode[T] //walk node
+		n *prbnode[T] //child of w
 	)
 	for w = it.tree.root; w != nil; w = n {
-		cmp := it.tree.cmpFunc(item, w.data, it.tree.extraParam)
+		cmp := it.tree.cmpFunc(item, w.data)
 		if cmp == 0 {
 			it.node = w
-			return w.data
+			return w.data, true
 		}
 		if cmp < 0 {
 			n = w.links[Left]
@@ -558,12 +1422,80 @@ func (it *PRbIter) Find(item Item) Item {
 		}
 	}
 	it.node = nil
-	return nil
+	return
 }
 
-func (it *PRbIter) Next() Item {
+//move iterator to item at index k in sorted order
+//if k is out of range, iterator is moved to nil position
+func (it *PRbSetIter[T]) Seek(k int) (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
+	}
+	it.generation = it.tree.generation
+	it.node = nil
+	if k < 0 || k >= it.tree.count {
+		return
+	}
+	w := it.tree.root
+	for {
+		ls := w.links[Left].subSize()
+		if k < ls {
+			w = w.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			w = w.links[Right]
+		} else {
+			break
+		}
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move iterator to the nearest item to item in direction dir, see near
+func (it *PRbSetIter[T]) seekNear(item T, dir int, eq bool) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	it.generation = it.tree.generation
+	it.node = it.tree.near(item, dir, eq)
+	if it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//move iterator to the least item greater than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PRbSetIter[T]) SeekGE(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, true)
+}
+
+//move iterator to the least item greater than item
+//if there is no such item, iterator is moved to nil position
+func (it *PRbSetIter[T]) SeekGT(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, false)
+}
+
+//move iterator to the greatest item less than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *PRbSetIter[T]) SeekLE(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, true)
+}
+
+//move iterator to the greatest item less than item
+//if there is no such item, iterator is moved to nil position
+func (it *PRbSetIter[T]) SeekLT(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, false)
+}
+
+func (it *PRbSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Right, false)
 	}
 	w := it.node
 	if w == nil {
@@ -577,21 +1509,25 @@ func (it *PRbIter) Next() Item {
 		for p := w.parent; ; w, p = p, p.parent {
 			if p == nil {
 				it.node = p
-				return nil
+				return
 			}
 			if w == p.links[Left] {
 				it.node = p
-				return p.data
+				return p.data, true
 			}
 		}
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *PRbIter) Prev() Item {
+func (it *PRbSetIter[T]) Prev() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
+	}
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Left, false)
 	}
 	w := it.node
 	if w == nil {
@@ -605,52 +1541,134 @@ func (it *PRbIter) Prev() Item {
 		for p := w.parent; ; w, p = p, p.parent {
 			if p == nil {
 				it.node = p
-				return nil
+				return
 			}
 			if w == p.links[Right] {
 				it.node = p
-				return p.data
+				return p.data, true
 			}
 		}
 	}
 	it.node = w
-	return w.data
+	return w.data, true
+}
+
+//locate current item again after tree is changed
+//return false if current item is deleted, iterator is left alone then
+func (it *PRbSetIter[T]) refresh() bool {
+	n := it.tree.near(it.node.data, Right, true)
+	if n == nil || it.tree.cmpFunc(n.data, it.node.data) != 0 {
+		return false
+	}
+	it.node = n
+	it.generation = it.tree.generation
+	return true
 }
 
-func (it *PRbIter) Current() Item {
+//report whether tree is unchanged since iterator was positioned,
+//panic with ErrConcurrentModification if not and policy is IterFailFast
+func (it *PRbSetIter[T]) synced() bool {
+	if it.generation == it.tree.generation {
+		return true
+	}
+	if it.policy == IterFailFast {
+		panic(ErrConcurrentModification)
+	}
+	return false
+}
+
+func (it *PRbSetIter[T]) Current() (item T, ok bool) {
 	if it == nil || it.node == nil {
-		return nil
+		return
+	}
+	return it.node.data, true
+}
+
+//return index of current item in sorted order
+//return -1 if iterator is at nil position
+func (it *PRbSetIter[T]) Index() int {
+	if it == nil || it.tree == nil || it.node == nil {
+		return -1
+	}
+	if !it.synced() && !it.refresh() {
+		return -1
 	}
-	return it.node.data
+	idx := it.node.links[Left].subSize()
+	for n, p := it.node, it.node.parent; p != nil; n, p = p, p.parent {
+		if p.links[Right] == n {
+			idx += p.links[Left].subSize() + 1
+		}
+	}
+	return idx
 }
 
 //don't change key part of item
-func (it *PRbIter) Replace(new Item) Item {
-	if it == nil || it.node == nil || new == nil {
-		return nil
+func (it *PRbSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
 	}
 	old := it.node.data
 	it.node.data = new
-	return old
+	it.tree.fixUp(it.node)
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *PRbSetIter[T]) Delete() (item T, ok bool) {
+	return it.delete(Right)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *PRbSetIter[T]) DeletePrev() (item T, ok bool) {
+	return it.delete(Left)
 }
 
-func (it *PRbIter) CopyFrom(other *PRbIter) Item {
+//delete current item and move to its neighbour in direction dir,
+//the tree is changed by the iterator itself, so it stays valid
+func (it *PRbSetIter[T]) delete(dir int) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
+	}
+	w := it.node
+	if dir == Right {
+		item, ok = it.Next()
+	} else {
+		item, ok = it.Prev()
+	}
+	//nodes are relinked but never copied, so the neighbour stays in tree
+	it.tree.deleteNode(w)
+	it.generation = it.tree.generation
+	return
+}
+
+func (it *PRbSetIter[T]) CopyFrom(other *PRbSetIter[T]) (item T, ok bool) {
 	if it == nil || other == nil {
-		return nil
+		return
 	}
 	it.tree = other.tree
 	it.node = other.node
+	it.generation = other.generation
+	it.policy = other.policy
 	if it.node == nil {
-		return nil
+		return
 	}
-	return it.node.data
+	return it.node.data, true
 }
 
-func (it *PRbIter) Insert(item Item) (*Item, bool) {
-	if it == nil || it.tree == nil || item == nil {
+func (it *PRbSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
 		return nil, false
 	}
 	addr, ok := it.tree.insert(item)
-	it.node = (*prbnode)(unsafe.Pointer(uintptr(unsafe.Pointer(addr)) - unsafe.Offsetof(it.node.data)))
+	it.node = (*prbnode[T])(unsafe.Pointer(uintptr(unsafe.Pointer(addr)) - unsafe.Offsetof(it.node.data)))
+	it.generation = it.tree.generation
 	return addr, ok
 }
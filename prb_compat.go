@@ -0,0 +1,459 @@
+package bbst
+
+import (
+	"iter"
+	"slices"
+)
+
+//PRbTree is the interface{} flavour of PRbSet,
+//kept as a thin layer over PRbSet[Item] for existing callers
+type PRbTree struct {
+	PRbSet[Item]
+}
+
+func NewPRbTree(cmp Compare, extra interface{}) *PRbTree {
+	if cmp == nil {
+		return nil
+	}
+	return &PRbTree{PRbSet[Item]{cmpFunc: compareOf(cmp, extra)}}
+}
+
+//create a tree holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n)
+//return ErrNilItem if any item is nil,
+//ErrNotSorted if items are out of order or have duplicates
+func NewPRbTreeFromSorted(cmp Compare, extra interface{}, items []Item) (*PRbTree, error) {
+	t := NewPRbTree(cmp, extra)
+	if t == nil {
+		return nil, nil
+	}
+	if err := checkNil(items); err != nil {
+		return nil, err
+	}
+	if err := t.PRbSet.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *PRbTree) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.PRbSet.Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *PRbTree) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := t.PRbSet.Find(target)
+	return item
+}
+
+//return item at index k in sorted order, k counts from 0
+//return nil if k is out of range
+func (t *PRbTree) Select(k int) Item {
+	if t == nil {
+		return nil
+	}
+	item, _ := t.PRbSet.Select(k)
+	return item
+}
+
+//return number of items in tree less than item
+func (t *PRbTree) Rank(item Item) int {
+	if t == nil || item == nil {
+		return 0
+	}
+	return t.PRbSet.Rank(item)
+}
+
+//return the greatest item less than or equal to item
+//return nil if there is no such item
+func (t *PRbTree) Floor(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.PRbSet.Floor(item)
+	return found
+}
+
+//return the least item greater than or equal to item
+//return nil if there is no such item
+func (t *PRbTree) Ceiling(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.PRbSet.Ceiling(item)
+	return found
+}
+
+//return iterator at the first item not less than item
+func (t *PRbTree) LowerBound(item Item) Iterator {
+	it := NewPRbIter()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *PRbTree) UpperBound(item Item) Iterator {
+	it := NewPRbIter()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+func (t *PRbTree) insert(item Item) (*Item, bool) {
+	if t == nil || item == nil {
+		return nil, false
+	}
+	return t.PRbSet.insert(item)
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *PRbTree) Insert(item Item) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//insert all items in tree, nil items and items equal to one in tree
+//or to an earlier one in items are skipped
+//return number of inserted items and skipped non nil items in ascending order
+func (t *PRbTree) InsertBatch(items []Item) (inserted int, dups []Item) {
+	if t == nil {
+		return
+	}
+	return t.PRbSet.InsertBatch(slices.DeleteFunc(slices.Clone(items), func(item Item) bool {
+		return item == nil
+	}))
+}
+
+//replace item in tree with same key item
+//return old item
+func (t *PRbTree) Replace(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	old, _ := t.PRbSet.Replace(item)
+	return old
+}
+
+//delete item in tree
+//return item if find it
+//else  return nil
+func (t *PRbTree) Delete(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	deleted, _ := t.PRbSet.Delete(item)
+	return deleted
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *PRbTree) Range(lo, hi Item, fn func(item Item) bool) {
+	if t == nil || lo == nil || hi == nil {
+		return
+	}
+	t.PRbSet.Range(lo, hi, fn)
+}
+
+//delete all items in [lo, hi)
+//return number of deleted items
+func (t *PRbTree) DeleteRange(lo, hi Item) int {
+	if t == nil || lo == nil || hi == nil {
+		return 0
+	}
+	return t.PRbSet.DeleteRange(lo, hi)
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied
+//return false and change nothing if items are out of order
+func (t *PRbTree) Join(pivot Item, right *PRbTree) bool {
+	if t == nil || pivot == nil {
+		return false
+	}
+	if right == nil {
+		return t.PRbSet.Join(pivot, nil)
+	}
+	return t.PRbSet.Join(pivot, &right.PRbSet)
+}
+
+//split t into items less than item and items greater than item
+//eq is the item equal to item, or nil if there is no such item
+//t is emptied
+func (t *PRbTree) Split(item Item) (lt *PRbTree, eq Item, gt *PRbTree) {
+	if t == nil || item == nil {
+		return
+	}
+	l, eq, _, r := t.PRbSet.Split(item)
+	return &PRbTree{*l}, eq, &PRbTree{*r}
+}
+
+//split t into the first k items and the rest
+//t is emptied
+func (t *PRbTree) SplitAt(k int) (lt, ge *PRbTree) {
+	if t == nil {
+		return
+	}
+	l, r := t.PRbSet.SplitAt(k)
+	return &PRbTree{*l}, &PRbTree{*r}
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *PRbTree) Union(other *PRbTree, resolve func(a, b Item) Item) {
+	if t == nil || other == nil {
+		return
+	}
+	t.PRbSet.Union(&other.PRbSet, resolve)
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *PRbTree) Intersect(other *PRbTree, resolve func(a, b Item) Item) {
+	if t == nil {
+		return
+	}
+	if other == nil {
+		t.PRbSet.Intersect(nil, resolve)
+		return
+	}
+	t.PRbSet.Intersect(&other.PRbSet, resolve)
+}
+
+//delete all items of other from t, other is emptied
+func (t *PRbTree) Difference(other *PRbTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.PRbSet.Difference(&other.PRbSet)
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *PRbTree) SymmetricDifference(other *PRbTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.PRbSet.SymmetricDifference(&other.PRbSet)
+}
+
+func (t *PRbTree) Copy() *PRbTree {
+	if t == nil {
+		return nil
+	}
+	return &PRbTree{*t.PRbSet.Copy()}
+}
+
+//sequence of all items in ascending order
+func (t *PRbTree) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PRbSet.All()
+}
+
+//sequence of all items in descending order
+func (t *PRbTree) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.PRbSet.Backward()
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *PRbTree) Ascend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PRbSet.Ascend(from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *PRbTree) Descend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.PRbSet.Descend(from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *PRbTree) Between(lo, hi Item) iter.Seq[Item] {
+	if t == nil || lo == nil || hi == nil {
+		return empty[Item]
+	}
+	return t.PRbSet.Between(lo, hi)
+}
+
+func (t *PRbTree) Iter() Iterator {
+	it := NewPRbIter()
+	return it.HookWith(t)
+}
+
+type PRbIter struct {
+	PRbSetIter[Item]
+}
+
+func NewPRbIter() *PRbIter {
+	return &PRbIter{}
+}
+
+func (it *PRbIter) HookWith(tree *PRbTree) *PRbIter {
+	if it == nil {
+		return nil
+	}
+	it.PRbSetIter.HookWith(&tree.PRbSet)
+	return it
+}
+
+func (it *PRbIter) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.First()
+	return item
+}
+
+func (it *PRbIter) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.Last()
+	return item
+}
+
+func (it *PRbIter) Find(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PRbSetIter.Find(item)
+	return found
+}
+
+//move iterator to item at index k in sorted order
+func (it *PRbIter) Seek(k int) Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.Seek(k)
+	return item
+}
+
+//return index of current item, -1 at nil position
+func (it *PRbIter) Index() int {
+	if it == nil {
+		return -1
+	}
+	return it.PRbSetIter.Index()
+}
+
+//move iterator to the least item greater than or equal to item
+func (it *PRbIter) SeekGE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PRbSetIter.SeekGE(item)
+	return found
+}
+
+//move iterator to the least item greater than item
+func (it *PRbIter) SeekGT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PRbSetIter.SeekGT(item)
+	return found
+}
+
+//move iterator to the greatest item less than or equal to item
+func (it *PRbIter) SeekLE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PRbSetIter.SeekLE(item)
+	return found
+}
+
+//move iterator to the greatest item less than item
+func (it *PRbIter) SeekLT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.PRbSetIter.SeekLT(item)
+	return found
+}
+
+func (it *PRbIter) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.Next()
+	return item
+}
+
+func (it *PRbIter) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.Prev()
+	return item
+}
+
+func (it *PRbIter) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.Current()
+	return item
+}
+
+//don't change key part of item
+func (it *PRbIter) Replace(new Item) Item {
+	if it == nil || new == nil {
+		return nil
+	}
+	old, _ := it.PRbSetIter.Replace(new)
+	return old
+}
+
+//delete current item and move to its successor, return the successor
+func (it *PRbIter) Delete() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.Delete()
+	return item
+}
+
+//delete current item and move to its predecessor, return the predecessor
+func (it *PRbIter) DeletePrev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.DeletePrev()
+	return item
+}
+
+func (it *PRbIter) CopyFrom(other *PRbIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.PRbSetIter.CopyFrom(&other.PRbSetIter)
+	return item
+}
+
+func (it *PRbIter) Insert(item Item) (*Item, bool) {
+	if it == nil || item == nil {
+		return nil, false
+	}
+	return it.PRbSetIter.Insert(item)
+}
@@ -6,7 +6,7 @@ import (
 	"testing"
 )
 
-func (n *prbnode) print(lvl int) {
+func (n *prbnode[T]) print(lvl int) {
 	if n == nil {
 		return
 	}
@@ -26,7 +26,7 @@ func (n *prbnode) print(lvl int) {
 	}
 }
 
-func recurseVerifyPRbTree(t *testing.T, node *prbnode, ok *bool, count *int, min, max int, bh *int) {
+func recurseVerifyPRbTree(t *testing.T, node *prbnode[Item], ok *bool, count *int, min, max int, bh *int) {
 	var (
 		d        int           //data of tree node
 		subcount [ChildNum]int //count of subtree
@@ -50,6 +50,10 @@ func recurseVerifyPRbTree(t *testing.T, node *prbnode, ok *bool, count *int, min
 	recurseVerifyPRbTree(t, node.links[Right], ok, &subcount[Right], d+1, max, &subbh[Right])
 
 	*count = 1 + subcount[Left] + subcount[Right]
+	if node.size != *count {
+		t.Errorf("Node %d has size %d, but subtree has %d nodes.\n", d, node.size, *count)
+		*ok = false
+	}
 	h := 0
 	if node.color == black {
 		h = 1
@@ -240,17 +244,17 @@ func (it *PRbIter) check(t *testing.T, i, n int, title string) bool {
 	return ok
 }
 
-func comparePRbTrees(t *testing.T, a, b *prbnode) bool {
+func comparePRbTrees(t *testing.T, a, b *prbnode[Item]) bool {
 	if a == nil && b == nil {
 		return true
 	}
-	pf := func(n *prbnode) Item {
+	pf := func(n *prbnode[Item]) Item {
 		if n.parent != nil {
 			return n.parent.data
 		}
 		return -1
 	}
-	cf := func(n *prbnode, dir int) string {
+	cf := func(n *prbnode[Item], dir int) string {
 		if n.links[dir] != nil {
 			return "has"
 		}
@@ -261,7 +265,7 @@ func comparePRbTrees(t *testing.T, a, b *prbnode) bool {
 		((a.links[Right] != nil) != (b.links[Right] != nil)) ||
 		((a.parent != nil) != (b.parent != nil)) ||
 		(a.parent != nil && b.parent != nil && a.parent.data != b.parent.data) ||
-		a.color != b.color {
+		a.color != b.color || a.size != b.size {
 
 		t.Logf("Copied nodes differ:\n"+
 			"a=%d, color %d, parent %d, %s left child, %s right child\n"+
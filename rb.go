@@ -1,36 +1,104 @@
 package bbst
 
 import (
-	"unsafe"
+	"cmp"
+	"iter"
+	"math/bits"
 )
 
 const rbMaxHeight = 128
 
-type rbnode struct {
-	links [ChildNum]*rbnode //child node
-	data  Item              //data item
-	color byte              //node color
+type rbnode[T any] struct {
+	links [ChildNum]*rbnode[T] //child node
+	data  T                    //data item
+	color byte                 //node color
+	size  int                  //number of node in subtree
+	epoch uint64               //epoch of the tree owning node, see Snapshot
 }
 
-type RbTree struct {
-	root       *rbnode     //root of  tree
-	cmpFunc    Compare     //compare function
-	extraParam interface{} //extra param for cmpFunc
-	count      int         // number of item in tree
-	generation int         // generation number
+//number of node in subtree rooted at n
+func (n *rbnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+//recompute size of n from its children
+func (n *rbnode[T]) updateSize() {
+	n.size = n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+//address of data of n, nil if n is nil
+func (n *rbnode[T]) dataAddr() *T {
+	if n == nil {
+		return nil
+	}
+	return &n.data
+}
+
+//child of n in direction dir
+func (n *rbnode[T]) child(dir int) *rbnode[T] {
+	return n.links[dir]
+}
+
+//color bit of shape byte of n, see marshalTree
+func (n *rbnode[T]) shape() byte {
+	return n.color
+}
+
+//return n if it is owned by epoch, else a copy of n owned by epoch
+func (n *rbnode[T]) own(epoch uint64) *rbnode[T] {
+	if n == nil || n.epoch == epoch {
+		return n
+	}
+	c := *n
+	c.epoch = epoch
+	return &c
+}
+
+type RbSet[T any] struct {
+	root       *rbnode[T]       //root of  tree
+	cmpFunc    func(a, b T) int //compare function
+	count      int              // number of item in tree
+	generation int              // generation number
+	iterPolicy IterPolicy       //policy of iterators hooked with tree
+	codec      Codec[T]         //codec of items for MarshalBinary and UnmarshalBinary
+	epoch      uint64           //nodes of other epochs are shared, copy before write
+	fix        func(n, l, r *T) //recompute augmented data of item n from children l and r, nil if tree is not augmented
 }
 
-func NewRbTree(cmp Compare, extra interface{}) *RbTree {
+func NewRbSet[T any](cmp func(a, b T) int) *RbSet[T] {
 	if cmp == nil {
 		return nil
 	}
-	return &RbTree{
-		cmpFunc:    cmp,
-		extraParam: extra,
+	return &RbSet[T]{
+		cmpFunc: cmp,
+		epoch:   newEpoch(),
 	}
 }
 
-func (t *RbTree) Count() int {
+//create a set holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n) without rebalancing
+//return ErrNotSorted if items are out of order or have duplicates
+func NewRbSetFromSorted[T any](cmp func(a, b T) int, items []T) (*RbSet[T], error) {
+	t := NewRbSet(cmp)
+	if t == nil {
+		return nil, nil
+	}
+	if err := t.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedRbSet[T cmp.Ordered]() *RbSet[T] {
+	return NewRbSet(cmp.Compare[T])
+}
+
+func (t *RbSet[T]) Count() int {
 	if t == nil {
 		return 0
 	}
@@ -39,41 +107,241 @@ func (t *RbTree) Count() int {
 
 //search target in tree
 //if find it return item
-//else return nil
-func (t *RbTree) Find(target Item) Item {
-	if t == nil || target == nil {
-		return nil
+//else ok is false
+func (t *RbSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
 	}
 	for w := t.root; w != nil; {
-		ret := t.cmpFunc(target, w.data, t.extraParam)
+		ret := t.cmpFunc(target, w.data)
 		if ret < 0 {
 			w = w.links[Left]
 		} else if ret > 0 {
 			w = w.links[Right]
 		} else {
-			return w.data
+			return w.data, true
 		}
 	}
+	return
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *RbSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil || k < 0 || k >= t.count {
+		return
+	}
+	w := t.root
+	for {
+		ls := w.links[Left].subSize()
+		if k < ls {
+			w = w.links[Left]
+		} else if k > ls {
+			k -= ls + 1
+			w = w.links[Right]
+		} else {
+			return w.data, true
+		}
+	}
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *RbSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
+	}
+	r := 0
+	for w := t.root; w != nil; {
+		ret := t.cmpFunc(item, w.data)
+		if ret < 0 {
+			w = w.links[Left]
+		} else if ret > 0 {
+			r += w.links[Left].subSize() + 1
+			w = w.links[Right]
+		} else {
+			return r + w.links[Left].subSize()
+		}
+	}
+	return r
+}
+
+//return the nearest node to item in direction dir,
+//dir Right looks for the least node greater than item,
+//dir Left looks for the greatest node less than item,
+//node equal to item is accepted if eq is true
+func (t *RbSet[T]) near(item T, dir int, eq bool) *rbnode[T] {
+	var c *rbnode[T] //candidate node
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			return w
+		}
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	return c
+}
+
+//return the greatest item less than or equal to item
+//ok is false if there is no such item
+func (t *RbSet[T]) Floor(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Left, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return the least item greater than or equal to item
+//ok is false if there is no such item
+func (t *RbSet[T]) Ceiling(item T) (found T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.near(item, Right, true); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return iterator at the first item not less than item
+func (t *RbSet[T]) LowerBound(item T) IteratorOf[T] {
+	it := NewRbSetIter[T]()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *RbSet[T]) UpperBound(item T) IteratorOf[T] {
+	it := NewRbSetIter[T]()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *RbSet[T]) Range(lo, hi T, fn func(item T) bool) {
+	if t == nil || fn == nil {
+		return
+	}
+	var it RbSetIter[T]
+	for item, ok := it.HookWith(t).SeekGE(lo); ok && t.cmpFunc(item, hi) < 0; item, ok = it.Next() {
+		if !fn(item) {
+			return
+		}
+	}
+}
+
+//recompute size and augmented data of n from its children
+func (t *RbSet[T]) update(n *rbnode[T]) {
+	n.updateSize()
+	if t.fix != nil {
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of nodes in path, which goes from top to bottom
+func (t *RbSet[T]) fixPath(path []*rbnode[T]) {
+	if t.fix == nil {
+		return
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		t.fix(&n.data, n.links[Left].dataAddr(), n.links[Right].dataAddr())
+	}
+}
+
+//recompute augmented data of nodes on the path from root to item,
+//after item was changed in place
+func (t *RbSet[T]) fixKey(item T) {
+	if t.fix == nil {
+		return
+	}
+	var (
+		pa  [rbMaxHeight]*rbnode[T]
+		h   int
+		dir = Left
+	)
+	head := rbnode[T]{links: [ChildNum]*rbnode[T]{Left: t.root}}
+	for w := t.ownChild(&head, Left); w != nil; w = t.ownChild(w, dir) {
+		pa[h] = w
+		h++
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			break
+		}
+		if cmp > 0 {
+			dir = Right
+		} else {
+			dir = Left
+		}
+	}
+	t.root = head.links[Left]
+	t.fixPath(pa[:h])
+}
+
+//replace items of t with items, which are sorted in strictly ascending order
+func (t *RbSet[T]) loadSorted(items []T) error {
+	if err := checkSorted(t.cmpFunc, items); err != nil {
+		return err
+	}
+	t.root = t.build(items, 0, redDepth(len(items)))
+	t.count = len(items)
+	t.generation++
 	return nil
 }
 
-func (t *RbTree) insert(item Item) (*Item, bool) {
-	if t == nil || item == nil {
+//build a perfectly balanced subtree of items at depth d, return its root
+//all leaves are at the last two levels, nodes at depth rd are painted red,
+//so every path has the same number of black nodes
+func (t *RbSet[T]) build(items []T, d, rd int) *rbnode[T] {
+	if len(items) == 0 {
+		return nil
+	}
+	m := len(items) / 2
+	n := &rbnode[T]{data: items[m], epoch: t.epoch}
+	if d == rd {
+		n.color = red
+	}
+	return t.link(n, t.build(items[:m], d+1, rd), t.build(items[m+1:], d+1, rd))
+}
+
+//depth of red nodes in a perfectly balanced red black tree of n nodes,
+//nodes of the last level are red unless it is full, -1 if there is none
+func redDepth(n int) int {
+	if n&(n+1) == 0 {
+		return -1
+	}
+	return bits.Len(uint(n)) - 1
+}
+
+func (t *RbSet[T]) insert(item T) (*T, bool) {
+	if t == nil {
 		return nil, false
 	}
 	var (
-		pa [rbMaxHeight]*rbnode //stack of rbnode
-		da [rbMaxHeight]byte    //缓存的下降方向数组
-		k  int                  //length of da
-		w  *rbnode              //current walk node
-		n  *rbnode              //new node
+		pa [rbMaxHeight]*rbnode[T] //stack of rbnode
+		da [rbMaxHeight]byte       //缓存的下降方向数组
+		k  int                     //length of da
+		w  *rbnode[T]              //current walk node
+		n  *rbnode[T]              //new node
 	)
-	pa[0] = (*rbnode)(unsafe.Pointer(&t.root))
+	//header node stands for parent of root, its left link is root
+	head := rbnode[T]{links: [ChildNum]*rbnode[T]{Left: t.root}}
+	pa[0] = &head
 	da[0] = Left
 	k = 1
-	for w = t.root; w != nil; w = w.links[da[k-1]] {
-		cmp := t.cmpFunc(item, w.data, t.extraParam)
+	for w = t.ownChild(pa[0], Left); w != nil; w = t.ownChild(w, int(da[k-1])) {
+		cmp := t.cmpFunc(item, w.data)
 		if cmp == 0 {
+			t.root = head.links[Left]
 			return &w.data, false
 		}
 		pa[k] = w
@@ -84,9 +352,17 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 		da[k] = byte(dir)
 		k++
 	}
-	n = &rbnode{data: item, color: red}
+	n = &rbnode[T]{data: item, color: red, size: 1, epoch: t.epoch}
 	pa[k-1].links[da[k-1]] = n
 	t.count++
+	//pa[0] is header node standing for parent of root, skip it
+	for i := 1; i < k; i++ {
+		pa[i].size++
+	}
+	if t.fix != nil {
+		t.fix(&n.data, nil, nil)
+		t.fixPath(pa[1:k])
+	}
 	t.generation++
 	for k >= 3 && pa[k-1].color == red {
 		if da[k-2] == Left {
@@ -100,12 +376,13 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 			*/
 			y := pa[k-2].links[Right]
 			if y != nil && y.color == red {
+				y = t.ownChild(pa[k-2], Right)
 				pa[k-1].color = black
 				y.color = black
 				pa[k-2].color = red
 				k -= 2
 			} else {
-				var x *rbnode
+				var x *rbnode[T]
 				/*
 				 case 2, node n is left child of pa[k-1]
 				 pa[k-2]|x (black)                      y(black)
@@ -129,6 +406,8 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 					y = x.links[Right]
 					x.links[Right] = y.links[Left]
 					y.links[Left] = x
+					t.update(x)
+					t.update(y)
 					pa[k-2].links[Left] = y
 				}
 				x = pa[k-2]
@@ -136,18 +415,21 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 				y.color = black
 				x.links[Left] = y.links[Right]
 				y.links[Right] = x
+				t.update(x)
+				t.update(y)
 				pa[k-3].links[da[k-3]] = y
 				break
 			}
 		} else {
 			y := pa[k-2].links[Left]
 			if y != nil && y.color == red {
+				y = t.ownChild(pa[k-2], Left)
 				pa[k-1].color = black
 				y.color = black
 				pa[k-2].color = red
 				k -= 2
 			} else {
-				var x *rbnode
+				var x *rbnode[T]
 				if da[k-1] == Right {
 					y = pa[k-1]
 				} else {
@@ -155,6 +437,8 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 					y = x.links[Left]
 					x.links[Left] = y.links[Right]
 					y.links[Right] = x
+					t.update(x)
+					t.update(y)
 					pa[k-2].links[Right] = y
 				}
 				x = pa[k-2]
@@ -162,11 +446,14 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 				y.color = black
 				x.links[Right] = y.links[Left]
 				y.links[Left] = x
+				t.update(x)
+				t.update(y)
 				pa[k-3].links[da[k-3]] = y
 				break
 			}
 		}
 	}
+	t.root = head.links[Left]
 	t.root.color = black
 	return &n.data, true
 }
@@ -174,38 +461,51 @@ func (t *RbTree) insert(item Item) (*Item, bool) {
 //insert item in tree
 //return true if item was successfully inserted
 //return false if item already in tree
-func (t *RbTree) Insert(item Item) bool {
+func (t *RbSet[T]) Insert(item T) bool {
 	_, succ := t.insert(item)
 	return succ
 }
 
+//insert all items in tree, items equal to one in tree or to an earlier one in items are skipped
+//the batch is sorted and merged into tree, O(m log(n/m + 1)) for m items in a tree of n items
+//return number of inserted items and skipped items in ascending order
+func (t *RbSet[T]) InsertBatch(items []T) (inserted int, dups []T) {
+	if t == nil {
+		return
+	}
+	return insertBatch(t, t.cmpFunc, items)
+}
+
 //replace item in tree with same key item
-func (t *RbTree) Replace(item Item) Item {
+//insert item if no such key, ok is false in that case
+func (t *RbSet[T]) Replace(item T) (old T, ok bool) {
 	addr, succ := t.insert(item)
 	if addr == nil || succ {
-		return nil
+		return
 	}
 	r := *addr
 	*addr = item
-	return r
+	t.fixKey(item)
+	return r, true
 }
 
 //delete item in tree
 //return item if find it
-//else  return nil
-func (t *RbTree) Delete(item Item) Item {
-	if t == nil || item == nil {
-		return nil
+//else ok is false
+func (t *RbSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
 	}
 	var (
-		pa  [rbMaxHeight]*rbnode //stack of rbnode
-		da  [rbMaxHeight]byte    //缓存的下降方向数组
-		k   int                  //length of da
-		w   *rbnode              //current walk node
+		pa  [rbMaxHeight]*rbnode[T] //stack of rbnode
+		da  [rbMaxHeight]byte       //缓存的下降方向数组
+		k   int                     //length of da
+		w   *rbnode[T]              //current walk node
 		cmp int
 	)
-	w = (*rbnode)(unsafe.Pointer(&t.root))
-	for cmp = -1; cmp != 0; cmp = t.cmpFunc(item, w.data, t.extraParam) {
+	head := rbnode[T]{links: [ChildNum]*rbnode[T]{Left: t.root}}
+	w = &head
+	for cmp = -1; cmp != 0; cmp = t.cmpFunc(item, w.data) {
 		dir := Left
 		if cmp > 0 {
 			dir = Right
@@ -213,32 +513,45 @@ func (t *RbTree) Delete(item Item) Item {
 		pa[k] = w
 		da[k] = byte(dir)
 		k++
-		w = w.links[dir]
+		w = t.ownChild(w, dir)
 		if w == nil {
-			return nil
+			t.root = head.links[Left]
+			return
 		}
 	}
+	deleted, _ = t.deleteAt(&pa, &da, k)
+	return deleted, true
+}
+
+//delete the node at pa[k-1].links[da[k-1]], pa[0] is a header node whose left link is root,
+//all nodes on the path must be owned by t already
+//return deleted item and the least level of pa where a rotation happened,
+//nodes in pa above it keep their place, rbMaxHeight if no rotation at all
+func (t *RbSet[T]) deleteAt(pa *[rbMaxHeight]*rbnode[T], da *[rbMaxHeight]byte, k int) (deleted T, rot int) {
+	rot = rbMaxHeight
+	w := pa[k-1].links[da[k-1]]
 	ret := w.data
 	if w.links[Right] == nil { //case 1, node to delete has no right child
 		pa[k-1].links[da[k-1]] = w.links[Left]
 	} else {
-		r := w.links[Right]
+		r := t.ownChild(w, Right)
 		if r.links[Left] == nil { //case 2, node to delete w's right child has no left child
 			r.links[Left] = w.links[Left]
 			r.color, w.color = w.color, r.color //swap color
-			pa[k-1].links[da[k-1]] = r          // hook w's right subtree with w's parent
+			r.size = w.size
+			pa[k-1].links[da[k-1]] = r // hook w's right subtree with w's parent
 			da[k] = Right
 			pa[k] = r
 			k++
 		} else { //case 3, node to delete w's right child has left child
-			var s *rbnode //w's successor
+			var s *rbnode[T] //w's successor
 			j := k
 			k++
 			for {
 				da[k] = Left
 				pa[k] = r
 				k++
-				s = r.links[Left]
+				s = t.ownChild(r, Left)
 				if s.links[Left] == nil {
 					break
 				}
@@ -254,12 +567,18 @@ func (t *RbTree) Delete(item Item) Item {
 			r.links[Left] = s.links[Right]
 			s.links[Right] = w.links[Right]
 			s.color, w.color = w.color, s.color
+			s.size = w.size
 		}
 	}
+	for i := 1; i < k; i++ {
+		pa[i].size--
+	}
+	t.fixPath(pa[1:k])
 	if w.color == black {
 		for {
 			x := pa[k-1].links[da[k-1]]
 			if x != nil && x.color == red {
+				x = t.ownChild(pa[k-1], int(da[k-1]))
 				x.color = black
 				break
 			}
@@ -268,75 +587,91 @@ func (t *RbTree) Delete(item Item) Item {
 			}
 			if da[k-1] == Left {
 				//node x's sibling
-				s := pa[k-1].links[Right]
+				s := t.ownChild(pa[k-1], Right)
 				if s.color == red {
+					rot = k - 1
 					s.color = black
 					pa[k-1].color = red
 					pa[k-1].links[Right] = s.links[Left]
 					s.links[Left] = pa[k-1]
+					t.update(pa[k-1])
+					t.update(s)
 					pa[k-2].links[da[k-2]] = s
 					pa[k] = pa[k-1]
 					da[k] = Left
 					pa[k-1] = s
 					k++
-					s = pa[k-1].links[Right]
+					s = t.ownChild(pa[k-1], Right)
 				}
 				if (s.links[Left] == nil || s.links[Left].color == black) &&
 					(s.links[Right] == nil || s.links[Right].color == black) {
 					s.color = red
 				} else {
+					rot = min(rot, k-1)
 					if s.links[Right] == nil || s.links[Right].color == black {
-						y := s.links[Left]
+						y := t.ownChild(s, Left)
 						y.color = black
 						s.color = red
 						s.links[Left] = y.links[Right]
 						y.links[Right] = s
+						t.update(s)
+						t.update(y)
 						pa[k-1].links[Right] = y
 						s = y
 					}
 					s.color = pa[k-1].color
 					pa[k-1].color = black
-					s.links[Right].color = black
+					t.ownChild(s, Right).color = black
 
 					pa[k-1].links[Right] = s.links[Left]
 					s.links[Left] = pa[k-1]
+					t.update(pa[k-1])
+					t.update(s)
 					pa[k-2].links[da[k-2]] = s
 					break
 				}
 			} else {
 				//node x's sibling
-				s := pa[k-1].links[Left]
+				s := t.ownChild(pa[k-1], Left)
 				if s.color == red {
+					rot = k - 1
 					s.color = black
 					pa[k-1].color = red
 					pa[k-1].links[Left] = s.links[Right]
 					s.links[Right] = pa[k-1]
+					t.update(pa[k-1])
+					t.update(s)
 					pa[k-2].links[da[k-2]] = s
 					pa[k] = pa[k-1]
 					da[k] = Right
 					pa[k-1] = s
 					k++
-					s = pa[k-1].links[Left]
+					s = t.ownChild(pa[k-1], Left)
 				}
 				if (s.links[Left] == nil || s.links[Left].color == black) &&
 					(s.links[Right] == nil || s.links[Right].color == black) {
 					s.color = red
 				} else {
+					rot = min(rot, k-1)
 					if s.links[Left] == nil || s.links[Left].color == black {
-						y := s.links[Right]
+						y := t.ownChild(s, Right)
 						y.color = black
 						s.color = red
 						s.links[Right] = y.links[Left]
 						y.links[Left] = s
+						t.update(s)
+						t.update(y)
 						pa[k-1].links[Left] = y
 						s = y
 					}
 					s.color = pa[k-1].color
 					pa[k-1].color = black
-					s.links[Left].color = black
+					t.ownChild(s, Left).color = black
 
 					pa[k-1].links[Left] = s.links[Right]
 					s.links[Right] = pa[k-1]
+					t.update(pa[k-1])
+					t.update(s)
 					pa[k-2].links[da[k-2]] = s
 					break
 				}
@@ -345,34 +680,508 @@ func (t *RbTree) Delete(item Item) Item {
 		}
 	}
 	w = nil
+	t.root = pa[0].links[Left]
 	t.count--
 	t.generation++
-	return ret
+	return ret, rot
+}
+
+//delete all items in [lo, hi)
+//the range is split out of tree and the rest joined back, O(log n)
+//return number of deleted items
+func (t *RbSet[T]) DeleteRange(lo, hi T) int {
+	if t == nil || t.cmpFunc(lo, hi) >= 0 {
+		return 0
+	}
+	i, j := t.Rank(lo), t.Rank(hi)
+	if i == j {
+		return 0
+	}
+	l, lh, r, rh := t.splitAt(t.root, t.root.blackHeight(), i)
+	_, _, r, rh = t.splitAt(r, rh, j-i)
+	root, _ := t.concat(l, lh, r, rh)
+	t.setRoot(root)
+	t.count -= j - i
+	t.generation++
+	return j - i
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied, O(log n)
+//return false and change nothing if items are out of order
+func (t *RbSet[T]) Join(pivot T, right *RbSet[T]) bool {
+	if t == nil || right == t {
+		return false
+	}
+	if n := t.root.extreme(Right); n != nil && t.cmpFunc(n.data, pivot) >= 0 {
+		return false
+	}
+	var (
+		r     *rbnode[T]
+		count int
+	)
+	if right != nil {
+		r, count = right.root, right.count
+	}
+	if n := r.extreme(Left); n != nil && t.cmpFunc(pivot, n.data) >= 0 {
+		return false
+	}
+	root, _ := t.join(t.root, t.root.blackHeight(), &rbnode[T]{data: pivot, epoch: t.epoch}, r, r.blackHeight())
+	t.setRoot(root)
+	t.count += count + 1
+	t.generation++
+	if right != nil {
+		right.clear()
+	}
+	return true
+}
+
+//split t into items less than item and items greater than item, O(log n)
+//eq is the item equal to item, found is false if there is no such item
+//t is emptied
+func (t *RbSet[T]) Split(item T) (lt *RbSet[T], eq T, found bool, gt *RbSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, e, r, _ := t.split(t.root, t.root.blackHeight(), item)
+	if e != nil {
+		eq, found = e.data, true
+	}
+	lt, gt = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//split t into the first k items and the rest, O(log n)
+//t is emptied
+func (t *RbSet[T]) SplitAt(k int) (lt, ge *RbSet[T]) {
+	if t == nil {
+		return
+	}
+	l, _, r, _ := t.splitAt(t.root, t.root.blackHeight(), k)
+	lt, ge = t.withRoot(l), t.withRoot(r)
+	t.clear()
+	return
+}
+
+//number of black node on the path from n down to nil
+func (n *rbnode[T]) blackHeight() int {
+	h := 0
+	for ; n != nil; n = n.links[Left] {
+		if n.color == black {
+			h++
+		}
+	}
+	return h
+}
+
+//black heights of left and right subtree of n, h is black height of n
+func (n *rbnode[T]) childHeights(h int) (lh, rh int) {
+	if n.color == black {
+		h--
+	}
+	return h, h
+}
+
+//the last node of subtree rooted at n in direction dir
+func (n *rbnode[T]) extreme(dir int) *rbnode[T] {
+	for n != nil && n.links[dir] != nil {
+		n = n.links[dir]
+	}
+	return n
+}
+
+//make l and r children of n
+func (t *RbSet[T]) link(n, l, r *rbnode[T]) *rbnode[T] {
+	n.links[Left] = l
+	n.links[Right] = r
+	t.update(n)
+	return n
+}
+
+//set root of t to n, a red root is painted black
+func (t *RbSet[T]) setRoot(n *rbnode[T]) {
+	if n != nil && n.color == red {
+		n = n.own(t.epoch)
+		n.color = black
+	}
+	t.root = n
+}
+
+//make an empty tree with the same order, augmentation and settings as t
+func (t *RbSet[T]) emptyLike() *RbSet[T] {
+	s := t.withRoot(nil)
+	s.iterPolicy = t.iterPolicy
+	s.codec = t.codec
+	return s
+}
+
+//make a tree with the same order as t rooted at n
+func (t *RbSet[T]) withRoot(n *rbnode[T]) *RbSet[T] {
+	s := &RbSet[T]{cmpFunc: t.cmpFunc, epoch: newEpoch(), fix: t.fix}
+	s.setRoot(n)
+	s.count = n.subSize()
+	return s
+}
+
+//join subtree l, node n and subtree r, whose items are in ascending order
+//lh and rh are black heights of l and r, which may be of any difference
+//return root and black height of the joined tree
+func (t *RbSet[T]) join(l *rbnode[T], lh int, n *rbnode[T], r *rbnode[T], rh int) (*rbnode[T], int) {
+	//a red root can always be painted black
+	if l != nil && l.color == red {
+		l = l.own(t.epoch)
+		l.color = black
+		lh++
+	}
+	if r != nil && r.color == red {
+		r = r.own(t.epoch)
+		r.color = black
+		rh++
+	}
+	var root *rbnode[T]
+	switch {
+	case lh > rh:
+		root = t.joinRight(l, lh, n, r, rh)
+	case rh > lh:
+		root = t.joinLeft(l, lh, n, r, rh)
+	default:
+		n.color = red
+		return t.link(n, l, r), lh
+	}
+	h := max(lh, rh)
+	//red violation may be pushed up to root
+	if root.color == red {
+		root.color = black
+		h++
+	}
+	return root, h
+}
+
+//join n and r into right spine of l, black height of l is greater than r,
+//root of r is black
+func (t *RbSet[T]) joinRight(l *rbnode[T], lh int, n *rbnode[T], r *rbnode[T], rh int) *rbnode[T] {
+	if (l == nil || l.color == black) && lh == rh {
+		n.color = red
+		return t.link(n, l, r)
+	}
+	if l.color == black {
+		lh--
+	}
+	l = l.own(t.epoch)
+	c := t.joinRight(l.links[Right], lh, n, r, rh)
+	if l.color == black && c.color == red && c.links[Right] != nil && c.links[Right].color == red {
+		//rotate left at l
+		cr := c.links[Right].own(t.epoch)
+		cr.color = black
+		return t.link(c, t.link(l, l.links[Left], c.links[Left]), cr)
+	}
+	return t.link(l, l.links[Left], c)
+}
+
+//join l and n into left spine of r, black height of r is greater than l,
+//root of l is black
+func (t *RbSet[T]) joinLeft(l *rbnode[T], lh int, n *rbnode[T], r *rbnode[T], rh int) *rbnode[T] {
+	if (r == nil || r.color == black) && lh == rh {
+		n.color = red
+		return t.link(n, l, r)
+	}
+	if r.color == black {
+		rh--
+	}
+	r = r.own(t.epoch)
+	c := t.joinLeft(l, lh, n, r.links[Left], rh)
+	if r.color == black && c.color == red && c.links[Left] != nil && c.links[Left].color == red {
+		//rotate right at r
+		cl := c.links[Left].own(t.epoch)
+		cl.color = black
+		return t.link(c, cl, t.link(r, c.links[Right], r.links[Right]))
+	}
+	return t.link(r, c, r.links[Right])
+}
+
+//join subtree l and subtree r, every item in l is less than every item in r
+func (t *RbSet[T]) concat(l *rbnode[T], lh int, r *rbnode[T], rh int) (*rbnode[T], int) {
+	if l == nil {
+		return r, rh
+	}
+	l, lh, n := t.splitLast(l, lh)
+	return t.join(l, lh, n, r, rh)
+}
+
+//unlink the greatest node of nonempty subtree n, whose black height is h
+//return the rest subtree with its black height and the greatest node
+func (t *RbSet[T]) splitLast(n *rbnode[T], h int) (l *rbnode[T], lh int, last *rbnode[T]) {
+	n = n.own(t.epoch)
+	a, b := n.links[Left], n.links[Right]
+	if n.color == black {
+		h--
+	}
+	if b == nil {
+		return a, h, n
+	}
+	l, lh, last = t.splitLast(b, h)
+	l, lh = t.join(a, h, n, l, lh)
+	return
+}
+
+//split subtree n, whose black height is h, into subtree less than item and subtree greater than item
+//eq is the node equal to item, or nil if there is no such node
+func (t *RbSet[T]) split(n *rbnode[T], h int, item T) (l *rbnode[T], lh int, eq *rbnode[T], r *rbnode[T], rh int) {
+	if n == nil {
+		return
+	}
+	n = n.own(t.epoch)
+	a, b := n.links[Left], n.links[Right]
+	if n.color == black {
+		h--
+	}
+	switch c := t.cmpFunc(item, n.data); {
+	case c < 0:
+		l, lh, eq, r, rh = t.split(a, h, item)
+		r, rh = t.join(r, rh, n, b, h)
+	case c > 0:
+		l, lh, eq, r, rh = t.split(b, h, item)
+		l, lh = t.join(a, h, n, l, lh)
+	default:
+		l, lh, eq, r, rh = a, h, n, b, h
+	}
+	return
+}
+
+//split subtree n, whose black height is h, into subtree of the first k nodes and subtree of the rest
+func (t *RbSet[T]) splitAt(n *rbnode[T], h int, k int) (l *rbnode[T], lh int, r *rbnode[T], rh int) {
+	if n == nil {
+		return
+	}
+	n = n.own(t.epoch)
+	a, b := n.links[Left], n.links[Right]
+	if n.color == black {
+		h--
+	}
+	if s := a.subSize(); k <= s {
+		l, lh, r, rh = t.splitAt(a, h, k)
+		r, rh = t.join(r, rh, n, b, h)
+	} else {
+		l, lh, r, rh = t.splitAt(b, h, k-s-1)
+		l, lh = t.join(a, h, n, l, lh)
+	}
+	return
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+//O(m log(n/m + 1)) for sizes m <= n, big trees are merged in parallel,
+//so compare function and resolve must be safe for concurrent use
+func (t *RbSet[T]) Union(other *RbSet[T], resolve func(a, b T) T) {
+	if t == nil || other == nil || other == t {
+		return
+	}
+	root, _ := t.union(t.root, t.root.blackHeight(), other.root, other.root.blackHeight(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *RbSet[T]) Intersect(other *RbSet[T], resolve func(a, b T) T) {
+	if t == nil || other == t {
+		return
+	}
+	if other == nil {
+		t.clear()
+		return
+	}
+	root, _ := t.intersect(t.root, t.root.blackHeight(), other.root, other.root.blackHeight(), resolve)
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//delete all items of other from t, other is emptied
+func (t *RbSet[T]) Difference(other *RbSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.difference(t.root, t.root.blackHeight(), other.root, other.root.blackHeight())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *RbSet[T]) SymmetricDifference(other *RbSet[T]) {
+	if t == nil || other == nil {
+		return
+	}
+	if other == t {
+		t.clear()
+		return
+	}
+	root, _ := t.symmetricDifference(t.root, t.root.blackHeight(), other.root, other.root.blackHeight())
+	t.setRoot(root)
+	t.count = root.subSize()
+	t.generation++
+	other.clear()
+}
+
+//drop all items of t
+//the nodes may live on in another tree, so t never writes them again
+func (t *RbSet[T]) clear() {
+	t.root = nil
+	t.count = 0
+	t.generation++
+	t.epoch = newEpoch()
+}
+
+//union of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *RbSet[T]) union(a *rbnode[T], ah int, b *rbnode[T], bh int, resolve func(a, b T) T) (*rbnode[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	a = a.own(t.epoch)
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *rbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.union(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.union(r1, rh1, r2, rh2, resolve)
+	})
+	if eq != nil && resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//intersection of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *RbSet[T]) intersect(a *rbnode[T], ah int, b *rbnode[T], bh int, resolve func(a, b T) T) (*rbnode[T], int) {
+	if a == nil || b == nil {
+		return nil, 0
+	}
+	a = a.own(t.epoch)
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *rbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.intersect(l1, lh1, l2, lh2, resolve)
+	}, func() {
+		r, rh = t.intersect(r1, rh1, r2, rh2, resolve)
+	})
+	if eq == nil {
+		return t.concat(l, lh, r, rh)
+	}
+	if resolve != nil {
+		a.data = resolve(a.data, eq.data)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+//subtree a without items of subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *RbSet[T]) difference(a *rbnode[T], ah int, b *rbnode[T], bh int) (*rbnode[T], int) {
+	if a == nil || b == nil {
+		return a, ah
+	}
+	n := a.size + b.size
+	l2, r2 := b.links[Left], b.links[Right]
+	lh2, rh2 := b.childHeights(bh)
+	l1, lh1, _, r1, rh1 := t.split(a, ah, b.data)
+	var (
+		l, r   *rbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.difference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.difference(r1, rh1, r2, rh2)
+	})
+	return t.concat(l, lh, r, rh)
 }
 
-func (t *RbTree) Copy() *RbTree {
+//items in exactly one of subtree a and subtree b, whose heights are ah and bh
+//return root and height of the result
+func (t *RbSet[T]) symmetricDifference(a *rbnode[T], ah int, b *rbnode[T], bh int) (*rbnode[T], int) {
+	if a == nil {
+		return b, bh
+	}
+	if b == nil {
+		return a, ah
+	}
+	a = a.own(t.epoch)
+	n := a.size + b.size
+	l1, r1 := a.links[Left], a.links[Right]
+	lh1, rh1 := a.childHeights(ah)
+	l2, lh2, eq, r2, rh2 := t.split(b, bh, a.data)
+	var (
+		l, r   *rbnode[T]
+		lh, rh int
+	)
+	fork(n, func() {
+		l, lh = t.symmetricDifference(l1, lh1, l2, lh2)
+	}, func() {
+		r, rh = t.symmetricDifference(r1, rh1, r2, rh2)
+	})
+	if eq != nil {
+		return t.concat(l, lh, r, rh)
+	}
+	return t.join(l, lh, a, r, rh)
+}
+
+func (t *RbSet[T]) Copy() *RbSet[T] {
 	if t == nil {
 		return nil
 	}
-	n := NewRbTree(t.cmpFunc, t.extraParam)
+	n := NewRbSet(t.cmpFunc)
 	if n == nil {
 		return nil
 	}
 	n.count = t.count
+	n.iterPolicy = t.iterPolicy
+	n.codec = t.codec
+	n.fix = t.fix
 	if n.count == 0 {
 		return n
 	}
 	var (
-		stack  [2 * (rbMaxHeight + 1)]*rbnode
+		stack  [2 * (rbMaxHeight + 1)]*rbnode[T]
 		height int
-		x      *rbnode
-		y      *rbnode
+		x      *rbnode[T]
+		y      *rbnode[T]
 	)
-	x = (*rbnode)(unsafe.Pointer(&t.root))
-	y = (*rbnode)(unsafe.Pointer(&n.root))
+	//header nodes stand for parents of roots
+	var hx, hy rbnode[T]
+	hx.links[Left] = t.root
+	x, y = &hx, &hy
 	for {
 		for x.links[Left] != nil {
-			y.links[Left] = &rbnode{}
+			y.links[Left] = &rbnode[T]{}
 			stack[height] = x
 			height++
 			stack[height] = y
@@ -384,8 +1193,10 @@ func (t *RbTree) Copy() *RbTree {
 		for {
 			y.data = x.data
 			y.color = x.color
+			y.size = x.size
+			y.epoch = n.epoch
 			if x.links[Right] != nil {
-				y.links[Right] = &rbnode{}
+				y.links[Right] = &rbnode[T]{}
 				x = x.links[Right]
 				y = y.links[Right]
 				break
@@ -393,6 +1204,7 @@ func (t *RbTree) Copy() *RbTree {
 				y.links[Right] = nil
 			}
 			if height <= 2 {
+				n.root = hy.links[Left]
 				return n
 			}
 			height--
@@ -403,27 +1215,156 @@ func (t *RbTree) Copy() *RbTree {
 	}
 }
 
-func (t *RbTree) Iter() Iterator {
-	it := NewRbIter()
+//make child of p in direction dir owned by t and return it,
+//p must be owned by t already
+//cloning a node moves it in memory, so iterators walking the old one are out of date
+func (t *RbSet[T]) ownChild(p *rbnode[T], dir int) *rbnode[T] {
+	c := p.links[dir]
+	if c != nil && c.epoch != t.epoch {
+		c = c.own(t.epoch)
+		p.links[dir] = c
+		t.generation++
+	}
+	return c
+}
+
+//return a copy of t in O(1) time,
+//t and the copy share all nodes, both take a new epoch,
+//so shared nodes are cloned lazily by whichever tree writes them first
+func (t *RbSet[T]) Snapshot() *RbSet[T] {
+	if t == nil {
+		return nil
+	}
+	s := &RbSet[T]{
+		root:       t.root,
+		cmpFunc:    t.cmpFunc,
+		count:      t.count,
+		epoch:      newEpoch(),
+		iterPolicy: t.iterPolicy,
+		codec:      t.codec,
+		fix:        t.fix,
+	}
+	t.epoch = newEpoch()
+	return s
+}
+
+func (t *RbSet[T]) Iter() IteratorOf[T] {
+	it := NewRbSetIter[T]()
 	return it.HookWith(t)
 }
 
-type RbIter struct {
-	tree       *RbTree              //the tree be iterated
-	node       *rbnode              //current node in tree
-	stack      [rbMaxHeight]*rbnode //all node above current node
-	height     int                  //current depth of stack
-	generation int                  // generation number
+//set policy of iterators hooked with tree from now on, see IterPolicy
+func (t *RbSet[T]) SetIterPolicy(p IterPolicy) {
+	if t == nil {
+		return
+	}
+	t.iterPolicy = p
 }
 
-func NewRbIter() *RbIter {
-	return &RbIter{}
+//set codec of items used by MarshalBinary and UnmarshalBinary
+func (t *RbSet[T]) SetCodec(c Codec[T]) {
+	if t == nil {
+		return
+	}
+	t.codec = c
 }
 
-func (it *RbIter) HookWith(tree *RbTree) *RbIter {
+//encode items of t in ascending order along with the shape of tree,
+//implements encoding.BinaryMarshaler
+func (t *RbSet[T]) MarshalBinary() ([]byte, error) {
+	if t == nil {
+		return nil, ErrNoCodec
+	}
+	return marshalTree(encRb, t.root, t.count, t.codec)
+}
+
+//replace items of t with the tree encoded by MarshalBinary of a RbSet,
+//the same tree is rebuilt in O(n) without comparisons,
+//so compare function of t must order items as the encoding tree did
+//implements encoding.BinaryUnmarshaler
+func (t *RbSet[T]) UnmarshalBinary(data []byte) error {
+	if t == nil {
+		return ErrNoCodec
+	}
+	root, count, err := unmarshalTree(encRb, data, t.codec, func(c byte, l *rbnode[T], item T, r *rbnode[T]) *rbnode[T] {
+		n := &rbnode[T]{links: [ChildNum]*rbnode[T]{l, r}, data: item, color: c, epoch: t.epoch}
+		t.update(n)
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	t.root = root
+	t.count = count
+	t.generation++
+	return nil
+}
+
+func (t *RbSet[T]) seekIter() seekIterOf[T] {
+	return NewRbSetIter[T]().HookWith(t)
+}
+
+//sequence of all items in ascending order
+func (t *RbSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAll(t.seekIter)
+}
+
+//sequence of all items in descending order
+func (t *RbSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBackward(t.seekIter)
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *RbSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqAscend(t.seekIter, from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *RbSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqDescend(t.seekIter, from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *RbSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return seqBetween(t.seekIter, t.cmpFunc, lo, hi)
+}
+
+type RbSetIter[T any] struct {
+	tree       *RbSet[T]               //the tree be iterated
+	node       *rbnode[T]              //current node in tree
+	stack      [rbMaxHeight]*rbnode[T] //all node above current node
+	height     int                     //current depth of stack
+	generation int                     // generation number
+	policy     IterPolicy              //how to react to changes of tree
+}
+
+func NewRbSetIter[T any]() *RbSetIter[T] {
+	return &RbSetIter[T]{}
+}
+
+func (it *RbSetIter[T]) HookWith(tree *RbSet[T]) *RbSetIter[T] {
 	if it == nil {
 		return nil
 	}
+	it.policy = tree.iterPolicy
+	if it.policy == IterSnapshot {
+		tree = tree.Snapshot()
+	}
 	it.tree = tree
 	it.node = nil
 	it.height = 0
@@ -432,14 +1373,16 @@ func (it *RbIter) HookWith(tree *RbTree) *RbIter {
 	return it
 }
 
-func (it *RbIter) First() Item {
+func (it *RbSetIter[T]) First() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	it.height = 0
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Left] != nil {
 		it.stack[it.height] = w
@@ -447,17 +1390,19 @@ func (it *RbIter) First() Item {
 		w = w.links[Left]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *RbIter) Last() Item {
+func (it *RbSetIter[T]) Last() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
+	it.generation = it.tree.generation
 	it.height = 0
 	w := it.tree.root
 	if w == nil {
-		return nil
+		it.node = nil
+		return
 	}
 	for w.links[Right] != nil {
 		it.stack[it.height] = w
@@ -465,23 +1410,24 @@ func (it *RbIter) Last() Item {
 		w = w.links[Right]
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *RbIter) Find(item Item) Item {
-	if it == nil || it.tree == nil || item == nil {
-		return nil
+func (it *RbSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
 	}
+	it.generation = it.tree.generation
 	it.height = 0
 	var (
-		w *rbnode //walk node
-		n *rbnode //child of w
+		w *rbnode[T] //walk node
+		n *rbnode[T] //child of w
 	)
 	for w = it.tree.root; w != nil; w = n {
-		cmp := it.tree.cmpFunc(item, w.data, it.tree.extraParam)
+		cmp := it.tree.cmpFunc(item, w.data)
 		if cmp == 0 {
 			it.node = w
-			return w.data
+			return w.data, true
 		}
 		if cmp < 0 {
 			n = w.links[Left]
@@ -493,15 +1439,106 @@ func (it *RbIter) Find(item Item) Item {
 	}
 	it.height = 0
 	it.node = nil
-	return nil
+	return
 }
 
-func (it *RbIter) Next() Item {
+//move iterator to item at index k in sorted order
+//if k is out of range, iterator is moved to nil position
+func (it *RbSetIter[T]) Seek(k int) (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
+	}
+	it.generation = it.tree.generation
+	it.height = 0
+	it.node = nil
+	if k < 0 || k >= it.tree.count {
+		return
+	}
+	w := it.tree.root
+	for {
+		ls := w.links[Left].subSize()
+		if k == ls {
+			break
+		}
+		it.stack[it.height] = w
+		it.height++
+		if k < ls {
+			w = w.links[Left]
+		} else {
+			k -= ls + 1
+			w = w.links[Right]
+		}
+	}
+	it.node = w
+	return w.data, true
+}
+
+//move iterator to the nearest item to item in direction dir, see near
+func (it *RbSetIter[T]) seekNear(item T, dir int, eq bool) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	it.generation = it.tree.generation
+	var (
+		c  *rbnode[T] //candidate node
+		ch int        //stack height of candidate node
+	)
+	it.height = 0
+	for w := it.tree.root; w != nil; {
+		cmp := it.tree.cmpFunc(item, w.data)
+		if cmp == 0 && eq {
+			c, ch = w, it.height
+			break
+		}
+		it.stack[it.height] = w
+		it.height++
+		if (dir == Right && cmp < 0) || (dir == Left && cmp > 0) {
+			c, ch = w, it.height-1
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	it.node = c
+	if c == nil {
+		it.height = 0
+		return
+	}
+	it.height = ch
+	return c.data, true
+}
+
+//move iterator to the least item greater than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *RbSetIter[T]) SeekGE(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, true)
+}
+
+//move iterator to the least item greater than item
+//if there is no such item, iterator is moved to nil position
+func (it *RbSetIter[T]) SeekGT(item T) (found T, ok bool) {
+	return it.seekNear(item, Right, false)
+}
+
+//move iterator to the greatest item less than or equal to item
+//if there is no such item, iterator is moved to nil position
+func (it *RbSetIter[T]) SeekLE(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, true)
+}
+
+//move iterator to the greatest item less than item
+//if there is no such item, iterator is moved to nil position
+func (it *RbSetIter[T]) SeekLT(item T) (found T, ok bool) {
+	return it.seekNear(item, Left, false)
+}
+
+func (it *RbSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
 	}
-	if it.generation != it.tree.generation {
-		it.refresh()
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Right, false)
 	}
 	w := it.node
 	if w == nil {
@@ -519,7 +1556,7 @@ func (it *RbIter) Next() Item {
 		for {
 			if it.height == 0 {
 				it.node = nil
-				return nil
+				return
 			}
 			n := w
 			it.height--
@@ -530,15 +1567,16 @@ func (it *RbIter) Next() Item {
 		}
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *RbIter) Prev() Item {
+func (it *RbSetIter[T]) Prev() (item T, ok bool) {
 	if it == nil || it.tree == nil {
-		return nil
+		return
 	}
-	if it.generation != it.tree.generation {
-		it.refresh()
+	if it.node != nil && !it.synced() {
+		//current item may be gone, move on from its key
+		return it.seekNear(it.node.data, Left, false)
 	}
 	w := it.node
 	if w == nil {
@@ -556,7 +1594,7 @@ func (it *RbIter) Prev() Item {
 		for {
 			if it.height == 0 {
 				it.node = nil
-				return nil
+				return
 			}
 			n := w
 			it.height--
@@ -568,75 +1606,222 @@ func (it *RbIter) Prev() Item {
 
 	}
 	it.node = w
-	return w.data
+	return w.data, true
 }
 
-func (it *RbIter) refresh() {
-	if it == nil || it.tree == nil {
-		return
-	}
-	it.generation = it.tree.generation
-	if it.node != nil {
-		cmpFunc := it.tree.cmpFunc
-		param := it.tree.extraParam
-		node := it.node
-		it.height = 0
-		for w := it.tree.root; w != node; {
-			it.stack[it.height] = w
-			it.height++
-			ret := cmpFunc(node.data, w.data, param)
-			if ret > 0 {
-				w = w.links[Right]
-			} else {
-				w = w.links[Left]
-			}
+//locate current item again after tree is changed, node may have been replaced by its copy
+//return false if current item is deleted, iterator is left alone then
+func (it *RbSetIter[T]) refresh() bool {
+	cmpFunc := it.tree.cmpFunc
+	node := it.node
+	it.height = 0
+	for w := it.tree.root; w != nil; {
+		ret := cmpFunc(node.data, w.data)
+		if ret == 0 {
+			it.node = w
+			it.generation = it.tree.generation
+			return true
+		}
+		it.stack[it.height] = w
+		it.height++
+		if ret > 0 {
+			w = w.links[Right]
+		} else {
+			w = w.links[Left]
 		}
 	}
+	return false
 }
 
-func (it *RbIter) Current() Item {
+//report whether tree is unchanged since iterator was positioned,
+//panic with ErrConcurrentModification if not and policy is IterFailFast
+func (it *RbSetIter[T]) synced() bool {
+	if it.generation == it.tree.generation {
+		return true
+	}
+	if it.policy == IterFailFast {
+		panic(ErrConcurrentModification)
+	}
+	return false
+}
+
+func (it *RbSetIter[T]) Current() (item T, ok bool) {
 	if it == nil || it.node == nil {
-		return nil
+		return
+	}
+	return it.node.data, true
+}
+
+//return index of current item in sorted order
+//return -1 if iterator is at nil position
+func (it *RbSetIter[T]) Index() int {
+	if it == nil || it.tree == nil || it.node == nil {
+		return -1
 	}
-	return it.node.data
+	if !it.synced() && !it.refresh() {
+		return -1
+	}
+	idx := it.node.links[Left].subSize()
+	for i, n := it.height-1, it.node; i >= 0; i, n = i-1, it.stack[i] {
+		if it.stack[i].links[Right] == n {
+			idx += it.stack[i].links[Left].subSize() + 1
+		}
+	}
+	return idx
 }
 
 //don't change key part of item
-func (it *RbIter) Replace(new Item) Item {
-	if it == nil || it.node == nil || new == nil {
-		return nil
+func (it *RbSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
+	}
+	if it.node.epoch != it.tree.epoch {
+		//node is shared, copy the path down to it
+		it.Insert(it.node.data)
 	}
 	old := it.node.data
 	it.node.data = new
-	return old
+	it.tree.fixKey(new)
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *RbSetIter[T]) Delete() (item T, ok bool) {
+	return it.delete(Right)
 }
 
-func (it *RbIter) CopyFrom(other *RbIter) Item {
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *RbSetIter[T]) DeletePrev() (item T, ok bool) {
+	return it.delete(Left)
+}
+
+//delete current item and move to its neighbour in direction dir,
+//the tree is changed by the iterator itself, so it stays valid
+func (it *RbSetIter[T]) delete(dir int) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	if !it.synced() && !it.refresh() {
+		return
+	}
+	t := it.tree
+	var (
+		pa  [rbMaxHeight]*rbnode[T] //path to current node, pa[0] is header node whose left link is root
+		da  [rbMaxHeight]byte       //direction taken at each node of pa
+		ext [rbMaxHeight]*rbnode[T] //path to neighbour below pa
+		n   int                     //number of nodes in ext
+	)
+	//read directions off the stack before shared nodes are cloned
+	k := it.height + 1
+	for i := 0; i < it.height; i++ {
+		next := it.node
+		if i+1 < it.height {
+			next = it.stack[i+1]
+		}
+		if it.stack[i].links[Right] == next {
+			da[i+1] = Right
+		} else {
+			da[i+1] = Left
+		}
+	}
+	head := rbnode[T]{links: [ChildNum]*rbnode[T]{Left: t.root}}
+	pa[0] = &head
+	for i := 1; i < k; i++ {
+		pa[i] = t.ownChild(pa[i-1], int(da[i-1]))
+	}
+	w := t.ownChild(pa[k-1], int(da[k-1]))
+
+	//find the neighbour before deletion, owning its path so rebalancing won't clone it,
+	//its new stack is pa[1:prefix] plus ext, valid if no rotation at level top or above
+	var (
+		nb          *rbnode[T]
+		prefix, top int
+		s           *rbnode[T] //successor which takes place of w
+	)
+	if w.links[Right] != nil {
+		for s = t.ownChild(w, Right); s.links[Left] != nil; s = t.ownChild(s, Left) {
+		}
+	}
+	if w.links[dir] == nil {
+		//neighbour is the nearest ancestor we left in opposite direction
+		for i := k - 1; i > 0; i-- {
+			if int(da[i]) == 1-dir {
+				nb, prefix, top = pa[i], i, i
+				break
+			}
+		}
+	} else if dir == Right {
+		nb, prefix, top = s, k, k
+	} else {
+		prefix, top = k, k-1
+		if s != nil {
+			ext[n] = s
+			n++
+			top = k
+		}
+		for nb = t.ownChild(w, Left); nb.links[Right] != nil; nb = t.ownChild(nb, Right) {
+			ext[n] = nb
+			n++
+		}
+	}
+
+	_, rot := t.deleteAt(&pa, &da, k)
+	it.generation = t.generation
+	it.node = nb
+	if nb == nil {
+		it.height = 0
+		return
+	}
+	if top < rot {
+		it.height = copy(it.stack[:], pa[1:prefix])
+		it.height += copy(it.stack[it.height:], ext[:n])
+		return nb.data, true
+	}
+	//nodes from level rot down were rotated, search neighbour below them only
+	it.height = copy(it.stack[:], pa[1:rot])
+	for x := pa[rot-1].links[da[rot-1]]; x != nb; {
+		it.stack[it.height] = x
+		it.height++
+		if t.cmpFunc(nb.data, x.data) < 0 {
+			x = x.links[Left]
+		} else {
+			x = x.links[Right]
+		}
+	}
+	return nb.data, true
+}
+
+func (it *RbSetIter[T]) CopyFrom(other *RbSetIter[T]) (item T, ok bool) {
 	if it == nil || other == nil {
-		return nil
+		return
 	}
 	if it != other {
 		it.tree = other.tree
 		it.node = other.node
 		it.generation = other.generation
+		it.policy = other.policy
 		if it.generation == it.tree.generation {
 			it.height = other.height
 			copy(it.stack[:it.height], other.stack[:other.height])
 		}
 	}
 	if it.node == nil {
-		return nil
+		return
 	}
-	return it.node.data
+	return it.node.data, true
 }
 
-func (it *RbIter) Insert(item Item) (*Item, bool) {
-	if it == nil || it.tree == nil || item == nil {
+func (it *RbSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
 		return nil, false
 	}
 	addr, ok := it.tree.insert(item)
-
-	it.node = (*rbnode)(unsafe.Pointer(uintptr(unsafe.Pointer(addr)) - unsafe.Offsetof(it.node.data)))
-	it.generation = it.tree.generation - 1
+	//move to item and rebuild stack, which rotations may have broken
+	it.Find(item)
 	return addr, ok
 }
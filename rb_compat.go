@@ -0,0 +1,467 @@
+package bbst
+
+import (
+	"iter"
+	"slices"
+)
+
+//RbTree is the interface{} flavour of RbSet,
+//kept as a thin layer over RbSet[Item] for existing callers
+type RbTree struct {
+	RbSet[Item]
+}
+
+func NewRbTree(cmp Compare, extra interface{}) *RbTree {
+	if cmp == nil {
+		return nil
+	}
+	return &RbTree{RbSet[Item]{cmpFunc: compareOf(cmp, extra), epoch: newEpoch()}}
+}
+
+//create a tree holding items, which must be in strictly ascending order by cmp,
+//the tree is built perfectly balanced in O(n)
+//return ErrNilItem if any item is nil,
+//ErrNotSorted if items are out of order or have duplicates
+func NewRbTreeFromSorted(cmp Compare, extra interface{}, items []Item) (*RbTree, error) {
+	t := NewRbTree(cmp, extra)
+	if t == nil {
+		return nil, nil
+	}
+	if err := checkNil(items); err != nil {
+		return nil, err
+	}
+	if err := t.RbSet.loadSorted(items); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *RbTree) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.RbSet.Count()
+}
+
+//search target in tree
+//if find it return item
+//else return nil
+func (t *RbTree) Find(target Item) Item {
+	if t == nil || target == nil {
+		return nil
+	}
+	item, _ := t.RbSet.Find(target)
+	return item
+}
+
+//return item at index k in sorted order, k counts from 0
+//return nil if k is out of range
+func (t *RbTree) Select(k int) Item {
+	if t == nil {
+		return nil
+	}
+	item, _ := t.RbSet.Select(k)
+	return item
+}
+
+//return number of items in tree less than item
+func (t *RbTree) Rank(item Item) int {
+	if t == nil || item == nil {
+		return 0
+	}
+	return t.RbSet.Rank(item)
+}
+
+//return the greatest item less than or equal to item
+//return nil if there is no such item
+func (t *RbTree) Floor(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.RbSet.Floor(item)
+	return found
+}
+
+//return the least item greater than or equal to item
+//return nil if there is no such item
+func (t *RbTree) Ceiling(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	found, _ := t.RbSet.Ceiling(item)
+	return found
+}
+
+//return iterator at the first item not less than item
+func (t *RbTree) LowerBound(item Item) Iterator {
+	it := NewRbIter()
+	it.HookWith(t).SeekGE(item)
+	return it
+}
+
+//return iterator at the first item greater than item
+func (t *RbTree) UpperBound(item Item) Iterator {
+	it := NewRbIter()
+	it.HookWith(t).SeekGT(item)
+	return it
+}
+
+func (t *RbTree) insert(item Item) (*Item, bool) {
+	if t == nil || item == nil {
+		return nil, false
+	}
+	return t.RbSet.insert(item)
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *RbTree) Insert(item Item) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//insert all items in tree, nil items and items equal to one in tree
+//or to an earlier one in items are skipped
+//return number of inserted items and skipped non nil items in ascending order
+func (t *RbTree) InsertBatch(items []Item) (inserted int, dups []Item) {
+	if t == nil {
+		return
+	}
+	return t.RbSet.InsertBatch(slices.DeleteFunc(slices.Clone(items), func(item Item) bool {
+		return item == nil
+	}))
+}
+
+//replace item in tree with same key item
+//return old item
+func (t *RbTree) Replace(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	old, _ := t.RbSet.Replace(item)
+	return old
+}
+
+//delete item in tree
+//return item if find it
+//else  return nil
+func (t *RbTree) Delete(item Item) Item {
+	if t == nil || item == nil {
+		return nil
+	}
+	deleted, _ := t.RbSet.Delete(item)
+	return deleted
+}
+
+//call fn for each item in [lo, hi) in ascending order
+//stop early if fn return false
+func (t *RbTree) Range(lo, hi Item, fn func(item Item) bool) {
+	if t == nil || lo == nil || hi == nil {
+		return
+	}
+	t.RbSet.Range(lo, hi, fn)
+}
+
+//delete all items in [lo, hi)
+//return number of deleted items
+func (t *RbTree) DeleteRange(lo, hi Item) int {
+	if t == nil || lo == nil || hi == nil {
+		return 0
+	}
+	return t.RbSet.DeleteRange(lo, hi)
+}
+
+//join pivot and all items of right into t,
+//every item in t must be less than pivot, every item in right must be greater than pivot,
+//right is emptied
+//return false and change nothing if items are out of order
+func (t *RbTree) Join(pivot Item, right *RbTree) bool {
+	if t == nil || pivot == nil {
+		return false
+	}
+	if right == nil {
+		return t.RbSet.Join(pivot, nil)
+	}
+	return t.RbSet.Join(pivot, &right.RbSet)
+}
+
+//split t into items less than item and items greater than item
+//eq is the item equal to item, or nil if there is no such item
+//t is emptied
+func (t *RbTree) Split(item Item) (lt *RbTree, eq Item, gt *RbTree) {
+	if t == nil || item == nil {
+		return
+	}
+	l, eq, _, r := t.RbSet.Split(item)
+	return &RbTree{*l}, eq, &RbTree{*r}
+}
+
+//split t into the first k items and the rest
+//t is emptied
+func (t *RbTree) SplitAt(k int) (lt, ge *RbTree) {
+	if t == nil {
+		return
+	}
+	l, r := t.RbSet.SplitAt(k)
+	return &RbTree{*l}, &RbTree{*r}
+}
+
+//merge all items of other into t, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *RbTree) Union(other *RbTree, resolve func(a, b Item) Item) {
+	if t == nil || other == nil {
+		return
+	}
+	t.RbSet.Union(&other.RbSet, resolve)
+}
+
+//keep only items of t which are also in other, other is emptied
+//resolve chooses the item to keep when a from t and b from other are equal,
+//nil resolve keeps a
+func (t *RbTree) Intersect(other *RbTree, resolve func(a, b Item) Item) {
+	if t == nil {
+		return
+	}
+	if other == nil {
+		t.RbSet.Intersect(nil, resolve)
+		return
+	}
+	t.RbSet.Intersect(&other.RbSet, resolve)
+}
+
+//delete all items of other from t, other is emptied
+func (t *RbTree) Difference(other *RbTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.RbSet.Difference(&other.RbSet)
+}
+
+//keep items which are in exactly one of t and other in t, other is emptied
+func (t *RbTree) SymmetricDifference(other *RbTree) {
+	if t == nil || other == nil {
+		return
+	}
+	t.RbSet.SymmetricDifference(&other.RbSet)
+}
+
+func (t *RbTree) Copy() *RbTree {
+	if t == nil {
+		return nil
+	}
+	return &RbTree{*t.RbSet.Copy()}
+}
+
+//return a copy of t in O(1) time, see RbSet.Snapshot
+func (t *RbTree) Snapshot() *RbTree {
+	if t == nil {
+		return nil
+	}
+	return &RbTree{*t.RbSet.Snapshot()}
+}
+
+//sequence of all items in ascending order
+func (t *RbTree) All() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.RbSet.All()
+}
+
+//sequence of all items in descending order
+func (t *RbTree) Backward() iter.Seq[Item] {
+	if t == nil {
+		return empty[Item]
+	}
+	return t.RbSet.Backward()
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *RbTree) Ascend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.RbSet.Ascend(from)
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *RbTree) Descend(from Item) iter.Seq[Item] {
+	if t == nil || from == nil {
+		return empty[Item]
+	}
+	return t.RbSet.Descend(from)
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *RbTree) Between(lo, hi Item) iter.Seq[Item] {
+	if t == nil || lo == nil || hi == nil {
+		return empty[Item]
+	}
+	return t.RbSet.Between(lo, hi)
+}
+
+func (t *RbTree) Iter() Iterator {
+	it := NewRbIter()
+	return it.HookWith(t)
+}
+
+type RbIter struct {
+	RbSetIter[Item]
+}
+
+func NewRbIter() *RbIter {
+	return &RbIter{}
+}
+
+func (it *RbIter) HookWith(tree *RbTree) *RbIter {
+	if it == nil {
+		return nil
+	}
+	it.RbSetIter.HookWith(&tree.RbSet)
+	return it
+}
+
+func (it *RbIter) First() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.First()
+	return item
+}
+
+func (it *RbIter) Last() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.Last()
+	return item
+}
+
+func (it *RbIter) Find(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.RbSetIter.Find(item)
+	return found
+}
+
+//move iterator to item at index k in sorted order
+func (it *RbIter) Seek(k int) Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.Seek(k)
+	return item
+}
+
+//return index of current item, -1 at nil position
+func (it *RbIter) Index() int {
+	if it == nil {
+		return -1
+	}
+	return it.RbSetIter.Index()
+}
+
+//move iterator to the least item greater than or equal to item
+func (it *RbIter) SeekGE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.RbSetIter.SeekGE(item)
+	return found
+}
+
+//move iterator to the least item greater than item
+func (it *RbIter) SeekGT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.RbSetIter.SeekGT(item)
+	return found
+}
+
+//move iterator to the greatest item less than or equal to item
+func (it *RbIter) SeekLE(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.RbSetIter.SeekLE(item)
+	return found
+}
+
+//move iterator to the greatest item less than item
+func (it *RbIter) SeekLT(item Item) Item {
+	if it == nil || item == nil {
+		return nil
+	}
+	found, _ := it.RbSetIter.SeekLT(item)
+	return found
+}
+
+func (it *RbIter) Next() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.Next()
+	return item
+}
+
+func (it *RbIter) Prev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.Prev()
+	return item
+}
+
+func (it *RbIter) Current() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.Current()
+	return item
+}
+
+//don't change key part of item
+func (it *RbIter) Replace(new Item) Item {
+	if it == nil || new == nil {
+		return nil
+	}
+	old, _ := it.RbSetIter.Replace(new)
+	return old
+}
+
+//delete current item and move to its successor, return the successor
+func (it *RbIter) Delete() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.Delete()
+	return item
+}
+
+//delete current item and move to its predecessor, return the predecessor
+func (it *RbIter) DeletePrev() Item {
+	if it == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.DeletePrev()
+	return item
+}
+
+func (it *RbIter) CopyFrom(other *RbIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.RbSetIter.CopyFrom(&other.RbSetIter)
+	return item
+}
+
+func (it *RbIter) Insert(item Item) (*Item, bool) {
+	if it == nil || item == nil {
+		return nil, false
+	}
+	return it.RbSetIter.Insert(item)
+}
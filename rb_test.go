@@ -6,7 +6,7 @@ import (
 	"testing"
 )
 
-func (n *rbnode) print(lvl int) {
+func (n *rbnode[T]) print(lvl int) {
 	if n == nil {
 		return
 	}
@@ -26,7 +26,7 @@ func (n *rbnode) print(lvl int) {
 	}
 }
 
-func recurseVerifyRbTree(t *testing.T, node *rbnode, ok *bool, count *int, min, max int, bh *int) {
+func recurseVerifyRbTree(t *testing.T, node *rbnode[Item], ok *bool, count *int, min, max int, bh *int) {
 	var (
 		d        int           //data of tree node
 		subcount [ChildNum]int //count of subtree
@@ -50,6 +50,10 @@ func recurseVerifyRbTree(t *testing.T, node *rbnode, ok *bool, count *int, min,
 	recurseVerifyRbTree(t, node.links[Right], ok, &subcount[Right], d+1, max, &subbh[Right])
 
 	*count = 1 + subcount[Left] + subcount[Right]
+	if node.size != *count {
+		t.Errorf("Node %d has size %d, but subtree has %d nodes.\n", d, node.size, *count)
+		*ok = false
+	}
 	h := 0
 	if node.color == black {
 		h = 1
@@ -226,7 +230,7 @@ func (it *RbIter) check(t *testing.T, i, n int, title string) bool {
 	return ok
 }
 
-func compareRbTrees(t *testing.T, a, b *rbnode) bool {
+func compareRbTrees(t *testing.T, a, b *rbnode[Item]) bool {
 	if a == nil && b == nil {
 		return true
 	}
@@ -239,7 +243,7 @@ func compareRbTrees(t *testing.T, a, b *rbnode) bool {
 	if a.data != b.data ||
 		((a.links[Left] != nil) != (b.links[Left] != nil)) ||
 		((a.links[Right] != nil) != (b.links[Right] != nil)) ||
-		a.color != b.color {
+		a.color != b.color || a.size != b.size {
 		t.Logf("Copied nodes differ: a=%d (color=%c) b=%d (color=%c) a:", a.data, cf(a.color), b.data, cf(b.color))
 		if a.links[Left] != nil {
 			t.Logf("l")
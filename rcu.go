@@ -0,0 +1,247 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+//one version of a persistent set, as seen by RcuSet
+type pstSetOf[T any] interface {
+	Count() int
+	Find(target T) (item T, ok bool)
+	Select(k int) (item T, ok bool)
+	Rank(item T) int
+	Iter() IteratorOf[T]
+	All() iter.Seq[T]
+	Backward() iter.Seq[T]
+	Ascend(from T) iter.Seq[T]
+	Descend(from T) iter.Seq[T]
+	Between(lo, hi T) iter.Seq[T]
+	insertVersion(item T) (pstSetOf[T], bool)
+	replaceVersion(item T) (pstSetOf[T], T, bool)
+	deleteVersion(item T) (pstSetOf[T], T, bool)
+}
+
+func (t *PersistentAvlSet[T]) insertVersion(item T) (pstSetOf[T], bool) {
+	s, ok := t.Insert(item)
+	return s, ok
+}
+
+func (t *PersistentAvlSet[T]) replaceVersion(item T) (pstSetOf[T], T, bool) {
+	s, old, ok := t.Replace(item)
+	return s, old, ok
+}
+
+func (t *PersistentAvlSet[T]) deleteVersion(item T) (pstSetOf[T], T, bool) {
+	s, deleted, ok := t.Delete(item)
+	return s, deleted, ok
+}
+
+func (t *PersistentRbSet[T]) insertVersion(item T) (pstSetOf[T], bool) {
+	s, ok := t.Insert(item)
+	return s, ok
+}
+
+func (t *PersistentRbSet[T]) replaceVersion(item T) (pstSetOf[T], T, bool) {
+	s, old, ok := t.Replace(item)
+	return s, old, ok
+}
+
+func (t *PersistentRbSet[T]) deleteVersion(item T) (pstSetOf[T], T, bool) {
+	s, deleted, ok := t.Delete(item)
+	return s, deleted, ok
+}
+
+//published version of RcuSet
+type rcuVersion[T any] struct {
+	pstSetOf[T]
+}
+
+//RcuSet is a set for read mostly workloads,
+//readers load the current version through an atomic pointer and never block,
+//writers are serialized, each one builds a new version by path copying
+//and publishes it atomically,
+//old versions are reclaimed by the garbage collector once no reader uses them
+type RcuSet[T any] struct {
+	root atomic.Pointer[rcuVersion[T]] //current version
+	mu   sync.Mutex                    //serialize writers
+}
+
+func newRcuSet[T any](s pstSetOf[T]) *RcuSet[T] {
+	t := &RcuSet[T]{}
+	t.root.Store(&rcuVersion[T]{s})
+	return t
+}
+
+//create a set on top of persistent avl tree
+func NewRcuAvlSet[T any](cmp func(a, b T) int) *RcuSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return newRcuSet[T](NewPersistentAvlSet(cmp))
+}
+
+//create a set on top of persistent red black tree
+func NewRcuRbSet[T any](cmp func(a, b T) int) *RcuSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return newRcuSet[T](NewPersistentRbSet(cmp))
+}
+
+//create a set ordered by the natural order of T,
+//no comparator needs to be written for builtin ordered types
+func NewOrderedRcuAvlSet[T cmp.Ordered]() *RcuSet[T] {
+	return NewRcuAvlSet(cmp.Compare[T])
+}
+
+func NewOrderedRcuRbSet[T cmp.Ordered]() *RcuSet[T] {
+	return NewRcuRbSet(cmp.Compare[T])
+}
+
+func (t *RcuSet[T]) load() pstSetOf[T] {
+	return t.root.Load().pstSetOf
+}
+
+func (t *RcuSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.load().Count()
+}
+
+//search target in tree
+//if find it return item
+//else ok is false
+func (t *RcuSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	return t.load().Find(target)
+}
+
+//return item at index k in sorted order, k counts from 0
+//ok is false if k is out of range
+func (t *RcuSet[T]) Select(k int) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	return t.load().Select(k)
+}
+
+//return number of items in tree less than item,
+//which is also index of item if it is in tree
+func (t *RcuSet[T]) Rank(item T) int {
+	if t == nil {
+		return 0
+	}
+	return t.load().Rank(item)
+}
+
+//return iterator over the current version,
+//later writes are not seen by it, so it is never invalidated
+func (t *RcuSet[T]) Iter() IteratorOf[T] {
+	if t == nil {
+		return nil
+	}
+	return t.load().Iter()
+}
+
+//the sequences below range over the version current when ranging starts
+
+//sequence of all items in ascending order
+func (t *RcuSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.load().All()(yield)
+	}
+}
+
+//sequence of all items in descending order
+func (t *RcuSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.load().Backward()(yield)
+	}
+}
+
+//sequence of items greater than or equal to from in ascending order
+func (t *RcuSet[T]) Ascend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.load().Ascend(from)(yield)
+	}
+}
+
+//sequence of items less than or equal to from in descending order
+func (t *RcuSet[T]) Descend(from T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.load().Descend(from)(yield)
+	}
+}
+
+//sequence of items in [lo, hi) in ascending order
+func (t *RcuSet[T]) Between(lo, hi T) iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.load().Between(lo, hi)(yield)
+	}
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *RcuSet[T]) Insert(item T) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.load().insertVersion(item)
+	if ok {
+		t.root.Store(&rcuVersion[T]{s})
+	}
+	return ok
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+func (t *RcuSet[T]) Replace(item T) (old T, ok bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, old, ok := t.load().replaceVersion(item)
+	t.root.Store(&rcuVersion[T]{s})
+	return old, ok
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *RcuSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, deleted, ok := t.load().deleteVersion(item)
+	if ok {
+		t.root.Store(&rcuVersion[T]{s})
+	}
+	return deleted, ok
+}
@@ -0,0 +1,494 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+	"math"
+)
+
+type sgnode[T any] struct {
+	links [ChildNum]*sgnode[T] //child node
+	data  T                    //data item
+}
+
+//number of node in subtree rooted at n, O(size)
+func (n *sgnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+const defaultAlpha = 0.7
+
+//ScapegoatSet is a scapegoat tree, see Galperin and Rivest, Scapegoat Trees,
+//nodes keep no balance data, a too deep insertion rebuilds the subtree of an ancestor
+//whose child is heavier than alpha of it, and too many deletions rebuild the whole tree,
+//operations are O(log n) amortized, searches O(log n) in worst case
+type ScapegoatSet[T any] struct {
+	root     *sgnode[T]       //root of  tree
+	cmpFunc  func(a, b T) int //compare function
+	count    int              // number of item in tree
+	maxCount int              //max count since the whole tree was rebuilt
+	alpha    float64          //weight balance factor, 0.5 <= alpha < 1
+}
+
+func NewScapegoatSet[T any](cmp func(a, b T) int) *ScapegoatSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &ScapegoatSet[T]{cmpFunc: cmp, alpha: defaultAlpha}
+}
+
+func NewOrderedScapegoatSet[T cmp.Ordered]() *ScapegoatSet[T] {
+	return NewScapegoatSet(cmp.Compare[T])
+}
+
+//set weight balance factor of tree, alpha out of [0.5, 1) is ignored,
+//a smaller alpha keeps tree shallower but rebuilds more often, the default is 0.7
+func (t *ScapegoatSet[T]) SetAlpha(alpha float64) {
+	if t == nil || alpha < 0.5 || alpha >= 1 {
+		return
+	}
+	t.alpha = alpha
+}
+
+func (t *ScapegoatSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+//max depth allowed for a tree of n items, log(n) to base 1/alpha
+func (t *ScapegoatSet[T]) maxDepth(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	//the epsilon keeps exact powers of 1/alpha from rounding down
+	return int(math.Log(float64(n))/-math.Log(t.alpha) + 1e-9)
+}
+
+//return node of target, nil if there is none
+func (t *ScapegoatSet[T]) find(target T) *sgnode[T] {
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(target, w.data)
+		if cmp == 0 {
+			return w
+		} else if cmp < 0 {
+			w = w.links[Left]
+		} else {
+			w = w.links[Right]
+		}
+	}
+	return nil
+}
+
+//search target in tree
+//return found item and true if find it
+//else ok is false
+func (t *ScapegoatSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.find(target); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//insert item in tree
+//return node of item and true if inserted,
+//node of the equal item and false if item already in tree
+func (t *ScapegoatSet[T]) insert(item T) (*sgnode[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	var (
+		buf [64]*sgnode[T]
+		pa  = buf[:0] //ancestors of new node from root
+	)
+	p := &t.root
+	for *p != nil {
+		cmp := t.cmpFunc(item, (*p).data)
+		if cmp == 0 {
+			return *p, false
+		}
+		pa = append(pa, *p)
+		if cmp < 0 {
+			p = &(*p).links[Left]
+		} else {
+			p = &(*p).links[Right]
+		}
+	}
+	n := &sgnode[T]{data: item}
+	*p = n
+	t.count++
+	t.maxCount = max(t.maxCount, t.count)
+	if len(pa) <= t.maxDepth(t.count) {
+		return n, true
+	}
+	//new node is too deep, walk up to the first ancestor out of weight balance
+	x, size := n, 1
+	for k := len(pa) - 1; k >= 0; k-- {
+		y := pa[k]
+		dir := Left
+		if y.links[Left] == x {
+			dir = Right
+		}
+		ysize := size + y.links[dir].subSize() + 1
+		if float64(size) > t.alpha*float64(ysize) {
+			if k == 0 {
+				t.root = t.rebuild(y, ysize)
+			} else if pa[k-1].links[Left] == y {
+				pa[k-1].links[Left] = t.rebuild(y, ysize)
+			} else {
+				pa[k-1].links[Right] = t.rebuild(y, ysize)
+			}
+			break
+		}
+		x, size = y, ysize
+	}
+	return n, true
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *ScapegoatSet[T]) Insert(item T) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+func (t *ScapegoatSet[T]) Replace(item T) (old T, ok bool) {
+	n, succ := t.insert(item)
+	if n == nil || succ {
+		return
+	}
+	r := n.data
+	n.data = item
+	return r, true
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *ScapegoatSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	p := &t.root
+	for {
+		if *p == nil {
+			return
+		}
+		cmp := t.cmpFunc(item, (*p).data)
+		if cmp == 0 {
+			break
+		}
+		if cmp < 0 {
+			p = &(*p).links[Left]
+		} else {
+			p = &(*p).links[Right]
+		}
+	}
+	n := *p
+	if n.links[Left] == nil {
+		*p = n.links[Right]
+	} else if n.links[Right] == nil {
+		*p = n.links[Left]
+	} else {
+		//relink successor s in place of n, nodes are never copied
+		q := &n.links[Right]
+		for (*q).links[Left] != nil {
+			q = &(*q).links[Left]
+		}
+		s := *q
+		*q = s.links[Right]
+		s.links[Left] = n.links[Left]
+		s.links[Right] = n.links[Right]
+		*p = s
+	}
+	t.count--
+	if float64(t.count) < t.alpha*float64(t.maxCount) {
+		t.Rebuild()
+	}
+	return n.data, true
+}
+
+//rebuild the whole tree perfectly balanced in O(n)
+func (t *ScapegoatSet[T]) Rebuild() {
+	if t == nil {
+		return
+	}
+	t.root = t.rebuild(t.root, t.count)
+	t.maxCount = t.count
+}
+
+//rebuild the subtree rooted at the node of item perfectly balanced,
+//in O(size of subtree), as an insertion does for its scapegoat
+//return false if item is not in tree
+func (t *ScapegoatSet[T]) RebuildAt(item T) bool {
+	if t == nil {
+		return false
+	}
+	p := &t.root
+	for *p != nil {
+		cmp := t.cmpFunc(item, (*p).data)
+		if cmp == 0 {
+			*p = t.rebuild(*p, (*p).subSize())
+			return true
+		}
+		if cmp < 0 {
+			p = &(*p).links[Left]
+		} else {
+			p = &(*p).links[Right]
+		}
+	}
+	return false
+}
+
+//flatten subtree n of size nodes and rebuild it perfectly balanced,
+//nodes are relinked, not copied
+//return the new root of subtree
+func (t *ScapegoatSet[T]) rebuild(n *sgnode[T], size int) *sgnode[T] {
+	nodes := make([]*sgnode[T], 0, size)
+	var flatten func(n *sgnode[T])
+	flatten = func(n *sgnode[T]) {
+		for ; n != nil; n = n.links[Right] {
+			flatten(n.links[Left])
+			nodes = append(nodes, n)
+		}
+	}
+	flatten(n)
+	var build func(nodes []*sgnode[T]) *sgnode[T]
+	build = func(nodes []*sgnode[T]) *sgnode[T] {
+		if len(nodes) == 0 {
+			return nil
+		}
+		mid := len(nodes) / 2
+		n := nodes[mid]
+		n.links[Left] = build(nodes[:mid])
+		n.links[Right] = build(nodes[mid+1:])
+		return n
+	}
+	return build(nodes)
+}
+
+//the first (dir is Left) or the last (dir is Right) node of tree
+func (t *ScapegoatSet[T]) extreme(dir int) *sgnode[T] {
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	for w.links[dir] != nil {
+		w = w.links[dir]
+	}
+	return w
+}
+
+//return the neighbour of item in direction dir, nil if there is none,
+//item need not be in tree
+func (t *ScapegoatSet[T]) near(item T, dir int) *sgnode[T] {
+	var found *sgnode[T]
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if dir == Right && cmp < 0 || dir == Left && cmp > 0 {
+			found = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	return found
+}
+
+func (t *ScapegoatSet[T]) Copy() *ScapegoatSet[T] {
+	if t == nil {
+		return nil
+	}
+	var copyTree func(x *sgnode[T]) *sgnode[T]
+	copyTree = func(x *sgnode[T]) *sgnode[T] {
+		if x == nil {
+			return nil
+		}
+		return &sgnode[T]{
+			links: [ChildNum]*sgnode[T]{copyTree(x.links[Left]), copyTree(x.links[Right])},
+			data:  x.data,
+		}
+	}
+	n := *t
+	n.root = copyTree(t.root)
+	return &n
+}
+
+func (t *ScapegoatSet[T]) Iter() IteratorOf[T] {
+	it := NewScapegoatSetIter[T]()
+	return it.HookWith(t)
+}
+
+//yield items of subtree n in order, dir is Left for ascending order,
+//Right for descending order
+func (t *ScapegoatSet[T]) walk(n *sgnode[T], dir int, yield func(T) bool) bool {
+	for ; n != nil; n = n.links[1-dir] {
+		if !t.walk(n.links[dir], dir, yield) || !yield(n.data) {
+			return false
+		}
+	}
+	return true
+}
+
+//sequence of all items in ascending order
+func (t *ScapegoatSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(t.root, Left, yield)
+	}
+}
+
+//sequence of all items in descending order
+func (t *ScapegoatSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(t.root, Right, yield)
+	}
+}
+
+//iterator of ScapegoatSet, it keeps the current node only,
+//each move searches the neighbour of current item from root in O(log n),
+//so it stays valid while tree changes and follows the key of a deleted item
+type ScapegoatSetIter[T any] struct {
+	tree *ScapegoatSet[T] //the tree be iterated
+	node *sgnode[T]       //current node in tree
+}
+
+func NewScapegoatSetIter[T any]() *ScapegoatSetIter[T] {
+	return &ScapegoatSetIter[T]{}
+}
+
+func (it *ScapegoatSetIter[T]) HookWith(tree *ScapegoatSet[T]) *ScapegoatSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	return it
+}
+
+//move to n and return its item
+func (it *ScapegoatSetIter[T]) moveTo(n *sgnode[T]) (item T, ok bool) {
+	it.node = n
+	if n == nil {
+		return
+	}
+	return n.data, true
+}
+
+func (it *ScapegoatSetIter[T]) First() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.extreme(Left))
+}
+
+func (it *ScapegoatSetIter[T]) Last() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.extreme(Right))
+}
+
+func (it *ScapegoatSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.find(item))
+}
+
+//move to the successor, or to the first item from nil position
+func (it *ScapegoatSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.First()
+	}
+	return it.moveTo(it.tree.near(it.node.data, Right))
+}
+
+//move to the predecessor, or to the last item from nil position
+func (it *ScapegoatSetIter[T]) Prev() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.Last()
+	}
+	return it.moveTo(it.tree.near(it.node.data, Left))
+}
+
+func (it *ScapegoatSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//replace current item with new, which must have the same key
+//return the old item
+func (it *ScapegoatSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	old := it.node.data
+	it.node.data = new
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *ScapegoatSetIter[T]) Delete() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	//nodes are never copied, so the successor node survives deletion
+	next := it.tree.near(it.node.data, Right)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(next)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *ScapegoatSetIter[T]) DeletePrev() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	prev := it.tree.near(it.node.data, Left)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(prev)
+}
+
+func (it *ScapegoatSetIter[T]) CopyFrom(other *ScapegoatSetIter[T]) (item T, ok bool) {
+	if it == nil || other == nil {
+		return
+	}
+	it.tree = other.tree
+	return it.moveTo(other.node)
+}
+
+//insert item in tree and move to it, or to the equal item already in tree
+//return true if item was inserted
+func (it *ScapegoatSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
+		return nil, false
+	}
+	n, ok := it.tree.insert(item)
+	it.node = n
+	return &n.data, ok
+}
@@ -0,0 +1,74 @@
+package bbst
+
+//ScapegoatTree is the interface{} flavour of ScapegoatSet
+type ScapegoatTree struct {
+	compatTree[ScapegoatSet[Item], *ScapegoatSet[Item]]
+}
+
+func NewScapegoatTree(cmp Compare, extra interface{}) *ScapegoatTree {
+	if cmp == nil {
+		return nil
+	}
+	return &ScapegoatTree{compatTree[ScapegoatSet[Item], *ScapegoatSet[Item]]{*NewScapegoatSet(compareOf(cmp, extra))}}
+}
+
+//set weight balance factor of tree, alpha out of [0.5, 1) is ignored
+func (t *ScapegoatTree) SetAlpha(alpha float64) {
+	if t == nil {
+		return
+	}
+	t.set.SetAlpha(alpha)
+}
+
+//rebuild the whole tree perfectly balanced
+func (t *ScapegoatTree) Rebuild() {
+	if t == nil {
+		return
+	}
+	t.set.Rebuild()
+}
+
+//rebuild the subtree rooted at the node of item perfectly balanced
+//return false if item is not in tree
+func (t *ScapegoatTree) RebuildAt(item Item) bool {
+	if t == nil || item == nil {
+		return false
+	}
+	return t.set.RebuildAt(item)
+}
+
+func (t *ScapegoatTree) Copy() *ScapegoatTree {
+	if t == nil {
+		return nil
+	}
+	return &ScapegoatTree{compatTree[ScapegoatSet[Item], *ScapegoatSet[Item]]{*t.set.Copy()}}
+}
+
+func (t *ScapegoatTree) Iter() Iterator {
+	it := NewScapegoatIter()
+	return it.HookWith(t)
+}
+
+type ScapegoatIter struct {
+	compatIter[ScapegoatSetIter[Item], *ScapegoatSetIter[Item]]
+}
+
+func NewScapegoatIter() *ScapegoatIter {
+	return &ScapegoatIter{}
+}
+
+func (it *ScapegoatIter) HookWith(tree *ScapegoatTree) *ScapegoatIter {
+	if it == nil {
+		return nil
+	}
+	it.it.HookWith(&tree.set)
+	return it
+}
+
+func (it *ScapegoatIter) CopyFrom(other *ScapegoatIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.it.CopyFrom(&other.it)
+	return item
+}
@@ -0,0 +1,127 @@
+package bbst
+
+import (
+	"math"
+	"testing"
+)
+
+//check order of subtree n, whose items are in min...max
+//append its nodes in order to nodes, return height of n
+func recurseVerifyScapegoatTree(t *testing.T, n *sgnode[Item], ok *bool, nodes *[]*sgnode[Item], min, max int) int {
+	if n == nil {
+		return 0
+	}
+	d := n.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	lh := recurseVerifyScapegoatTree(t, n.links[Left], ok, nodes, min, d-1)
+	*nodes = append(*nodes, n)
+	rh := recurseVerifyScapegoatTree(t, n.links[Right], ok, nodes, d+1, max)
+	if lh > rh {
+		return lh + 1
+	}
+	return rh + 1
+}
+
+func verifyScapegoatTree(t *testing.T, tree *ScapegoatTree, arr []int) bool {
+	ok := true
+	if tree.Count() != len(arr) {
+		t.Errorf("Tree count is %d, but should be %d.\n", tree.Count(), len(arr))
+		return false
+	}
+	var nodes []*sgnode[Item]
+	height := recurseVerifyScapegoatTree(t, tree.set.root, &ok, &nodes, 0, int(^uint(0)>>1))
+	if limit := tree.set.maxDepth(tree.set.maxCount) + 1; height > limit {
+		t.Errorf("Tree height is %d, but should not exceed %d.\n", height, limit)
+		ok = false
+	}
+	if !ok {
+		return false
+	}
+	if len(nodes) != len(arr) {
+		t.Errorf("Tree has %d nodes, but should have %d.\n", len(nodes), len(arr))
+		return false
+	}
+	for _, elem := range arr {
+		if tree.Find(elem) == nil {
+			t.Errorf("Tree does not contain expected value %d.\n", elem)
+			ok = false
+		}
+	}
+	return ok && verifyIntTraversal(t, tree.Iter(), nodes, func(n *sgnode[Item]) Item {
+		return n.data
+	})
+}
+
+func TestScapegoat(t *testing.T) {
+	for _, alpha := range []float64{0.5, 0.6, 0.75, 0.9} {
+		tree := NewScapegoatTree(intCmp, nil)
+		tree.SetAlpha(alpha)
+		for i, elem := range insertArr {
+			tree.Insert(elem)
+			if !verifyScapegoatTree(t, tree, insertArr[:i+1]) {
+				t.Errorf("Inserting %d with alpha %v failed.\n", elem, alpha)
+				return
+			}
+		}
+		for i, elem := range deleteArr {
+			tree.Delete(elem)
+			if !verifyScapegoatTree(t, tree, deleteArr[i+1:]) {
+				t.Errorf("Deleting %d with alpha %v failed.\n", elem, alpha)
+				return
+			}
+		}
+	}
+
+	tree := NewScapegoatTree(intCmp, nil)
+	tree.SetAlpha(0.99)
+	for _, elem := range insertArr {
+		tree.Insert(elem)
+	}
+	tree.Rebuild()
+	height := 0
+	for n := len(insertArr); n > 0; n /= 2 {
+		height++
+	}
+	if h := recurseVerifyScapegoatTree(t, tree.set.root, new(bool), new([]*sgnode[Item]), 0, len(insertArr)); h != height {
+		t.Errorf("Rebuilt tree has height %d, but should be %d.\n", h, height)
+	}
+	verifyScapegoatTree(t, tree, insertArr)
+
+	//ascending inserts with alpha near 1 leave a path, rebuilding at its top balances it
+	tree = NewScapegoatTree(intCmp, nil)
+	tree.SetAlpha(0.99)
+	for i := 0; i < 100; i++ {
+		tree.Insert(i)
+	}
+	top := tree.set.root.links[Right].data
+	if !tree.RebuildAt(top) || tree.RebuildAt(100) || tree.RebuildAt(nil) {
+		t.Errorf("RebuildAt reports wrong result.\n")
+	}
+	if h := recurseVerifyScapegoatTree(t, tree.set.root.links[Right], new(bool), new([]*sgnode[Item]), 0, 100); h != 7 {
+		t.Errorf("Subtree rebuilt at %v has height %d, but should be 7.\n", top, h)
+	}
+	verifyScapegoatTree(t, tree, intRange(0, 100))
+}
+
+func TestScapegoatAlpha(t *testing.T) {
+	const alpha = 0.55
+	tree := NewScapegoatTree(intCmp, nil)
+	tree.SetAlpha(alpha)
+	tree.SetAlpha(1)
+	tree.SetAlpha(0.4)
+	if tree.set.alpha != alpha {
+		t.Fatalf("Alpha is %v, but should be %v.\n", tree.set.alpha, alpha)
+	}
+	//ascending inserts always go deepest, every one of them is bounded by log(n) to base 1/alpha
+	for i := 0; i < 2000; i++ {
+		tree.Insert(i)
+		limit := int(math.Log(float64(i+1))/math.Log(1/alpha)+1e-9) + 1
+		if h := recurseVerifyScapegoatTree(t, tree.set.root, new(bool), new([]*sgnode[Item]), 0, i); h > limit {
+			t.Fatalf("Tree of %d items has height %d, but should not exceed %d.\n", i+1, h, limit)
+		}
+	}
+	verifyScapegoatTree(t, tree, intRange(0, 2000))
+}
@@ -0,0 +1,84 @@
+package bbst
+
+import (
+	"iter"
+)
+
+//iterator able to seek by key, implemented by the iterators of every tree
+type seekIterOf[T any] interface {
+	IteratorOf[T]
+	SeekGE(item T) (found T, ok bool)
+	SeekLE(item T) (found T, ok bool)
+}
+
+//sequences of every set
+type seqTab[T any] interface {
+	All() iter.Seq[T]
+	Backward() iter.Seq[T]
+	Ascend(from T) iter.Seq[T]
+	Descend(from T) iter.Seq[T]
+	Between(lo, hi T) iter.Seq[T]
+}
+
+//sequence of no item
+func empty[T any](yield func(T) bool) {}
+
+//sequence of no pair
+func empty2[K, V any](yield func(K, V) bool) {}
+
+//turn a sequence of entries into a sequence of key value pairs
+func pairs[K, V any](seq iter.Seq[Entry[K, V]]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := range seq {
+			if !yield(e.Key, e.Value) {
+				return
+			}
+		}
+	}
+}
+
+//yield items from the one start returns on, moving by step,
+//until there is no item or yield returns false
+func walk[T any](start, step func() (T, bool), yield func(T) bool) {
+	for item, ok := start(); ok && yield(item); item, ok = step() {
+	}
+}
+
+//every range over a sequence below hooks a new iterator made by newIter,
+//so a sequence can be ranged over many times
+
+func seqAll[T any](newIter func() seekIterOf[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it := newIter()
+		walk(it.First, it.Next, yield)
+	}
+}
+
+func seqBackward[T any](newIter func() seekIterOf[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it := newIter()
+		walk(it.Last, it.Prev, yield)
+	}
+}
+
+func seqAscend[T any](newIter func() seekIterOf[T], from T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it := newIter()
+		walk(func() (T, bool) { return it.SeekGE(from) }, it.Next, yield)
+	}
+}
+
+func seqDescend[T any](newIter func() seekIterOf[T], from T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it := newIter()
+		walk(func() (T, bool) { return it.SeekLE(from) }, it.Prev, yield)
+	}
+}
+
+func seqBetween[T any](newIter func() seekIterOf[T], cmp func(a, b T) int, lo, hi T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it := newIter()
+		for item, ok := it.SeekGE(lo); ok && cmp(item, hi) < 0 && yield(item); item, ok = it.Next() {
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+type splaynode[T any] struct {
+	links [ChildNum]*splaynode[T] //child node
+	data  T                       //data item
+}
+
+//SplaySet is a top-down splay tree, see Sleator and Tarjan, Self-Adjusting Binary Search Trees,
+//every access splays the item to root, so recently used items are found quickly,
+//operations are O(log n) amortized, but a single one may take O(n),
+//Find and iterator moves change the tree too, so concurrent readers need a write lock
+type SplaySet[T any] struct {
+	root    *splaynode[T]    //root of  tree
+	cmpFunc func(a, b T) int //compare function
+	count   int              // number of item in tree
+}
+
+func NewSplaySet[T any](cmp func(a, b T) int) *SplaySet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &SplaySet[T]{cmpFunc: cmp}
+}
+
+func NewOrderedSplaySet[T cmp.Ordered]() *SplaySet[T] {
+	return NewSplaySet(cmp.Compare[T])
+}
+
+//reads splay the tree, so ConcurrentSymTab takes the write lock for them
+func (t *SplaySet[T]) readsMutate() bool {
+	return true
+}
+
+func (t *SplaySet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+//move the node of item to root,
+//or the last node visited searching item if there is none
+func (t *SplaySet[T]) splay(item T) {
+	if t.root == nil {
+		return
+	}
+	var (
+		header splaynode[T]  //header.links[Right] is the left tree, header.links[Left] the right tree
+		l      *splaynode[T] //the greatest node of left tree
+		r      *splaynode[T] //the least node of right tree
+		x      *splaynode[T] //root of middle tree
+	)
+	l, r, x = &header, &header, t.root
+	for {
+		cmp := t.cmpFunc(item, x.data)
+		if cmp < 0 {
+			y := x.links[Left]
+			if y == nil {
+				break
+			}
+			if t.cmpFunc(item, y.data) < 0 {
+				//zig-zig, rotate right
+				x.links[Left] = y.links[Right]
+				y.links[Right] = x
+				x = y
+				if x.links[Left] == nil {
+					break
+				}
+			}
+			//link x to right tree
+			r.links[Left] = x
+			r = x
+			x = x.links[Left]
+		} else if cmp > 0 {
+			y := x.links[Right]
+			if y == nil {
+				break
+			}
+			if t.cmpFunc(item, y.data) > 0 {
+				//zag-zag, rotate left
+				x.links[Right] = y.links[Left]
+				y.links[Left] = x
+				x = y
+				if x.links[Right] == nil {
+					break
+				}
+			}
+			//link x to left tree
+			l.links[Right] = x
+			l = x
+			x = x.links[Right]
+		} else {
+			break
+		}
+	}
+	//assemble left, middle and right trees
+	l.links[Right] = x.links[Left]
+	r.links[Left] = x.links[Right]
+	x.links[Left] = header.links[Right]
+	x.links[Right] = header.links[Left]
+	t.root = x
+}
+
+//splay target to root, return its node, nil if there is none
+func (t *SplaySet[T]) find(target T) *splaynode[T] {
+	t.splay(target)
+	if t.root == nil || t.cmpFunc(target, t.root.data) != 0 {
+		return nil
+	}
+	return t.root
+}
+
+//search target in tree, the found item becomes root
+//return found item and true if find it
+//else ok is false
+func (t *SplaySet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	if n := t.find(target); n != nil {
+		return n.data, true
+	}
+	return
+}
+
+//insert item in tree, the item becomes root
+//return node of item and true if inserted,
+//node of the equal item and false if item already in tree
+func (t *SplaySet[T]) insert(item T) (*splaynode[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	t.splay(item)
+	n := &splaynode[T]{data: item}
+	if t.root != nil {
+		cmp := t.cmpFunc(item, t.root.data)
+		if cmp == 0 {
+			return t.root, false
+		}
+		//root is the neighbour of item, split tree at it
+		dir, rdir := Left, Right
+		if cmp > 0 {
+			dir, rdir = Right, Left
+		}
+		n.links[dir] = t.root.links[dir]
+		n.links[rdir] = t.root
+		t.root.links[dir] = nil
+	}
+	t.root = n
+	t.count++
+	return n, true
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *SplaySet[T]) Insert(item T) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+func (t *SplaySet[T]) Replace(item T) (old T, ok bool) {
+	n, succ := t.insert(item)
+	if n == nil || succ {
+		return
+	}
+	r := n.data
+	n.data = item
+	return r, true
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *SplaySet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	n := t.find(item)
+	if n == nil {
+		return
+	}
+	if n.links[Left] == nil {
+		t.root = n.links[Right]
+	} else {
+		//all of left subtree is less than item, so splay brings its greatest node to root
+		t.root = n.links[Left]
+		t.splay(item)
+		t.root.links[Right] = n.links[Right]
+	}
+	t.count--
+	return n.data, true
+}
+
+//splay the first (dir is Left) or the last (dir is Right) node to root,
+//return it, nil if tree is empty
+func (t *SplaySet[T]) extreme(dir int) *splaynode[T] {
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	for w.links[dir] != nil {
+		w = w.links[dir]
+	}
+	t.splay(w.data)
+	return w
+}
+
+//return the neighbour of n in direction dir, nil if there is none,
+//n need not be in tree any more, its key is searched
+func (t *SplaySet[T]) near(n *splaynode[T], dir int) *splaynode[T] {
+	t.splay(n.data)
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	cmp := t.cmpFunc(w.data, n.data)
+	if dir == Right && cmp > 0 || dir == Left && cmp < 0 {
+		return w
+	}
+	if w = w.links[dir]; w == nil {
+		return nil
+	}
+	for w.links[1-dir] != nil {
+		w = w.links[1-dir]
+	}
+	return w
+}
+
+func (t *SplaySet[T]) Copy() *SplaySet[T] {
+	if t == nil {
+		return nil
+	}
+	n := NewSplaySet(t.cmpFunc)
+	if n == nil {
+		return nil
+	}
+	n.count = t.count
+	//a splay tree may be a path of n nodes, so copy without recursion,
+	//nodes on stack are copied already, their right subtrees not yet
+	type pair struct{ src, dst *splaynode[T] }
+	var stack []pair
+	dst := &n.root
+	for x := t.root; x != nil || len(stack) > 0; {
+		if x == nil {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, dst = p.src.links[Right], &p.dst.links[Right]
+			continue
+		}
+		*dst = &splaynode[T]{data: x.data}
+		stack = append(stack, pair{x, *dst})
+		x, dst = x.links[Left], &(*dst).links[Left]
+	}
+	return n
+}
+
+func (t *SplaySet[T]) Iter() IteratorOf[T] {
+	it := NewSplaySetIter[T]()
+	return it.HookWith(t)
+}
+
+//yield items of tree in order, dir is Left for ascending order,
+//Right for descending order, tree is not splayed
+func (t *SplaySet[T]) walk(dir int, yield func(T) bool) {
+	//a splay tree may be a path of n nodes, so keep an explicit stack
+	var stack []*splaynode[T]
+	for w := t.root; w != nil || len(stack) > 0; {
+		for ; w != nil; w = w.links[dir] {
+			stack = append(stack, w)
+		}
+		w = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !yield(w.data) {
+			return
+		}
+		w = w.links[1-dir]
+	}
+}
+
+//sequence of all items in ascending order,
+//it does not splay, so tree must not change during the loop
+func (t *SplaySet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(Left, yield)
+	}
+}
+
+//sequence of all items in descending order,
+//it does not splay, so tree must not change during the loop
+func (t *SplaySet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(Right, yield)
+	}
+}
+
+//iterator of SplaySet, it keeps the current node only,
+//each move splays current item and steps to its neighbour,
+//so a full traversal is O(n), and the iterator follows the key of a deleted item
+type SplaySetIter[T any] struct {
+	tree *SplaySet[T]  //the tree be iterated
+	node *splaynode[T] //current node in tree
+}
+
+func NewSplaySetIter[T any]() *SplaySetIter[T] {
+	return &SplaySetIter[T]{}
+}
+
+func (it *SplaySetIter[T]) HookWith(tree *SplaySet[T]) *SplaySetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	return it
+}
+
+//move to n and return its item
+func (it *SplaySetIter[T]) moveTo(n *splaynode[T]) (item T, ok bool) {
+	it.node = n
+	if n == nil {
+		return
+	}
+	return n.data, true
+}
+
+func (it *SplaySetIter[T]) First() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.extreme(Left))
+}
+
+func (it *SplaySetIter[T]) Last() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.extreme(Right))
+}
+
+func (it *SplaySetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.find(item))
+}
+
+//move to the successor, or to the first item from nil position
+func (it *SplaySetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.First()
+	}
+	return it.moveTo(it.tree.near(it.node, Right))
+}
+
+//move to the predecessor, or to the last item from nil position
+func (it *SplaySetIter[T]) Prev() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.Last()
+	}
+	return it.moveTo(it.tree.near(it.node, Left))
+}
+
+func (it *SplaySetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//replace current item with new, which must have the same key
+//return the old item
+func (it *SplaySetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	old := it.node.data
+	it.node.data = new
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *SplaySetIter[T]) Delete() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	//nodes are never copied, so the successor node survives deletion
+	next := it.tree.near(it.node, Right)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(next)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *SplaySetIter[T]) DeletePrev() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	prev := it.tree.near(it.node, Left)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(prev)
+}
+
+func (it *SplaySetIter[T]) CopyFrom(other *SplaySetIter[T]) (item T, ok bool) {
+	if it == nil || other == nil {
+		return
+	}
+	it.tree = other.tree
+	return it.moveTo(other.node)
+}
+
+//insert item in tree and move to it, or to the equal item already in tree
+//return true if item was inserted
+func (it *SplaySetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
+		return nil, false
+	}
+	n, ok := it.tree.insert(item)
+	it.node = n
+	return &n.data, ok
+}
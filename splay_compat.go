@@ -0,0 +1,50 @@
+package bbst
+
+//SplayTree is the interface{} flavour of SplaySet,
+//its reads splay it as well
+type SplayTree struct {
+	compatTree[SplaySet[Item], *SplaySet[Item]]
+}
+
+func NewSplayTree(cmp Compare, extra interface{}) *SplayTree {
+	if cmp == nil {
+		return nil
+	}
+	return &SplayTree{compatTree[SplaySet[Item], *SplaySet[Item]]{*NewSplaySet(compareOf(cmp, extra))}}
+}
+
+func (t *SplayTree) Copy() *SplayTree {
+	if t == nil {
+		return nil
+	}
+	return &SplayTree{compatTree[SplaySet[Item], *SplaySet[Item]]{*t.set.Copy()}}
+}
+
+func (t *SplayTree) Iter() Iterator {
+	it := NewSplayIter()
+	return it.HookWith(t)
+}
+
+type SplayIter struct {
+	compatIter[SplaySetIter[Item], *SplaySetIter[Item]]
+}
+
+func NewSplayIter() *SplayIter {
+	return &SplayIter{}
+}
+
+func (it *SplayIter) HookWith(tree *SplayTree) *SplayIter {
+	if it == nil {
+		return nil
+	}
+	it.it.HookWith(&tree.set)
+	return it
+}
+
+func (it *SplayIter) CopyFrom(other *SplayIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.it.CopyFrom(&other.it)
+	return item
+}
@@ -0,0 +1,47 @@
+package bbst
+
+import (
+	"testing"
+)
+
+//check order of subtree n, whose items are in min...max
+//append its nodes in order to nodes
+func recurseVerifySplayTree(t *testing.T, n *splaynode[Item], ok *bool, nodes *[]*splaynode[Item], min, max int) {
+	if n == nil {
+		return
+	}
+	d := n.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	recurseVerifySplayTree(t, n.links[Left], ok, nodes, min, d-1)
+	*nodes = append(*nodes, n)
+	recurseVerifySplayTree(t, n.links[Right], ok, nodes, d+1, max)
+}
+
+func verifySplayTree(t *testing.T, tree *SplayTree, arr []int) bool {
+	ok := true
+	if tree.Count() != len(arr) {
+		t.Errorf("Tree count is %d, but should be %d.\n", tree.Count(), len(arr))
+		return false
+	}
+	var nodes []*splaynode[Item]
+	recurseVerifySplayTree(t, tree.set.root, &ok, &nodes, 0, int(^uint(0)>>1))
+	if len(nodes) != len(arr) {
+		t.Errorf("Tree has %d nodes, but should have %d.\n", len(nodes), len(arr))
+		return false
+	}
+	for _, elem := range arr {
+		if tree.Find(elem) == nil {
+			t.Errorf("Tree does not contain expected value %d.\n", elem)
+			ok = false
+		} else if tree.set.root.data != elem {
+			t.Errorf("Found value %d is not splayed to root.\n", elem)
+			ok = false
+		}
+	}
+	return ok && verifyIntTraversal(t, tree.Iter(), nodes, func(n *splaynode[Item]) Item {
+		return n.data
+	})
+}
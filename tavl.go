@@ -0,0 +1,714 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+//right threaded avl tree, see libavl rtavl.c
+//a node without right child keeps a thread to its successor in its right link,
+//so iterators need no stack and are a single node pointer,
+//nodes are relinked but never copied or moved in memory by Insert and Delete,
+//thus an iterator stays valid while other items change
+type tnode[T any] struct {
+	links   [ChildNum]*tnode[T] //left child, right child or thread to successor
+	data    T                   //data item
+	balance int8                //balance factor
+	rtag    bool                //links[Right] is a thread, not a child
+}
+
+//in-order successor of n, nil if n is the last node
+func (n *tnode[T]) next() *tnode[T] {
+	if n.rtag {
+		return n.links[Right]
+	}
+	n = n.links[Right]
+	for n.links[Left] != nil {
+		n = n.links[Left]
+	}
+	return n
+}
+
+//the last node of subtree rooted at n
+func (n *tnode[T]) last() *tnode[T] {
+	for !n.rtag {
+		n = n.links[Right]
+	}
+	return n
+}
+
+type TAvlSet[T any] struct {
+	root    *tnode[T]        //root of  tree
+	cmpFunc func(a, b T) int //compare function
+	count   int              // number of item in tree
+}
+
+func NewTAvlSet[T any](cmp func(a, b T) int) *TAvlSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &TAvlSet[T]{cmpFunc: cmp}
+}
+
+func NewOrderedTAvlSet[T cmp.Ordered]() *TAvlSet[T] {
+	return NewTAvlSet(cmp.Compare[T])
+}
+
+func (t *TAvlSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+//return node of target, nil if there is none
+func (t *TAvlSet[T]) find(target T) *tnode[T] {
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(target, w.data)
+		if cmp == 0 {
+			return w
+		} else if cmp < 0 {
+			w = w.links[Left]
+		} else if w.rtag {
+			return nil
+		} else {
+			w = w.links[Right]
+		}
+	}
+	return nil
+}
+
+//search target in tree
+//return found item and true if find it
+//else ok is false
+func (t *TAvlSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.find(target); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//in-order predecessor of n, nil if n is the first node
+//without left threads it is searched from root when n has no left child
+func (t *TAvlSet[T]) prev(n *tnode[T]) *tnode[T] {
+	if n.links[Left] != nil {
+		return n.links[Left].last()
+	}
+	var p *tnode[T]
+	for w := t.root; w != nil && w != n; {
+		if t.cmpFunc(n.data, w.data) > 0 {
+			p = w
+			if w.rtag {
+				break
+			}
+			w = w.links[Right]
+		} else {
+			w = w.links[Left]
+		}
+	}
+	return p
+}
+
+//insert item in tree
+//return node of item and true if inserted,
+//node of the equal item and false if item already in tree
+func (t *TAvlSet[T]) insert(item T) (*tnode[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	var (
+		y   *tnode[T]          //待更新平衡因子的最顶层节点
+		z   *tnode[T]          //y's  parent
+		p   *tnode[T]          //current walk node
+		q   *tnode[T]          //p's parent
+		n   *tnode[T]          //new node
+		w   *tnode[T]          //new root node of rebalanced subtree
+		dir int                //下降方向
+		da  [avlMaxHeight]byte //缓存的下降方向数组
+		k   int                //length of da
+	)
+	//header node stands for parent of root, its left link is root
+	head := tnode[T]{links: [ChildNum]*tnode[T]{Left: t.root}}
+	z = &head
+	y = t.root
+	if t.root != nil {
+		for q, p = z, y; ; q, p = p, p.links[dir] {
+			cmp := t.cmpFunc(item, p.data)
+			if cmp == 0 {
+				return p, false
+			}
+			if p.balance != 0 {
+				z = q
+				y = p
+				k = 0
+			}
+			if cmp > 0 {
+				dir = Right
+			} else {
+				dir = Left
+			}
+			da[k] = byte(dir)
+			k++
+			if dir == Left && p.links[Left] == nil || dir == Right && p.rtag {
+				break
+			}
+		}
+	} else {
+		p = z
+		dir = Left
+	}
+	n = &tnode[T]{data: item, rtag: true}
+	if dir == Left {
+		//successor of n is p
+		if t.root != nil {
+			n.links[Right] = p
+		}
+	} else {
+		//n takes over thread of p
+		n.links[Right] = p.links[Right]
+		p.rtag = false
+	}
+	p.links[dir] = n
+	t.root = head.links[Left]
+	t.count++
+	if t.root == n {
+		return n, true
+	}
+	for p, k = y, 0; p != n; p, k = p.links[da[k]], k+1 {
+		if da[k] == Left {
+			p.balance--
+		} else {
+			p.balance++
+		}
+	}
+	if y.balance == -2 {
+		x := y.links[Left]
+		if x.balance == -1 {
+			w = x
+			if x.rtag {
+				x.rtag = false
+				y.links[Left] = nil
+			} else {
+				y.links[Left] = x.links[Right]
+			}
+			x.links[Right] = y
+			x.balance = 0
+			y.balance = 0
+		} else { //x.balance == 1
+			w = x.links[Right]
+			x.links[Right] = w.links[Left]
+			w.links[Left] = x
+			y.links[Left] = w.links[Right]
+			w.links[Right] = y
+			if w.balance == -1 {
+				x.balance = 0
+				y.balance = 1
+			} else if w.balance == 0 {
+				x.balance = 0
+				y.balance = 0
+			} else {
+				x.balance = -1
+				y.balance = 0
+			}
+			w.balance = 0
+			if x.links[Right] == nil {
+				x.rtag = true
+				x.links[Right] = w
+			}
+			if w.rtag {
+				y.links[Left] = nil
+				w.rtag = false
+			}
+		}
+	} else if y.balance == 2 {
+		x := y.links[Right]
+		if x.balance == 1 {
+			w = x
+			if x.links[Left] == nil {
+				y.rtag = true
+				y.links[Right] = x
+			} else {
+				y.links[Right] = x.links[Left]
+			}
+			x.links[Left] = y
+			x.balance = 0
+			y.balance = 0
+		} else { //x.balance == -1
+			w = x.links[Left]
+			x.links[Left] = w.links[Right]
+			w.links[Right] = x
+			y.links[Right] = w.links[Left]
+			w.links[Left] = y
+			if w.balance == 1 {
+				x.balance = 0
+				y.balance = -1
+			} else if w.balance == 0 {
+				x.balance = 0
+				y.balance = 0
+			} else {
+				x.balance = 1
+				y.balance = 0
+			}
+			w.balance = 0
+			if y.links[Right] == nil {
+				y.rtag = true
+				y.links[Right] = w
+			}
+			if w.rtag {
+				x.links[Left] = nil
+				w.rtag = false
+			}
+		}
+	} else {
+		return n, true
+	}
+	if y != z.links[Left] {
+		z.links[Right] = w
+	} else {
+		z.links[Left] = w
+	}
+	t.root = head.links[Left]
+	return n, true
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *TAvlSet[T]) Insert(item T) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+func (t *TAvlSet[T]) Replace(item T) (old T, ok bool) {
+	n, succ := t.insert(item)
+	if n == nil || succ {
+		return
+	}
+	r := n.data
+	n.data = item
+	return r, true
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *TAvlSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	var (
+		pa  [avlMaxHeight]*tnode[T]
+		da  [avlMaxHeight]byte
+		k   int
+		p   *tnode[T]
+		cmp int
+	)
+	head := tnode[T]{links: [ChildNum]*tnode[T]{Left: t.root}}
+	p = &head
+	for cmp = -1; cmp != 0; cmp = t.cmpFunc(item, p.data) {
+		dir := Left
+		if cmp > 0 {
+			dir = Right
+		}
+		pa[k] = p
+		da[k] = byte(dir)
+		k++
+		if dir == Left && p.links[Left] == nil || dir == Right && p.rtag {
+			return
+		}
+		p = p.links[dir]
+	}
+	ret := p.data
+
+	if p.rtag { //case 1, p has no right child
+		if p.links[Left] != nil {
+			//predecessor of p takes over thread of p
+			p.links[Left].last().links[Right] = p.links[Right]
+			pa[k-1].links[da[k-1]] = p.links[Left]
+		} else {
+			pa[k-1].links[da[k-1]] = p.links[da[k-1]]
+			if da[k-1] == Right {
+				pa[k-1].rtag = true
+			}
+		}
+	} else { //case 2, p's right child has no left child
+		r := p.links[Right]
+		if r.links[Left] == nil {
+			r.links[Left] = p.links[Left]
+			r.balance = p.balance
+			if r.links[Left] != nil {
+				r.links[Left].last().links[Right] = r
+			}
+			pa[k-1].links[da[k-1]] = r
+			da[k] = Right
+			pa[k] = r
+			k++
+		} else { //case 3, p's right child has left child
+			var s *tnode[T]
+			j := k
+			k++
+			for {
+				da[k] = Left
+				pa[k] = r
+				k++
+				s = r.links[Left]
+				if s.links[Left] == nil {
+					break
+				}
+				r = s
+			}
+			if !s.rtag {
+				r.links[Left] = s.links[Right]
+			} else {
+				r.links[Left] = nil
+			}
+			if p.links[Left] != nil {
+				p.links[Left].last().links[Right] = s
+			}
+			s.links[Left] = p.links[Left]
+			s.links[Right] = p.links[Right]
+			s.rtag = false
+			s.balance = p.balance
+
+			pa[j-1].links[da[j-1]] = s
+			da[j] = Right
+			pa[j] = s
+		}
+	}
+
+	//删除后，更新平衡因子, 重新平衡
+	for k--; k > 0; k-- {
+		y := pa[k]
+		if da[k] == Left {
+			y.balance++
+			if y.balance == 1 {
+				break
+			} else if y.balance == 2 {
+				x := y.links[Right]
+				if x.balance == -1 {
+					w := x.links[Left]
+					x.links[Left] = w.links[Right]
+					w.links[Right] = x
+					y.links[Right] = w.links[Left]
+					w.links[Left] = y
+					if w.balance == 1 {
+						x.balance = 0
+						y.balance = -1
+					} else if w.balance == 0 {
+						x.balance = 0
+						y.balance = 0
+					} else {
+						x.balance = 1
+						y.balance = 0
+					}
+					w.balance = 0
+					if y.links[Right] == nil {
+						y.rtag = true
+						y.links[Right] = w
+					}
+					if w.rtag {
+						x.links[Left] = nil
+						w.rtag = false
+					}
+					pa[k-1].links[da[k-1]] = w
+				} else {
+					if x.links[Left] == nil {
+						y.rtag = true
+						y.links[Right] = x
+					} else {
+						y.links[Right] = x.links[Left]
+					}
+					x.links[Left] = y
+					pa[k-1].links[da[k-1]] = x
+					if x.balance == 0 {
+						x.balance = -1
+						y.balance = 1
+						break
+					} else {
+						x.balance = 0
+						y.balance = 0
+					}
+				}
+			}
+		} else {
+			y.balance--
+			if y.balance == -1 {
+				break
+			} else if y.balance == -2 {
+				x := y.links[Left]
+				if x.balance == 1 {
+					w := x.links[Right]
+					x.links[Right] = w.links[Left]
+					w.links[Left] = x
+					y.links[Left] = w.links[Right]
+					w.links[Right] = y
+					if w.balance == -1 {
+						x.balance = 0
+						y.balance = 1
+					} else if w.balance == 0 {
+						x.balance = 0
+						y.balance = 0
+					} else {
+						x.balance = -1
+						y.balance = 0
+					}
+					w.balance = 0
+					if x.links[Right] == nil {
+						x.rtag = true
+						x.links[Right] = w
+					}
+					if w.rtag {
+						y.links[Left] = nil
+						w.rtag = false
+					}
+					pa[k-1].links[da[k-1]] = w
+				} else {
+					if x.rtag {
+						x.rtag = false
+						y.links[Left] = nil
+					} else {
+						y.links[Left] = x.links[Right]
+					}
+					x.links[Right] = y
+					pa[k-1].links[da[k-1]] = x
+					if x.balance == 0 {
+						x.balance = 1
+						y.balance = -1
+						break
+					} else {
+						x.balance = 0
+						y.balance = 0
+					}
+				}
+			}
+		}
+	}
+	t.root = head.links[Left]
+	t.count--
+	return ret, true
+}
+
+func (t *TAvlSet[T]) Copy() *TAvlSet[T] {
+	if t == nil {
+		return nil
+	}
+	n := NewTAvlSet(t.cmpFunc)
+	if n == nil {
+		return nil
+	}
+	n.count = t.count
+	//the last copied node, its thread points to the next copied one
+	var last *tnode[T]
+	var copyTree func(x *tnode[T]) *tnode[T]
+	copyTree = func(x *tnode[T]) *tnode[T] {
+		y := &tnode[T]{data: x.data, balance: x.balance, rtag: x.rtag}
+		if x.links[Left] != nil {
+			y.links[Left] = copyTree(x.links[Left])
+		}
+		if last != nil && last.rtag {
+			last.links[Right] = y
+		}
+		last = y
+		if !x.rtag {
+			y.links[Right] = copyTree(x.links[Right])
+		}
+		return y
+	}
+	if t.root != nil {
+		n.root = copyTree(t.root)
+	}
+	return n
+}
+
+func (t *TAvlSet[T]) Iter() IteratorOf[T] {
+	it := NewTAvlSetIter[T]()
+	return it.HookWith(t)
+}
+
+//sequence of all items in ascending order, threads are followed without stack
+func (t *TAvlSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		for w := t.first(); w != nil; w = w.next() {
+			if !yield(w.data) {
+				return
+			}
+		}
+	}
+}
+
+//sequence of all items in descending order
+func (t *TAvlSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		//walk right subtree, node, then left subtree, no thread helps here
+		var walk func(n *tnode[T]) bool
+		walk = func(n *tnode[T]) bool {
+			if n == nil {
+				return true
+			}
+			if !n.rtag && !walk(n.links[Right]) {
+				return false
+			}
+			return yield(n.data) && walk(n.links[Left])
+		}
+		walk(t.root)
+	}
+}
+
+//the first node of tree, nil if tree is empty
+func (t *TAvlSet[T]) first() *tnode[T] {
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	for w.links[Left] != nil {
+		w = w.links[Left]
+	}
+	return w
+}
+
+//iterator of TAvlSet, it is just a node pointer,
+//it stays valid while other items are inserted and deleted
+type TAvlSetIter[T any] struct {
+	tree *TAvlSet[T] //the tree be iterated
+	node *tnode[T]   //current node in tree
+}
+
+func NewTAvlSetIter[T any]() *TAvlSetIter[T] {
+	return &TAvlSetIter[T]{}
+}
+
+func (it *TAvlSetIter[T]) HookWith(tree *TAvlSet[T]) *TAvlSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	return it
+}
+
+//move to n and return its item
+func (it *TAvlSetIter[T]) moveTo(n *tnode[T]) (item T, ok bool) {
+	it.node = n
+	if n == nil {
+		return
+	}
+	return n.data, true
+}
+
+func (it *TAvlSetIter[T]) First() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.first())
+}
+
+func (it *TAvlSetIter[T]) Last() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.tree.root == nil {
+		return it.moveTo(nil)
+	}
+	return it.moveTo(it.tree.root.last())
+}
+
+func (it *TAvlSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.find(item))
+}
+
+//move to the successor, or to the first item from nil position
+func (it *TAvlSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.First()
+	}
+	return it.moveTo(it.node.next())
+}
+
+//move to the predecessor, or to the last item from nil position,
+//O(log n) when current node has no left child
+func (it *TAvlSetIter[T]) Prev() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.Last()
+	}
+	return it.moveTo(it.tree.prev(it.node))
+}
+
+func (it *TAvlSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//replace current item with new, which must have the same key
+//return the old item
+func (it *TAvlSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	old := it.node.data
+	it.node.data = new
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *TAvlSetIter[T]) Delete() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	//nodes are never moved, so the successor node survives deletion
+	next := it.node.next()
+	it.tree.Delete(it.node.data)
+	return it.moveTo(next)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *TAvlSetIter[T]) DeletePrev() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	prev := it.tree.prev(it.node)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(prev)
+}
+
+func (it *TAvlSetIter[T]) CopyFrom(other *TAvlSetIter[T]) (item T, ok bool) {
+	if it == nil || other == nil {
+		return
+	}
+	it.tree = other.tree
+	return it.moveTo(other.node)
+}
+
+//insert item in tree and move to it, or to the equal item already in tree
+//return true if item was inserted
+func (it *TAvlSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
+		return nil, false
+	}
+	n, ok := it.tree.insert(item)
+	it.node = n
+	return &n.data, ok
+}
@@ -0,0 +1,49 @@
+package bbst
+
+//TAvlTree is the interface{} flavour of TAvlSet
+type TAvlTree struct {
+	compatTree[TAvlSet[Item], *TAvlSet[Item]]
+}
+
+func NewTAvlTree(cmp Compare, extra interface{}) *TAvlTree {
+	if cmp == nil {
+		return nil
+	}
+	return &TAvlTree{compatTree[TAvlSet[Item], *TAvlSet[Item]]{*NewTAvlSet(compareOf(cmp, extra))}}
+}
+
+func (t *TAvlTree) Copy() *TAvlTree {
+	if t == nil {
+		return nil
+	}
+	return &TAvlTree{compatTree[TAvlSet[Item], *TAvlSet[Item]]{*t.set.Copy()}}
+}
+
+func (t *TAvlTree) Iter() Iterator {
+	it := NewTAvlIter()
+	return it.HookWith(t)
+}
+
+type TAvlIter struct {
+	compatIter[TAvlSetIter[Item], *TAvlSetIter[Item]]
+}
+
+func NewTAvlIter() *TAvlIter {
+	return &TAvlIter{}
+}
+
+func (it *TAvlIter) HookWith(tree *TAvlTree) *TAvlIter {
+	if it == nil {
+		return nil
+	}
+	it.it.HookWith(&tree.set)
+	return it
+}
+
+func (it *TAvlIter) CopyFrom(other *TAvlIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.it.CopyFrom(&other.it)
+	return item
+}
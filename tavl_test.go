@@ -0,0 +1,81 @@
+package bbst
+
+import (
+	"testing"
+)
+
+//check order and balance factors of subtree n, whose items are in min...max
+//append its nodes in order to nodes, return height of n
+func recurseVerifyTAvlTree(t *testing.T, n *tnode[Item], ok *bool, nodes *[]*tnode[Item], min, max int) int {
+	if n == nil {
+		return 0
+	}
+	d := n.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	lh := recurseVerifyTAvlTree(t, n.links[Left], ok, nodes, min, d-1)
+	*nodes = append(*nodes, n)
+	rh := 0
+	if !n.rtag {
+		if n.links[Right] == nil {
+			t.Errorf("Node %d has nil right child.\n", d)
+			*ok = false
+		}
+		rh = recurseVerifyTAvlTree(t, n.links[Right], ok, nodes, d+1, max)
+	}
+	if rh-lh != int(n.balance) {
+		t.Errorf("Balance factor of node %d is %d, but should be %d.\n", d, n.balance, rh-lh)
+		*ok = false
+	}
+	if lh > rh {
+		return lh + 1
+	}
+	return rh + 1
+}
+
+//check every thread points to the successor of its node
+func verifyThreads[N any](t *testing.T, nodes []N, data func(n N) int, thread func(n N) (N, bool)) bool {
+	ok := true
+	var null N
+	for i, n := range nodes {
+		want := null
+		if i+1 < len(nodes) {
+			want = nodes[i+1]
+		}
+		if next, isThread := thread(n); isThread && any(next) != any(want) {
+			t.Errorf("Thread of node %d does not point to its successor.\n", data(n))
+			ok = false
+		}
+	}
+	return ok
+}
+
+func verifyTAvlTree(t *testing.T, tree *TAvlTree, arr []int) bool {
+	ok := true
+	if tree.Count() != len(arr) {
+		t.Errorf("Tree count is %d, but should be %d.\n", tree.Count(), len(arr))
+		return false
+	}
+	var nodes []*tnode[Item]
+	recurseVerifyTAvlTree(t, tree.set.root, &ok, &nodes, 0, int(^uint(0)>>1))
+	if len(nodes) != len(arr) {
+		t.Errorf("Tree has %d nodes, but should have %d.\n", len(nodes), len(arr))
+		return false
+	}
+	ok = ok && verifyThreads(t, nodes, func(n *tnode[Item]) int {
+		return n.data.(int)
+	}, func(n *tnode[Item]) (*tnode[Item], bool) {
+		return n.links[Right], n.rtag
+	})
+	for _, elem := range arr {
+		if tree.Find(elem) == nil {
+			t.Errorf("Tree does not contain expected value %d.\n", elem)
+			ok = false
+		}
+	}
+	return ok && verifyIntTraversal(t, tree.Iter(), nodes, func(n *tnode[Item]) Item {
+		return n.data
+	})
+}
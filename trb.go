@@ -0,0 +1,652 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+//right threaded red black tree, see libavl rtrb.c
+//a node without right child keeps a thread to its successor in its right link,
+//so iterators need no stack and are a single node pointer,
+//nodes are relinked but never copied or moved in memory by Insert and Delete,
+//thus an iterator stays valid while other items change
+type trbnode[T any] struct {
+	links [ChildNum]*trbnode[T] //left child, right child or thread to successor
+	data  T                     //data item
+	color byte                  //node color
+	rtag  bool                  //links[Right] is a thread, not a child
+}
+
+//in-order successor of n, nil if n is the last node
+func (n *trbnode[T]) next() *trbnode[T] {
+	if n.rtag {
+		return n.links[Right]
+	}
+	n = n.links[Right]
+	for n.links[Left] != nil {
+		n = n.links[Left]
+	}
+	return n
+}
+
+//the last node of subtree rooted at n
+func (n *trbnode[T]) last() *trbnode[T] {
+	for !n.rtag {
+		n = n.links[Right]
+	}
+	return n
+}
+
+//child of n in direction dir, nil for a thread
+func (n *trbnode[T]) child(dir int) *trbnode[T] {
+	if dir == Right && n.rtag {
+		return nil
+	}
+	return n.links[dir]
+}
+
+func (n *trbnode[T]) isRed() bool {
+	return n != nil && n.color == red
+}
+
+//rotate left at y, return the new root x of the subtree,
+//y takes a thread to x if x has no left child
+func (y *trbnode[T]) rotateLeft() *trbnode[T] {
+	x := y.links[Right]
+	if x.links[Left] == nil {
+		y.rtag = true
+		y.links[Right] = x
+	} else {
+		y.links[Right] = x.links[Left]
+	}
+	x.links[Left] = y
+	return x
+}
+
+//rotate right at y, return the new root x of the subtree,
+//the thread of x to y becomes its right child link
+func (y *trbnode[T]) rotateRight() *trbnode[T] {
+	x := y.links[Left]
+	if x.rtag {
+		x.rtag = false
+		y.links[Left] = nil
+	} else {
+		y.links[Left] = x.links[Right]
+	}
+	x.links[Right] = y
+	return x
+}
+
+type TRbSet[T any] struct {
+	root    *trbnode[T]      //root of  tree
+	cmpFunc func(a, b T) int //compare function
+	count   int              // number of item in tree
+}
+
+func NewTRbSet[T any](cmp func(a, b T) int) *TRbSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &TRbSet[T]{cmpFunc: cmp}
+}
+
+func NewOrderedTRbSet[T cmp.Ordered]() *TRbSet[T] {
+	return NewTRbSet(cmp.Compare[T])
+}
+
+func (t *TRbSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+//return node of target, nil if there is none
+func (t *TRbSet[T]) find(target T) *trbnode[T] {
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(target, w.data)
+		if cmp == 0 {
+			return w
+		} else if cmp < 0 {
+			w = w.links[Left]
+		} else if w.rtag {
+			return nil
+		} else {
+			w = w.links[Right]
+		}
+	}
+	return nil
+}
+
+//search target in tree
+//return found item and true if find it
+//else ok is false
+func (t *TRbSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.find(target); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//in-order predecessor of n, nil if n is the first node
+//without left threads it is searched from root when n has no left child
+func (t *TRbSet[T]) prev(n *trbnode[T]) *trbnode[T] {
+	if n.links[Left] != nil {
+		return n.links[Left].last()
+	}
+	var p *trbnode[T]
+	for w := t.root; w != nil && w != n; {
+		if t.cmpFunc(n.data, w.data) > 0 {
+			p = w
+			if w.rtag {
+				break
+			}
+			w = w.links[Right]
+		} else {
+			w = w.links[Left]
+		}
+	}
+	return p
+}
+
+//insert item in tree
+//return node of item and true if inserted,
+//node of the equal item and false if item already in tree
+func (t *TRbSet[T]) insert(item T) (*trbnode[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	var (
+		pa [rbMaxHeight]*trbnode[T] //stack of trbnode
+		da [rbMaxHeight]byte        //缓存的下降方向数组
+		k  int                      //length of da
+		p  *trbnode[T]              //current walk node
+		n  *trbnode[T]              //new node
+	)
+	//header node stands for parent of root, its left link is root
+	head := trbnode[T]{links: [ChildNum]*trbnode[T]{Left: t.root}}
+	pa[0] = &head
+	da[0] = Left
+	k = 1
+	p = pa[0]
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			return w, false
+		}
+		dir := Left
+		if cmp > 0 {
+			dir = Right
+		}
+		pa[k] = w
+		da[k] = byte(dir)
+		k++
+		p = w
+		w = w.child(dir)
+	}
+	n = &trbnode[T]{data: item, color: red, rtag: true}
+	if da[k-1] == Left {
+		//successor of n is p
+		if t.root != nil {
+			n.links[Right] = p
+		}
+	} else {
+		//n takes over thread of p
+		n.links[Right] = p.links[Right]
+		p.rtag = false
+	}
+	p.links[da[k-1]] = n
+	t.count++
+	for k >= 3 && pa[k-1].color == red {
+		if da[k-2] == Left {
+			//case 1, uncle y is red, push the red up
+			y := pa[k-2].child(Right)
+			if y.isRed() {
+				pa[k-1].color = black
+				y.color = black
+				pa[k-2].color = red
+				k -= 2
+			} else {
+				//case 3, n is right child of pa[k-1], convert it to case 2
+				if da[k-1] == Right {
+					pa[k-2].links[Left] = pa[k-1].rotateLeft()
+				}
+				//case 2, n is left child of pa[k-1]
+				x := pa[k-2]
+				x.color = red
+				y = x.rotateRight()
+				y.color = black
+				pa[k-3].links[da[k-3]] = y
+				break
+			}
+		} else {
+			y := pa[k-2].links[Left]
+			if y.isRed() {
+				pa[k-1].color = black
+				y.color = black
+				pa[k-2].color = red
+				k -= 2
+			} else {
+				if da[k-1] == Left {
+					pa[k-2].links[Right] = pa[k-1].rotateRight()
+				}
+				x := pa[k-2]
+				x.color = red
+				y = x.rotateLeft()
+				y.color = black
+				pa[k-3].links[da[k-3]] = y
+				break
+			}
+		}
+	}
+	t.root = head.links[Left]
+	t.root.color = black
+	return n, true
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *TRbSet[T]) Insert(item T) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+func (t *TRbSet[T]) Replace(item T) (old T, ok bool) {
+	n, succ := t.insert(item)
+	if n == nil || succ {
+		return
+	}
+	r := n.data
+	n.data = item
+	return r, true
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *TRbSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	var (
+		pa  [rbMaxHeight]*trbnode[T] //stack of trbnode
+		da  [rbMaxHeight]byte        //缓存的下降方向数组
+		k   int                      //length of da
+		p   *trbnode[T]              //node to delete
+		cmp int
+	)
+	head := trbnode[T]{links: [ChildNum]*trbnode[T]{Left: t.root}}
+	p = &head
+	for cmp = -1; cmp != 0; cmp = t.cmpFunc(item, p.data) {
+		dir := Left
+		if cmp > 0 {
+			dir = Right
+		}
+		pa[k] = p
+		da[k] = byte(dir)
+		k++
+		if p = p.child(dir); p == nil {
+			return
+		}
+	}
+	ret := p.data
+
+	if p.rtag { //case 1, p has no right child
+		if p.links[Left] != nil {
+			//predecessor of p takes over thread of p
+			p.links[Left].last().links[Right] = p.links[Right]
+			pa[k-1].links[da[k-1]] = p.links[Left]
+		} else {
+			pa[k-1].links[da[k-1]] = p.links[da[k-1]]
+			if da[k-1] == Right {
+				pa[k-1].rtag = true
+			}
+		}
+	} else { //case 2, p's right child has no left child
+		r := p.links[Right]
+		if r.links[Left] == nil {
+			r.links[Left] = p.links[Left]
+			if r.links[Left] != nil {
+				r.links[Left].last().links[Right] = r
+			}
+			r.color, p.color = p.color, r.color
+			pa[k-1].links[da[k-1]] = r
+			da[k] = Right
+			pa[k] = r
+			k++
+		} else { //case 3, p's right child has left child
+			var s *trbnode[T] //p's successor
+			j := k
+			k++
+			for {
+				da[k] = Left
+				pa[k] = r
+				k++
+				s = r.links[Left]
+				if s.links[Left] == nil {
+					break
+				}
+				r = s
+			}
+			if !s.rtag {
+				r.links[Left] = s.links[Right]
+			} else {
+				r.links[Left] = nil
+			}
+			if p.links[Left] != nil {
+				p.links[Left].last().links[Right] = s
+			}
+			s.links[Left] = p.links[Left]
+			s.links[Right] = p.links[Right]
+			s.rtag = false
+			s.color, p.color = p.color, s.color
+
+			pa[j-1].links[da[j-1]] = s
+			da[j] = Right
+			pa[j] = s
+		}
+	}
+
+	//a black node is gone from pa[k-1] side da[k-1], restore black height
+	if p.color == black {
+		for {
+			x := pa[k-1].child(int(da[k-1]))
+			if x.isRed() {
+				x.color = black
+				break
+			}
+			if k < 2 {
+				break
+			}
+			if da[k-1] == Left {
+				//node x's sibling
+				s := pa[k-1].links[Right]
+				if s.color == red {
+					s.color = black
+					pa[k-1].color = red
+					pa[k-2].links[da[k-2]] = pa[k-1].rotateLeft()
+					pa[k] = pa[k-1]
+					da[k] = Left
+					pa[k-1] = s
+					k++
+					s = pa[k-1].links[Right]
+				}
+				if !s.links[Left].isRed() && !s.child(Right).isRed() {
+					s.color = red
+				} else {
+					if !s.child(Right).isRed() {
+						y := s.links[Left]
+						y.color = black
+						s.color = red
+						pa[k-1].links[Right] = s.rotateRight()
+						s = y
+					}
+					s.color = pa[k-1].color
+					pa[k-1].color = black
+					s.links[Right].color = black
+					pa[k-2].links[da[k-2]] = pa[k-1].rotateLeft()
+					break
+				}
+			} else {
+				//node x's sibling
+				s := pa[k-1].links[Left]
+				if s.color == red {
+					s.color = black
+					pa[k-1].color = red
+					pa[k-2].links[da[k-2]] = pa[k-1].rotateRight()
+					pa[k] = pa[k-1]
+					da[k] = Right
+					pa[k-1] = s
+					k++
+					s = pa[k-1].links[Left]
+				}
+				if !s.links[Left].isRed() && !s.child(Right).isRed() {
+					s.color = red
+				} else {
+					if !s.links[Left].isRed() {
+						y := s.links[Right]
+						y.color = black
+						s.color = red
+						pa[k-1].links[Left] = s.rotateLeft()
+						s = y
+					}
+					s.color = pa[k-1].color
+					pa[k-1].color = black
+					s.links[Left].color = black
+					pa[k-2].links[da[k-2]] = pa[k-1].rotateRight()
+					break
+				}
+			}
+			k--
+		}
+	}
+	t.root = head.links[Left]
+	t.count--
+	return ret, true
+}
+
+func (t *TRbSet[T]) Copy() *TRbSet[T] {
+	if t == nil {
+		return nil
+	}
+	n := NewTRbSet(t.cmpFunc)
+	if n == nil {
+		return nil
+	}
+	n.count = t.count
+	//the last copied node, its thread points to the next copied one
+	var last *trbnode[T]
+	var copyTree func(x *trbnode[T]) *trbnode[T]
+	copyTree = func(x *trbnode[T]) *trbnode[T] {
+		y := &trbnode[T]{data: x.data, color: x.color, rtag: x.rtag}
+		if x.links[Left] != nil {
+			y.links[Left] = copyTree(x.links[Left])
+		}
+		if last != nil && last.rtag {
+			last.links[Right] = y
+		}
+		last = y
+		if !x.rtag {
+			y.links[Right] = copyTree(x.links[Right])
+		}
+		return y
+	}
+	if t.root != nil {
+		n.root = copyTree(t.root)
+	}
+	return n
+}
+
+func (t *TRbSet[T]) Iter() IteratorOf[T] {
+	it := NewTRbSetIter[T]()
+	return it.HookWith(t)
+}
+
+//sequence of all items in ascending order, threads are followed without stack
+func (t *TRbSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		for w := t.first(); w != nil; w = w.next() {
+			if !yield(w.data) {
+				return
+			}
+		}
+	}
+}
+
+//sequence of all items in descending order
+func (t *TRbSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		//walk right subtree, node, then left subtree, no thread helps here
+		var walk func(n *trbnode[T]) bool
+		walk = func(n *trbnode[T]) bool {
+			if n == nil {
+				return true
+			}
+			if !n.rtag && !walk(n.links[Right]) {
+				return false
+			}
+			return yield(n.data) && walk(n.links[Left])
+		}
+		walk(t.root)
+	}
+}
+
+//the first node of tree, nil if tree is empty
+func (t *TRbSet[T]) first() *trbnode[T] {
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	for w.links[Left] != nil {
+		w = w.links[Left]
+	}
+	return w
+}
+
+//iterator of TRbSet, it is just a node pointer,
+//it stays valid while other items are inserted and deleted
+type TRbSetIter[T any] struct {
+	tree *TRbSet[T]  //the tree be iterated
+	node *trbnode[T] //current node in tree
+}
+
+func NewTRbSetIter[T any]() *TRbSetIter[T] {
+	return &TRbSetIter[T]{}
+}
+
+func (it *TRbSetIter[T]) HookWith(tree *TRbSet[T]) *TRbSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	return it
+}
+
+//move to n and return its item
+func (it *TRbSetIter[T]) moveTo(n *trbnode[T]) (item T, ok bool) {
+	it.node = n
+	if n == nil {
+		return
+	}
+	return n.data, true
+}
+
+func (it *TRbSetIter[T]) First() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.first())
+}
+
+func (it *TRbSetIter[T]) Last() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.tree.root == nil {
+		return it.moveTo(nil)
+	}
+	return it.moveTo(it.tree.root.last())
+}
+
+func (it *TRbSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.find(item))
+}
+
+//move to the successor, or to the first item from nil position
+func (it *TRbSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.First()
+	}
+	return it.moveTo(it.node.next())
+}
+
+//move to the predecessor, or to the last item from nil position,
+//O(log n) when current node has no left child
+func (it *TRbSetIter[T]) Prev() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.Last()
+	}
+	return it.moveTo(it.tree.prev(it.node))
+}
+
+func (it *TRbSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//replace current item with new, which must have the same key
+//return the old item
+func (it *TRbSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	old := it.node.data
+	it.node.data = new
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *TRbSetIter[T]) Delete() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	//nodes are never moved, so the successor node survives deletion
+	next := it.node.next()
+	it.tree.Delete(it.node.data)
+	return it.moveTo(next)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *TRbSetIter[T]) DeletePrev() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	prev := it.tree.prev(it.node)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(prev)
+}
+
+func (it *TRbSetIter[T]) CopyFrom(other *TRbSetIter[T]) (item T, ok bool) {
+	if it == nil || other == nil {
+		return
+	}
+	it.tree = other.tree
+	return it.moveTo(other.node)
+}
+
+//insert item in tree and move to it, or to the equal item already in tree
+//return true if item was inserted
+func (it *TRbSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
+		return nil, false
+	}
+	n, ok := it.tree.insert(item)
+	it.node = n
+	return &n.data, ok
+}
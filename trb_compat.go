@@ -0,0 +1,49 @@
+package bbst
+
+//TRbTree is the interface{} flavour of TRbSet
+type TRbTree struct {
+	compatTree[TRbSet[Item], *TRbSet[Item]]
+}
+
+func NewTRbTree(cmp Compare, extra interface{}) *TRbTree {
+	if cmp == nil {
+		return nil
+	}
+	return &TRbTree{compatTree[TRbSet[Item], *TRbSet[Item]]{*NewTRbSet(compareOf(cmp, extra))}}
+}
+
+func (t *TRbTree) Copy() *TRbTree {
+	if t == nil {
+		return nil
+	}
+	return &TRbTree{compatTree[TRbSet[Item], *TRbSet[Item]]{*t.set.Copy()}}
+}
+
+func (t *TRbTree) Iter() Iterator {
+	it := NewTRbIter()
+	return it.HookWith(t)
+}
+
+type TRbIter struct {
+	compatIter[TRbSetIter[Item], *TRbSetIter[Item]]
+}
+
+func NewTRbIter() *TRbIter {
+	return &TRbIter{}
+}
+
+func (it *TRbIter) HookWith(tree *TRbTree) *TRbIter {
+	if it == nil {
+		return nil
+	}
+	it.it.HookWith(&tree.set)
+	return it
+}
+
+func (it *TRbIter) CopyFrom(other *TRbIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.it.CopyFrom(&other.it)
+	return item
+}
@@ -0,0 +1,70 @@
+package bbst
+
+import (
+	"testing"
+)
+
+//check order and colors of subtree n, whose items are in min...max
+//append its nodes in order to nodes, return black height of n
+func recurseVerifyTRbTree(t *testing.T, n *trbnode[Item], ok *bool, nodes *[]*trbnode[Item], min, max int) int {
+	if n == nil {
+		return 0
+	}
+	d := n.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	l, r := n.links[Left], n.child(Right)
+	if !n.rtag && r == nil {
+		t.Errorf("Node %d has nil right child.\n", d)
+		*ok = false
+	}
+	if n.color == red && (l.isRed() || r.isRed()) {
+		t.Errorf("Red node %d has red child.\n", d)
+		*ok = false
+	}
+	lh := recurseVerifyTRbTree(t, l, ok, nodes, min, d-1)
+	*nodes = append(*nodes, n)
+	rh := recurseVerifyTRbTree(t, r, ok, nodes, d+1, max)
+	if lh != rh {
+		t.Errorf("Node %d has black height %d on left, but %d on right.\n", d, lh, rh)
+		*ok = false
+	}
+	if n.color == black {
+		lh++
+	}
+	return lh
+}
+
+func verifyTRbTree(t *testing.T, tree *TRbTree, arr []int) bool {
+	ok := true
+	if tree.Count() != len(arr) {
+		t.Errorf("Tree count is %d, but should be %d.\n", tree.Count(), len(arr))
+		return false
+	}
+	var nodes []*trbnode[Item]
+	if tree.set.root != nil && tree.set.root.color != black {
+		t.Errorf("Tree's root is not black.\n")
+		ok = false
+	}
+	recurseVerifyTRbTree(t, tree.set.root, &ok, &nodes, 0, int(^uint(0)>>1))
+	if len(nodes) != len(arr) {
+		t.Errorf("Tree has %d nodes, but should have %d.\n", len(nodes), len(arr))
+		return false
+	}
+	ok = ok && verifyThreads(t, nodes, func(n *trbnode[Item]) int {
+		return n.data.(int)
+	}, func(n *trbnode[Item]) (*trbnode[Item], bool) {
+		return n.links[Right], n.rtag
+	})
+	for _, elem := range arr {
+		if tree.Find(elem) == nil {
+			t.Errorf("Tree does not contain expected value %d.\n", elem)
+			ok = false
+		}
+	}
+	return ok && verifyIntTraversal(t, tree.Iter(), nodes, func(n *trbnode[Item]) Item {
+		return n.data
+	})
+}
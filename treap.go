@@ -0,0 +1,496 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+	"math/rand/v2"
+)
+
+type treapnode[T any] struct {
+	links [ChildNum]*treapnode[T] //child node
+	data  T                       //data item
+	prio  uint64                  //priority, not less than that of children
+	size  int                     //number of node in subtree
+}
+
+//number of node in subtree rooted at n
+func (n *treapnode[T]) subSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+//recompute size of n from its children
+func (n *treapnode[T]) updateSize() {
+	n.size = n.links[Left].subSize() + n.links[Right].subSize() + 1
+}
+
+//TreapSet is a binary search tree by item and a max heap by priority,
+//with random priorities it is balanced in expectation, O(log n) per operation,
+//with priorities chosen by user the item of the highest priority is always root
+type TreapSet[T any] struct {
+	root    *treapnode[T]    //root of  tree
+	cmpFunc func(a, b T) int //compare function
+	rng     *rand.Rand       //source of random priorities
+}
+
+func NewTreapSet[T any](cmp func(a, b T) int) *TreapSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &TreapSet[T]{
+		cmpFunc: cmp,
+		rng:     rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+func NewOrderedTreapSet[T cmp.Ordered]() *TreapSet[T] {
+	return NewTreapSet(cmp.Compare[T])
+}
+
+//set source of random priorities of later inserted items,
+//a source with fixed seed makes the shape of tree reproducible
+func (t *TreapSet[T]) SetSource(src rand.Source) {
+	if t == nil || src == nil {
+		return
+	}
+	t.rng = rand.New(src)
+}
+
+func (t *TreapSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.root.subSize()
+}
+
+//return node of target, nil if there is none
+func (t *TreapSet[T]) find(target T) *treapnode[T] {
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(target, w.data)
+		if cmp == 0 {
+			return w
+		} else if cmp < 0 {
+			w = w.links[Left]
+		} else {
+			w = w.links[Right]
+		}
+	}
+	return nil
+}
+
+//search target in tree
+//return found item and true if find it
+//else ok is false
+func (t *TreapSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.find(target); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//return item of the highest priority and its priority
+//ok is false if tree is empty
+func (t *TreapSet[T]) Top() (item T, prio uint64, ok bool) {
+	if t == nil || t.root == nil {
+		return
+	}
+	return t.root.data, t.root.prio, true
+}
+
+//insert item with priority prio in tree
+//return node of item and true if inserted,
+//node of the equal item and false if item already in tree
+func (t *TreapSet[T]) insert(item T, prio uint64) (*treapnode[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	if w := t.find(item); w != nil {
+		return w, false
+	}
+	//walk down to the first node of lower priority, the new node takes its place
+	p := &t.root
+	for *p != nil && (*p).prio >= prio {
+		(*p).size++
+		if t.cmpFunc(item, (*p).data) < 0 {
+			p = &(*p).links[Left]
+		} else {
+			p = &(*p).links[Right]
+		}
+	}
+	n := &treapnode[T]{data: item, prio: prio}
+	n.links[Left], _, n.links[Right] = t.split(*p, item)
+	n.updateSize()
+	*p = n
+	return n, true
+}
+
+//insert item in tree with a random priority
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *TreapSet[T]) Insert(item T) bool {
+	if t == nil {
+		return false
+	}
+	_, succ := t.insert(item, t.rng.Uint64())
+	return succ
+}
+
+//insert item in tree with priority prio, items of higher priority are nearer to root
+//return true if item was successfully inserted
+//return false if item already in tree, whose priority is not changed
+func (t *TreapSet[T]) InsertWithPriority(item T, prio uint64) bool {
+	_, succ := t.insert(item, prio)
+	return succ
+}
+
+//replace item in tree with same key item, its priority is kept
+//insert item if no such key, ok is false in that case
+func (t *TreapSet[T]) Replace(item T) (old T, ok bool) {
+	if t == nil {
+		return
+	}
+	n, succ := t.insert(item, t.rng.Uint64())
+	if succ {
+		return
+	}
+	r := n.data
+	n.data = item
+	return r, true
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *TreapSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil || t.find(item) == nil {
+		return
+	}
+	p := &t.root
+	for {
+		cmp := t.cmpFunc(item, (*p).data)
+		if cmp == 0 {
+			break
+		}
+		(*p).size--
+		if cmp < 0 {
+			p = &(*p).links[Left]
+		} else {
+			p = &(*p).links[Right]
+		}
+	}
+	n := *p
+	*p = t.merge(n.links[Left], n.links[Right])
+	return n.data, true
+}
+
+//split subtree n into nodes less than item, the node equal to item and nodes greater than item
+func (t *TreapSet[T]) split(n *treapnode[T], item T) (l, eq, r *treapnode[T]) {
+	if n == nil {
+		return
+	}
+	cmp := t.cmpFunc(item, n.data)
+	if cmp == 0 {
+		return n.links[Left], n, n.links[Right]
+	} else if cmp < 0 {
+		l, eq, n.links[Left] = t.split(n.links[Left], item)
+		r = n
+	} else {
+		n.links[Right], eq, r = t.split(n.links[Right], item)
+		l = n
+	}
+	n.updateSize()
+	return
+}
+
+//merge subtrees l and r, every item in l must be less than every item in r
+func (t *TreapSet[T]) merge(l, r *treapnode[T]) *treapnode[T] {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.prio >= r.prio {
+		l.links[Right] = t.merge(l.links[Right], r)
+		l.updateSize()
+		return l
+	}
+	r.links[Left] = t.merge(l, r.links[Left])
+	r.updateSize()
+	return r
+}
+
+//return tree of same compare function holding n,
+//its random source is seeded from that of t, so it is reproducible too
+func (t *TreapSet[T]) withRoot(n *treapnode[T]) *TreapSet[T] {
+	return &TreapSet[T]{
+		root:    n,
+		cmpFunc: t.cmpFunc,
+		rng:     rand.New(rand.NewPCG(t.rng.Uint64(), t.rng.Uint64())),
+	}
+}
+
+//split t into items less than item and items greater than item, in O(log n)
+//eq is the item equal to item, found is false if there is no such item
+//t is emptied
+func (t *TreapSet[T]) Split(item T) (lt *TreapSet[T], eq T, found bool, gt *TreapSet[T]) {
+	if t == nil {
+		return
+	}
+	l, e, r := t.split(t.root, item)
+	t.root = nil
+	if e != nil {
+		eq, found = e.data, true
+	}
+	return t.withRoot(l), eq, found, t.withRoot(r)
+}
+
+//append all items of right to t in O(log n),
+//every item in t must be less than every item in right,
+//right is emptied
+//return false and change nothing if items are out of order
+func (t *TreapSet[T]) Merge(right *TreapSet[T]) bool {
+	if t == nil {
+		return false
+	}
+	if right == nil || right.root == nil {
+		return true
+	}
+	if t.root != nil && t.cmpFunc(t.last().data, right.first().data) >= 0 {
+		return false
+	}
+	t.root = t.merge(t.root, right.root)
+	right.root = nil
+	return true
+}
+
+//the first node of tree, nil if tree is empty
+func (t *TreapSet[T]) first() *treapnode[T] {
+	return t.extreme(Left)
+}
+
+//the last node of tree, nil if tree is empty
+func (t *TreapSet[T]) last() *treapnode[T] {
+	return t.extreme(Right)
+}
+
+//the first (dir is Left) or the last (dir is Right) node of tree
+func (t *TreapSet[T]) extreme(dir int) *treapnode[T] {
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	for w.links[dir] != nil {
+		w = w.links[dir]
+	}
+	return w
+}
+
+//return the neighbour of item in direction dir, nil if there is none,
+//item need not be in tree
+func (t *TreapSet[T]) near(item T, dir int) *treapnode[T] {
+	var found *treapnode[T]
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if dir == Right && cmp < 0 || dir == Left && cmp > 0 {
+			found = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	return found
+}
+
+func (t *TreapSet[T]) Copy() *TreapSet[T] {
+	if t == nil {
+		return nil
+	}
+	var copyTree func(x *treapnode[T]) *treapnode[T]
+	copyTree = func(x *treapnode[T]) *treapnode[T] {
+		if x == nil {
+			return nil
+		}
+		return &treapnode[T]{
+			links: [ChildNum]*treapnode[T]{copyTree(x.links[Left]), copyTree(x.links[Right])},
+			data:  x.data,
+			prio:  x.prio,
+			size:  x.size,
+		}
+	}
+	return t.withRoot(copyTree(t.root))
+}
+
+func (t *TreapSet[T]) Iter() IteratorOf[T] {
+	it := NewTreapSetIter[T]()
+	return it.HookWith(t)
+}
+
+//yield items of tree in order, dir is Left for ascending order,
+//Right for descending order
+func (t *TreapSet[T]) walk(n *treapnode[T], dir int, yield func(T) bool) bool {
+	for ; n != nil; n = n.links[1-dir] {
+		if !t.walk(n.links[dir], dir, yield) || !yield(n.data) {
+			return false
+		}
+	}
+	return true
+}
+
+//sequence of all items in ascending order
+func (t *TreapSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(t.root, Left, yield)
+	}
+}
+
+//sequence of all items in descending order
+func (t *TreapSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(t.root, Right, yield)
+	}
+}
+
+//iterator of TreapSet, it keeps the current node only,
+//each move searches the neighbour of current item from root in O(log n),
+//so it stays valid while tree changes and follows the key of a deleted item
+type TreapSetIter[T any] struct {
+	tree *TreapSet[T]  //the tree be iterated
+	node *treapnode[T] //current node in tree
+}
+
+func NewTreapSetIter[T any]() *TreapSetIter[T] {
+	return &TreapSetIter[T]{}
+}
+
+func (it *TreapSetIter[T]) HookWith(tree *TreapSet[T]) *TreapSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	return it
+}
+
+//move to n and return its item
+func (it *TreapSetIter[T]) moveTo(n *treapnode[T]) (item T, ok bool) {
+	it.node = n
+	if n == nil {
+		return
+	}
+	return n.data, true
+}
+
+func (it *TreapSetIter[T]) First() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.first())
+}
+
+func (it *TreapSetIter[T]) Last() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.last())
+}
+
+func (it *TreapSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.find(item))
+}
+
+//move to the successor, or to the first item from nil position
+func (it *TreapSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.First()
+	}
+	return it.moveTo(it.tree.near(it.node.data, Right))
+}
+
+//move to the predecessor, or to the last item from nil position
+func (it *TreapSetIter[T]) Prev() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.Last()
+	}
+	return it.moveTo(it.tree.near(it.node.data, Left))
+}
+
+func (it *TreapSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//replace current item with new, which must have the same key
+//return the old item
+func (it *TreapSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	old := it.node.data
+	it.node.data = new
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *TreapSetIter[T]) Delete() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	//nodes are never copied, so the successor node survives deletion
+	next := it.tree.near(it.node.data, Right)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(next)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *TreapSetIter[T]) DeletePrev() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	prev := it.tree.near(it.node.data, Left)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(prev)
+}
+
+func (it *TreapSetIter[T]) CopyFrom(other *TreapSetIter[T]) (item T, ok bool) {
+	if it == nil || other == nil {
+		return
+	}
+	it.tree = other.tree
+	return it.moveTo(other.node)
+}
+
+//insert item in tree and move to it, or to the equal item already in tree
+//return true if item was inserted
+func (it *TreapSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
+		return nil, false
+	}
+	n, ok := it.tree.insert(item, it.tree.rng.Uint64())
+	it.node = n
+	return &n.data, ok
+}
@@ -0,0 +1,107 @@
+package bbst
+
+import (
+	"math/rand/v2"
+)
+
+//TreapTree is the interface{} flavour of TreapSet
+type TreapTree struct {
+	compatTree[TreapSet[Item], *TreapSet[Item]]
+}
+
+func NewTreapTree(cmp Compare, extra interface{}) *TreapTree {
+	if cmp == nil {
+		return nil
+	}
+	return &TreapTree{compatTree[TreapSet[Item], *TreapSet[Item]]{*NewTreapSet(compareOf(cmp, extra))}}
+}
+
+//set source of random priorities of later inserted items
+func (t *TreapTree) SetSource(src rand.Source) {
+	if t == nil {
+		return
+	}
+	t.set.SetSource(src)
+}
+
+//insert item in tree with priority prio, items of higher priority are nearer to root
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *TreapTree) InsertWithPriority(item Item, prio uint64) bool {
+	if t == nil || item == nil {
+		return false
+	}
+	return t.set.InsertWithPriority(item, prio)
+}
+
+//return item of the highest priority and its priority
+//return nil if tree is empty
+func (t *TreapTree) Top() (Item, uint64) {
+	if t == nil {
+		return nil, 0
+	}
+	item, prio, _ := t.set.Top()
+	return item, prio
+}
+
+//split t into items less than item and items greater than item
+//eq is the item equal to item, or nil if there is no such item
+//t is emptied
+func (t *TreapTree) Split(item Item) (lt *TreapTree, eq Item, gt *TreapTree) {
+	if t == nil || item == nil {
+		return
+	}
+	l, eq, _, r := t.set.Split(item)
+	lt = &TreapTree{compatTree[TreapSet[Item], *TreapSet[Item]]{*l}}
+	gt = &TreapTree{compatTree[TreapSet[Item], *TreapSet[Item]]{*r}}
+	return lt, eq, gt
+}
+
+//append all items of right to t, every item in t must be less than every item in right,
+//right is emptied
+//return false and change nothing if items are out of order
+func (t *TreapTree) Merge(right *TreapTree) bool {
+	if t == nil {
+		return false
+	}
+	if right == nil {
+		return true
+	}
+	return t.set.Merge(&right.set)
+}
+
+func (t *TreapTree) Copy() *TreapTree {
+	if t == nil {
+		return nil
+	}
+	return &TreapTree{compatTree[TreapSet[Item], *TreapSet[Item]]{*t.set.Copy()}}
+}
+
+func (t *TreapTree) Iter() Iterator {
+	it := NewTreapIter()
+	return it.HookWith(t)
+}
+
+type TreapIter struct {
+	compatIter[TreapSetIter[Item], *TreapSetIter[Item]]
+}
+
+func NewTreapIter() *TreapIter {
+	return &TreapIter{}
+}
+
+func (it *TreapIter) HookWith(tree *TreapTree) *TreapIter {
+	if it == nil {
+		return nil
+	}
+	it.it.HookWith(&tree.set)
+	return it
+}
+
+func (it *TreapIter) CopyFrom(other *TreapIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.it.CopyFrom(&other.it)
+	return item
+}
@@ -0,0 +1,121 @@
+package bbst
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+//check order, heap order of priorities and sizes of subtree n, whose items are in min...max
+//append its nodes in order to nodes
+func recurseVerifyTreapTree(t *testing.T, n *treapnode[Item], ok *bool, nodes *[]*treapnode[Item], min, max int) {
+	if n == nil {
+		return
+	}
+	d := n.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	for _, c := range n.links {
+		if c != nil && c.prio > n.prio {
+			t.Errorf("Node %d has priority less than its child %d.\n", d, c.data)
+			*ok = false
+		}
+	}
+	if size := n.links[Left].subSize() + n.links[Right].subSize() + 1; n.size != size {
+		t.Errorf("Node %d has size %d, but should be %d.\n", d, n.size, size)
+		*ok = false
+	}
+	recurseVerifyTreapTree(t, n.links[Left], ok, nodes, min, d-1)
+	*nodes = append(*nodes, n)
+	recurseVerifyTreapTree(t, n.links[Right], ok, nodes, d+1, max)
+}
+
+func verifyTreapTree(t *testing.T, tree *TreapTree, arr []int) bool {
+	ok := true
+	if tree.Count() != len(arr) {
+		t.Errorf("Tree count is %d, but should be %d.\n", tree.Count(), len(arr))
+		return false
+	}
+	var nodes []*treapnode[Item]
+	recurseVerifyTreapTree(t, tree.set.root, &ok, &nodes, 0, int(^uint(0)>>1))
+	if !ok {
+		return false
+	}
+	if len(nodes) != len(arr) {
+		t.Errorf("Tree has %d nodes, but should have %d.\n", len(nodes), len(arr))
+		return false
+	}
+	for _, elem := range arr {
+		if tree.Find(elem) == nil {
+			t.Errorf("Tree does not contain expected value %d.\n", elem)
+			ok = false
+		}
+	}
+	return ok && verifyIntTraversal(t, tree.Iter(), nodes, func(n *treapnode[Item]) Item {
+		return n.data
+	})
+}
+
+func TestTreap(t *testing.T) {
+	//shape of tree
+	shape := func(tree *TreapTree) []uint64 {
+		var prios []uint64
+		var walk func(n *treapnode[Item])
+		walk = func(n *treapnode[Item]) {
+			if n != nil {
+				prios = append(prios, n.prio)
+				walk(n.links[Left])
+				walk(n.links[Right])
+			}
+		}
+		walk(tree.set.root)
+		return prios
+	}
+	a, b := NewTreapTree(intCmp, nil), NewTreapTree(intCmp, nil)
+	a.SetSource(rand.NewPCG(1, 2))
+	b.SetSource(rand.NewPCG(1, 2))
+	for _, elem := range insertArr {
+		a.Insert(elem)
+		b.Insert(elem)
+	}
+	if !slices.Equal(shape(a), shape(b)) {
+		t.Errorf("Trees of same seed have different shapes.\n")
+	}
+
+	//the item of the highest priority is root, insertion order does not matter
+	tree := NewTreapTree(intCmp, nil)
+	for _, elem := range insertArr {
+		tree.InsertWithPriority(elem, uint64(len(insertArr)-elem))
+	}
+	if !verifyTreapTree(t, tree, insertArr) {
+		return
+	}
+	if item, prio := tree.Top(); len(insertArr) > 0 && (item != 0 || prio != uint64(len(insertArr))) {
+		t.Errorf("Top is %v of priority %d, but should be 0 of priority %d.\n", item, prio, len(insertArr))
+	}
+	n := len(insertArr)
+	if n > 0 && tree.InsertWithPriority(0, 0) {
+		t.Errorf("Inserting existing item succeeded.\n")
+	}
+
+	sorted := slices.Sorted(slices.Values(insertArr))
+	lt, eq, gt := tree.Split(n / 2)
+	if n > 0 && eq != n/2 || tree.Count() != 0 {
+		t.Errorf("Split found %v, left %d items in tree.\n", eq, tree.Count())
+	}
+	if !verifyTreapTree(t, lt, sorted[:n/2]) || n > 0 && !verifyTreapTree(t, gt, sorted[n/2+1:]) {
+		return
+	}
+	if lt.Count() > 0 && gt.Count() > 0 && gt.Merge(lt) {
+		t.Errorf("Merging out of order trees succeeded.\n")
+	}
+	if !lt.Merge(gt) || gt.Count() != 0 {
+		t.Errorf("Merge failed.\n")
+	}
+	if n > 0 {
+		lt.Insert(n / 2)
+	}
+	verifyTreapTree(t, lt, insertArr)
+}
@@ -0,0 +1,502 @@
+package bbst
+
+import (
+	"cmp"
+	"iter"
+)
+
+const wavlMaxHeight = 128
+
+type wavlnode[T any] struct {
+	links [ChildNum]*wavlnode[T] //child node
+	data  T                      //data item
+	rank  int8                   //rank of node
+}
+
+//rank of n, -1 if n is nil
+func wavlRank[T any](n *wavlnode[T]) int8 {
+	if n == nil {
+		return -1
+	}
+	return n.rank
+}
+
+//WavlSet is a weak avl tree, see Haeupler, Sen and Tarjan, Rank-Balanced Trees,
+//a child's rank is 1 or 2 below its parent's, a leaf has rank 0 and a missing node -1,
+//without deletions it is an avl tree, updates do at most two rotations
+//and its height is below 2 log(n)
+type WavlSet[T any] struct {
+	root    *wavlnode[T]     //root of  tree
+	cmpFunc func(a, b T) int //compare function
+	count   int              // number of item in tree
+}
+
+func NewWavlSet[T any](cmp func(a, b T) int) *WavlSet[T] {
+	if cmp == nil {
+		return nil
+	}
+	return &WavlSet[T]{cmpFunc: cmp}
+}
+
+func NewOrderedWavlSet[T cmp.Ordered]() *WavlSet[T] {
+	return NewWavlSet(cmp.Compare[T])
+}
+
+func (t *WavlSet[T]) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+//return node of target, nil if there is none
+func (t *WavlSet[T]) find(target T) *wavlnode[T] {
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(target, w.data)
+		if cmp == 0 {
+			return w
+		} else if cmp < 0 {
+			w = w.links[Left]
+		} else {
+			w = w.links[Right]
+		}
+	}
+	return nil
+}
+
+//search target in tree
+//return found item and true if find it
+//else ok is false
+func (t *WavlSet[T]) Find(target T) (item T, ok bool) {
+	if t == nil {
+		return
+	}
+	if w := t.find(target); w != nil {
+		return w.data, true
+	}
+	return
+}
+
+//insert item in tree
+//return node of item and true if inserted,
+//node of the equal item and false if item already in tree
+func (t *WavlSet[T]) insert(item T) (*wavlnode[T], bool) {
+	if t == nil {
+		return nil, false
+	}
+	var (
+		pa [wavlMaxHeight]**wavlnode[T] //links to nodes on the path, pa[0] is &t.root
+		da [wavlMaxHeight]byte          //缓存的下降方向数组
+		k  int                          //length of da
+		q  = &t.root                    //link to current walk node
+	)
+	for w := *q; w != nil; w = *q {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			return w, false
+		}
+		dir := Left
+		if cmp > 0 {
+			dir = Right
+		}
+		pa[k] = q
+		da[k] = byte(dir)
+		k++
+		q = &w.links[dir]
+	}
+	n := &wavlnode[T]{data: item}
+	*q = n
+	t.count++
+
+	//x is a 0-child of its parent p
+	for x := n; k >= 1; k-- {
+		p, dir := *pa[k-1], int(da[k-1])
+		if p.rank != x.rank {
+			break
+		}
+		if p.rank-wavlRank(p.links[1-dir]) == 1 {
+			//p is a 0,1 node, promote it and go up
+			p.rank++
+			x = p
+			continue
+		}
+		//p is a 0,2 node, rotate
+		var r *wavlnode[T]
+		if z := x.links[1-dir]; x.rank-wavlRank(z) == 2 {
+			//inner child z of x is a 2-child, single rotation
+			p.links[dir] = z
+			x.links[1-dir] = p
+			p.rank--
+			r = x
+		} else {
+			//inner child z of x is a 1-child, double rotation
+			x.links[1-dir] = z.links[dir]
+			z.links[dir] = x
+			p.links[dir] = z.links[1-dir]
+			z.links[1-dir] = p
+			z.rank++
+			x.rank--
+			p.rank--
+			r = z
+		}
+		*pa[k-1] = r
+		break
+	}
+	return n, true
+}
+
+//insert item in tree
+//return true if item was successfully inserted
+//return false if item already in tree
+func (t *WavlSet[T]) Insert(item T) bool {
+	_, succ := t.insert(item)
+	return succ
+}
+
+//replace item in tree with same key item
+//insert item if no such key, ok is false in that case
+func (t *WavlSet[T]) Replace(item T) (old T, ok bool) {
+	n, succ := t.insert(item)
+	if n == nil || succ {
+		return
+	}
+	r := n.data
+	n.data = item
+	return r, true
+}
+
+//delete item in tree
+//return item if find it
+//else ok is false
+func (t *WavlSet[T]) Delete(item T) (deleted T, ok bool) {
+	if t == nil {
+		return
+	}
+	var (
+		pa [wavlMaxHeight]**wavlnode[T] //links to nodes on the path, pa[0] is &t.root
+		da [wavlMaxHeight]byte          //缓存的下降方向数组
+		k  int                          //length of da
+		q  = &t.root                    //link to node to delete
+		w  *wavlnode[T]                 //node to delete
+	)
+	for w = *q; w != nil; w = *q {
+		cmp := t.cmpFunc(item, w.data)
+		if cmp == 0 {
+			break
+		}
+		dir := Left
+		if cmp > 0 {
+			dir = Right
+		}
+		pa[k] = q
+		da[k] = byte(dir)
+		k++
+		q = &w.links[dir]
+	}
+	if w == nil {
+		return
+	}
+	if w.links[Right] == nil { //case 1, w has no right child
+		*q = w.links[Left]
+	} else {
+		r := w.links[Right]
+		if r.links[Left] == nil { //case 2, w's right child r has no left child
+			r.links[Left] = w.links[Left]
+			r.rank = w.rank
+			*q = r
+			da[k] = Right
+			pa[k] = q
+			k++
+		} else { //case 3, w's right child has left child
+			j := k
+			//link to r is known once s takes place of w
+			da[j+1] = Left
+			k += 2
+			s := r.links[Left] //w's successor
+			for s.links[Left] != nil {
+				da[k] = Left
+				pa[k] = &r.links[Left]
+				k++
+				r, s = s, s.links[Left]
+			}
+			//s takes place and rank of w
+			r.links[Left] = s.links[Right]
+			s.links[Left] = w.links[Left]
+			s.links[Right] = w.links[Right]
+			s.rank = w.rank
+			*q = s
+			da[j] = Right
+			pa[j] = q
+			pa[j+1] = &s.links[Right]
+		}
+	}
+	t.count--
+
+	//child x of p in direction dir may be a 3-child, or p a 2,2 leaf
+	for ; k >= 1; k-- {
+		p, dir := *pa[k-1], int(da[k-1])
+		x, s := p.links[dir], p.links[1-dir]
+		if x == nil && s == nil && p.rank == 1 {
+			//p is a 2,2 leaf, demote it and go up
+			p.rank = 0
+			continue
+		}
+		if p.rank-wavlRank(x) != 3 {
+			break
+		}
+		if p.rank-s.rank == 2 {
+			//sibling is a 2-child, demote p and go up
+			p.rank--
+			continue
+		}
+		if s.rank-wavlRank(s.links[Left]) == 2 && s.rank-wavlRank(s.links[Right]) == 2 {
+			//sibling is a 2,2 node, demote both and go up
+			p.rank--
+			s.rank--
+			continue
+		}
+		var r *wavlnode[T]
+		if y := s.links[1-dir]; s.rank-wavlRank(y) == 1 {
+			//outer child y of s is a 1-child, single rotation
+			p.links[1-dir] = s.links[dir]
+			s.links[dir] = p
+			s.rank++
+			p.rank--
+			if p.links[Left] == nil && p.links[Right] == nil {
+				p.rank--
+			}
+			r = s
+		} else {
+			//inner child z of s is a 1-child, double rotation
+			z := s.links[dir]
+			s.links[dir] = z.links[1-dir]
+			z.links[1-dir] = s
+			p.links[1-dir] = z.links[dir]
+			z.links[dir] = p
+			z.rank += 2
+			s.rank--
+			p.rank -= 2
+			r = z
+		}
+		*pa[k-1] = r
+		break
+	}
+	return w.data, true
+}
+
+//the first (dir is Left) or the last (dir is Right) node of tree
+func (t *WavlSet[T]) extreme(dir int) *wavlnode[T] {
+	w := t.root
+	if w == nil {
+		return nil
+	}
+	for w.links[dir] != nil {
+		w = w.links[dir]
+	}
+	return w
+}
+
+//return the neighbour of item in direction dir, nil if there is none,
+//item need not be in tree
+func (t *WavlSet[T]) near(item T, dir int) *wavlnode[T] {
+	var found *wavlnode[T]
+	for w := t.root; w != nil; {
+		cmp := t.cmpFunc(item, w.data)
+		if dir == Right && cmp < 0 || dir == Left && cmp > 0 {
+			found = w
+			w = w.links[1-dir]
+		} else {
+			w = w.links[dir]
+		}
+	}
+	return found
+}
+
+func (t *WavlSet[T]) Copy() *WavlSet[T] {
+	if t == nil {
+		return nil
+	}
+	var copyTree func(x *wavlnode[T]) *wavlnode[T]
+	copyTree = func(x *wavlnode[T]) *wavlnode[T] {
+		if x == nil {
+			return nil
+		}
+		return &wavlnode[T]{
+			links: [ChildNum]*wavlnode[T]{copyTree(x.links[Left]), copyTree(x.links[Right])},
+			data:  x.data,
+			rank:  x.rank,
+		}
+	}
+	n := *t
+	n.root = copyTree(t.root)
+	return &n
+}
+
+func (t *WavlSet[T]) Iter() IteratorOf[T] {
+	it := NewWavlSetIter[T]()
+	return it.HookWith(t)
+}
+
+//yield items of subtree n in order, dir is Left for ascending order,
+//Right for descending order
+func (t *WavlSet[T]) walk(n *wavlnode[T], dir int, yield func(T) bool) bool {
+	for ; n != nil; n = n.links[1-dir] {
+		if !t.walk(n.links[dir], dir, yield) || !yield(n.data) {
+			return false
+		}
+	}
+	return true
+}
+
+//sequence of all items in ascending order
+func (t *WavlSet[T]) All() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(t.root, Left, yield)
+	}
+}
+
+//sequence of all items in descending order
+func (t *WavlSet[T]) Backward() iter.Seq[T] {
+	if t == nil {
+		return empty[T]
+	}
+	return func(yield func(T) bool) {
+		t.walk(t.root, Right, yield)
+	}
+}
+
+//iterator of WavlSet, it keeps the current node only,
+//each move searches the neighbour of current item from root in O(log n),
+//so it stays valid while tree changes and follows the key of a deleted item
+type WavlSetIter[T any] struct {
+	tree *WavlSet[T]  //the tree be iterated
+	node *wavlnode[T] //current node in tree
+}
+
+func NewWavlSetIter[T any]() *WavlSetIter[T] {
+	return &WavlSetIter[T]{}
+}
+
+func (it *WavlSetIter[T]) HookWith(tree *WavlSet[T]) *WavlSetIter[T] {
+	if it == nil {
+		return nil
+	}
+	it.tree = tree
+	it.node = nil
+	return it
+}
+
+//move to n and return its item
+func (it *WavlSetIter[T]) moveTo(n *wavlnode[T]) (item T, ok bool) {
+	it.node = n
+	if n == nil {
+		return
+	}
+	return n.data, true
+}
+
+func (it *WavlSetIter[T]) First() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.extreme(Left))
+}
+
+func (it *WavlSetIter[T]) Last() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.extreme(Right))
+}
+
+func (it *WavlSetIter[T]) Find(item T) (found T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	return it.moveTo(it.tree.find(item))
+}
+
+//move to the successor, or to the first item from nil position
+func (it *WavlSetIter[T]) Next() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.First()
+	}
+	return it.moveTo(it.tree.near(it.node.data, Right))
+}
+
+//move to the predecessor, or to the last item from nil position
+func (it *WavlSetIter[T]) Prev() (item T, ok bool) {
+	if it == nil || it.tree == nil {
+		return
+	}
+	if it.node == nil {
+		return it.Last()
+	}
+	return it.moveTo(it.tree.near(it.node.data, Left))
+}
+
+func (it *WavlSetIter[T]) Current() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	return it.node.data, true
+}
+
+//replace current item with new, which must have the same key
+//return the old item
+func (it *WavlSetIter[T]) Replace(new T) (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	old := it.node.data
+	it.node.data = new
+	return old, true
+}
+
+//delete current item and move to its successor
+//return the successor, ok is false if there is none
+func (it *WavlSetIter[T]) Delete() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	//nodes are never copied, so the successor node survives deletion
+	next := it.tree.near(it.node.data, Right)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(next)
+}
+
+//delete current item and move to its predecessor
+//return the predecessor, ok is false if there is none
+func (it *WavlSetIter[T]) DeletePrev() (item T, ok bool) {
+	if it == nil || it.node == nil {
+		return
+	}
+	prev := it.tree.near(it.node.data, Left)
+	it.tree.Delete(it.node.data)
+	return it.moveTo(prev)
+}
+
+func (it *WavlSetIter[T]) CopyFrom(other *WavlSetIter[T]) (item T, ok bool) {
+	if it == nil || other == nil {
+		return
+	}
+	it.tree = other.tree
+	return it.moveTo(other.node)
+}
+
+//insert item in tree and move to it, or to the equal item already in tree
+//return true if item was inserted
+func (it *WavlSetIter[T]) Insert(item T) (*T, bool) {
+	if it == nil || it.tree == nil {
+		return nil, false
+	}
+	n, ok := it.tree.insert(item)
+	it.node = n
+	return &n.data, ok
+}
@@ -0,0 +1,49 @@
+package bbst
+
+//WavlTree is the interface{} flavour of WavlSet
+type WavlTree struct {
+	compatTree[WavlSet[Item], *WavlSet[Item]]
+}
+
+func NewWavlTree(cmp Compare, extra interface{}) *WavlTree {
+	if cmp == nil {
+		return nil
+	}
+	return &WavlTree{compatTree[WavlSet[Item], *WavlSet[Item]]{*NewWavlSet(compareOf(cmp, extra))}}
+}
+
+func (t *WavlTree) Copy() *WavlTree {
+	if t == nil {
+		return nil
+	}
+	return &WavlTree{compatTree[WavlSet[Item], *WavlSet[Item]]{*t.set.Copy()}}
+}
+
+func (t *WavlTree) Iter() Iterator {
+	it := NewWavlIter()
+	return it.HookWith(t)
+}
+
+type WavlIter struct {
+	compatIter[WavlSetIter[Item], *WavlSetIter[Item]]
+}
+
+func NewWavlIter() *WavlIter {
+	return &WavlIter{}
+}
+
+func (it *WavlIter) HookWith(tree *WavlTree) *WavlIter {
+	if it == nil {
+		return nil
+	}
+	it.it.HookWith(&tree.set)
+	return it
+}
+
+func (it *WavlIter) CopyFrom(other *WavlIter) Item {
+	if it == nil || other == nil {
+		return nil
+	}
+	item, _ := it.it.CopyFrom(&other.it)
+	return item
+}
@@ -0,0 +1,89 @@
+package bbst
+
+import (
+	"math/bits"
+	"testing"
+)
+
+//check order and rank rules of subtree n, whose items are in min...max
+//append its nodes in order to nodes, return height of n
+func recurseVerifyWavlTree(t *testing.T, n *wavlnode[Item], ok *bool, nodes *[]*wavlnode[Item], min, max int) int {
+	if n == nil {
+		return 0
+	}
+	d := n.data.(int)
+	if d < min || d > max {
+		t.Errorf("Node %d is not in range %d...%d implied by its parents.\n", d, min, max)
+		*ok = false
+	}
+	for _, c := range n.links {
+		if diff := n.rank - wavlRank(c); diff != 1 && diff != 2 {
+			t.Errorf("Node %d has a child of rank difference %d.\n", d, diff)
+			*ok = false
+		}
+	}
+	if n.links[Left] == nil && n.links[Right] == nil && n.rank != 0 {
+		t.Errorf("Leaf %d has rank %d.\n", d, n.rank)
+		*ok = false
+	}
+	lh := recurseVerifyWavlTree(t, n.links[Left], ok, nodes, min, d-1)
+	*nodes = append(*nodes, n)
+	rh := recurseVerifyWavlTree(t, n.links[Right], ok, nodes, d+1, max)
+	if lh > rh {
+		return lh + 1
+	}
+	return rh + 1
+}
+
+func verifyWavlTree(t *testing.T, tree *WavlTree, arr []int) bool {
+	ok := true
+	if tree.Count() != len(arr) {
+		t.Errorf("Tree count is %d, but should be %d.\n", tree.Count(), len(arr))
+		return false
+	}
+	var nodes []*wavlnode[Item]
+	height := recurseVerifyWavlTree(t, tree.set.root, &ok, &nodes, 0, int(^uint(0)>>1))
+	if limit := 2 * bits.Len(uint(len(arr))); height > limit {
+		t.Errorf("Tree height is %d, but should not exceed %d.\n", height, limit)
+		ok = false
+	}
+	if !ok {
+		return false
+	}
+	if len(nodes) != len(arr) {
+		t.Errorf("Tree has %d nodes, but should have %d.\n", len(nodes), len(arr))
+		return false
+	}
+	for _, elem := range arr {
+		if tree.Find(elem) == nil {
+			t.Errorf("Tree does not contain expected value %d.\n", elem)
+			ok = false
+		}
+	}
+	return ok && verifyIntTraversal(t, tree.Iter(), nodes, func(n *wavlnode[Item]) Item {
+		return n.data
+	})
+}
+
+//without deletions a wavl tree is an avl tree whose ranks are heights
+func TestWavlInsertOnly(t *testing.T) {
+	tree := NewWavlTree(intCmp, nil)
+	for _, elem := range insertArr {
+		tree.Insert(elem)
+	}
+	if !verifyWavlTree(t, tree, insertArr) {
+		return
+	}
+	var walk func(n *wavlnode[Item]) int
+	walk = func(n *wavlnode[Item]) int {
+		if n == nil {
+			return -1
+		}
+		lh, rh := walk(n.links[Left]), walk(n.links[Right])
+		if lh-rh > 1 || rh-lh > 1 || int(n.rank) != max(lh, rh)+1 {
+			t.Errorf("Node %v of rank %d has subtrees of height %d and %d.\n", n.data, n.rank, lh, rh)
+		}
+		return max(lh, rh) + 1
+	}
+	walk(tree.set.root)
+}